@@ -5,25 +5,33 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/simp-lee/gohttpclient"
+	"strings"
 )
 
 type AlibabaStrategy struct {
 	chatClient  *gohttpclient.Client
 	embedClient *gohttpclient.Client
 	config      *Config
+	logger      *requestLogger
+}
+
+func init() {
+	RegisterProvider("alibaba", func(config Config) (interface{}, error) {
+		return NewAlibabaStrategy(config)
+	})
 }
 
 func NewAlibabaStrategy(config Config) (*AlibabaStrategy, error) {
-	if config.APIKey == "" {
+	if !config.hasCredentials() {
 		return nil, fmt.Errorf("Alibaba API key is required")
 	}
 
 	// Set default base URLs if not set
 	if config.ChatURL == "" {
-		config.ChatURL = "https://dashscope.aliyuncs.com/api/v1/services/chat/completions"
+		config.ChatURL = "https://dashscope.aliyuncs.com/api/v1/services/aigc/text-generation/generation"
 	}
 	if config.EmbedURL == "" {
-		config.EmbedURL = "https://dashscope.aliyuncs.com/api/v1/services/embeddings/text-embedding"
+		config.EmbedURL = "https://dashscope.aliyuncs.com/api/v1/services/embeddings/text-embedding/text-embedding"
 	}
 
 	// Use default common config if not set
@@ -31,14 +39,16 @@ func NewAlibabaStrategy(config Config) (*AlibabaStrategy, error) {
 		config.CommonConfig = DefaultCommonConfig()
 	}
 
+	logger := newRequestLogger(config.CommonConfig, "alibaba", config.APIKey)
+
 	// Prepare the chat client
-	chatClient, err := createClient(config.CommonConfig, config.APIKey)
+	chatClient, err := createClient(config, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Alibaba chat client: %w", err)
 	}
 
 	// Prepare the embedding client
-	embedClient, err := createClient(config.CommonConfig, config.APIKey)
+	embedClient, err := createClient(config, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Alibaba embedding client: %w", err)
 	}
@@ -47,74 +57,196 @@ func NewAlibabaStrategy(config Config) (*AlibabaStrategy, error) {
 		chatClient:  chatClient,
 		embedClient: embedClient,
 		config:      &config,
+		logger:      logger,
 	}, nil
 }
 
+// ProviderName returns the provider identifier used in telemetry.
+func (s *AlibabaStrategy) ProviderName() string {
+	return "alibaba"
+}
+
+// DefaultChatModel returns Config.ChatModel, the model of chat calls that do not set one.
+func (s *AlibabaStrategy) DefaultChatModel() string {
+	return s.config.ChatModel
+}
+
+// DefaultEmbedModel returns Config.EmbedModel, the model of embed calls that do not set one.
+func (s *AlibabaStrategy) DefaultEmbedModel() string {
+	return s.config.EmbedModel
+}
+
 func (s *AlibabaStrategy) Chat(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+	options = options.withDefaultModel(s.config.ChatModel)
 	request := map[string]interface{}{
-		"model":    options.Model,
-		"messages": chatMessages,
+		"model": options.Model,
+		"input": map[string]interface{}{
+			"messages": chatMessages,
+		},
 	}
+	parameters := map[string]interface{}{}
 	if options.Temperature != nil {
-		request["temperature"] = *options.Temperature
+		parameters["temperature"] = *options.Temperature
 	}
 	if options.MaxTokens != nil {
-		request["max_tokens"] = *options.MaxTokens
+		parameters["max_tokens"] = *options.MaxTokens
 	}
 	if options.TopP != nil {
-		request["top_p"] = *options.TopP
+		parameters["top_p"] = *options.TopP
 	}
 	if options.Stop != nil {
-		request["stop"] = options.Stop
+		parameters["stop"] = options.Stop
+	}
+	if options.StreamHandler != nil {
+		parameters["incremental_output"] = true
+	}
+	if len(parameters) > 0 {
+		request["parameters"] = parameters
+	}
+	if options.StreamHandler != nil {
+		return s.chatStream(ctx, request, options.Model, options.StreamHandler)
 	}
 
-	resp, err := s.chatClient.Post(ctx, s.config.ChatURL, request)
+	start := s.logger.start(ctx, "chat", options.Model, request)
+	resp, err := post(ctx, s.chatClient, s.config, s.config.ChatURL, request)
 	if err != nil {
+		s.logger.end(ctx, "chat", options.Model, start, nil, nil, err)
 		return nil, fmt.Errorf("Alibaba chat request failed: %w", err)
 	}
 
 	var alibabaResp AlibabaChatResponse
 	if err := json.Unmarshal(resp, &alibabaResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal Alibaba chat response: %w", err)
+		err = fmt.Errorf("failed to unmarshal Alibaba chat response: %w", err)
+		s.logger.end(ctx, "chat", options.Model, start, resp, nil, err)
+		return nil, err
 	}
+	s.logger.end(ctx, "chat", options.Model, start, resp, &alibabaResp, nil)
 
 	return &alibabaResp, nil
 }
 
+func (s *AlibabaStrategy) chatStream(ctx context.Context, request map[string]interface{}, model string, handler StreamHandler) (ChatResponse, error) {
+	var content strings.Builder
+	result := &AlibabaChatResponse{}
+	start := s.logger.start(ctx, "chat", model, request)
+	headers := s.config.requestHeaders()
+	headers["X-DashScope-SSE"] = "enable"
+	err := postStream(ctx, s.chatClient, s.config, s.config.ChatURL, headers, request, func(data []byte) error {
+		var chunk AlibabaChatResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal Alibaba chat chunk: %w", err)
+		}
+		result.Usage = chunk.Usage
+		result.RequestID = chunk.RequestID
+		if reason := chunk.Output.FinishReason; reason != "" && reason != "null" {
+			result.Output.FinishReason = reason
+		}
+		if chunk.Output.Text == "" {
+			return nil
+		}
+		content.WriteString(chunk.Output.Text)
+		return handler(chunk.Output.Text)
+	})
+	if err != nil {
+		s.logger.end(ctx, "chat", model, start, nil, nil, err)
+		return nil, fmt.Errorf("Alibaba chat stream failed: %w", err)
+	}
+
+	result.Output.Text = content.String()
+	s.logger.end(ctx, "chat", model, start, nil, result, nil)
+	return result, nil
+}
+
 type AlibabaChatResponse struct {
 	Output struct {
-		Text string `json:"text"`
+		Text         string `json:"text"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"output"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+	RequestID string `json:"request_id"`
 }
 
 func (r *AlibabaChatResponse) GetContent() string {
 	return r.Output.Text
 }
 
+func (r *AlibabaChatResponse) GetUsage() Usage {
+	total := r.Usage.TotalTokens
+	if total == 0 {
+		total = r.Usage.InputTokens + r.Usage.OutputTokens
+	}
+	return Usage{
+		PromptTokens:     r.Usage.InputTokens,
+		CompletionTokens: r.Usage.OutputTokens,
+		TotalTokens:      total,
+	}
+}
+
+func (r *AlibabaChatResponse) GetFinishReasons() []string {
+	if r.Output.FinishReason == "" {
+		return nil
+	}
+	return []string{r.Output.FinishReason}
+}
+
 func (s *AlibabaStrategy) Embed(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+	if len(texts) == 0 {
+		return &AlibabaEmbedResponseWrapper{}, nil
+	}
+	options = options.withDefaultModel(s.config.EmbedModel)
+
 	request := map[string]interface{}{
 		"model": options.Model,
 		"input": map[string]interface{}{
 			"texts": texts,
 		},
 	}
+	// The native API only returns float vectors, so EncodingFormat is not sent.
+	parameters := map[string]interface{}{}
 	if options.EmbeddingType != "" {
-		request["params"] = map[string]string{
-			"text_type": options.EmbeddingType,
-		}
+		parameters["text_type"] = options.EmbeddingType
+	}
+	if nativeDimensions("alibaba", options) {
+		parameters["dimension"] = options.Dimensions
+	}
+	if options.OutputType != "" {
+		parameters["output_type"] = options.OutputType
+	}
+	if len(parameters) > 0 {
+		request["parameters"] = parameters
 	}
 
-	resp, err := s.embedClient.Post(ctx, s.config.EmbedURL, request)
+	start := s.logger.start(ctx, "embed", options.Model, request)
+	resp, err := post(ctx, s.embedClient, s.config, s.config.EmbedURL, request)
 	if err != nil {
+		s.logger.end(ctx, "embed", options.Model, start, nil, nil, err)
 		return nil, fmt.Errorf("Alibaba embed request failed: %w", err)
 	}
 
 	var alibabaResp AlibabaEmbeddingResponse
 	if err := json.Unmarshal(resp, &alibabaResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal Alibaba embed response: %w", err)
+		err = fmt.Errorf("failed to unmarshal Alibaba embed response: %w", err)
+		s.logger.end(ctx, "embed", options.Model, start, resp, nil, err)
+		return nil, err
 	}
+	for i := range alibabaResp.Output.Embeddings {
+		alibabaResp.Output.Embeddings[i].Embedding = shapeEmbedding(alibabaResp.Output.Embeddings[i].Embedding, options)
+	}
+	result := &AlibabaEmbedResponseWrapper{AlibabaEmbeddingResponse: alibabaResp}
+	if options.wantsSparse() {
+		if result.Sparse, err = unmarshalSparse(resp); err != nil {
+			err = fmt.Errorf("failed to unmarshal Alibaba sparse embeddings: %w", err)
+			s.logger.end(ctx, "embed", options.Model, start, resp, nil, err)
+			return nil, err
+		}
+	}
+	s.logger.end(ctx, "embed", options.Model, start, resp, result, nil)
 
-	return &AlibabaEmbedResponseWrapper{alibabaResp}, nil
+	return result, nil
 }
 
 type AlibabaEmbeddingResponse struct {
@@ -132,12 +264,52 @@ type AlibabaEmbeddingResponse struct {
 
 type AlibabaEmbedResponseWrapper struct {
 	AlibabaEmbeddingResponse
+	// Sparse are the sparse vectors in input order, set when a sparse output type was requested.
+	Sparse []SparseVector `json:"-"`
 }
 
+// GetEmbeddings returns the embeddings in input order.
 func (r *AlibabaEmbedResponseWrapper) GetEmbeddings() [][]float32 {
+	indexes := make([]int, len(r.Output.Embeddings))
 	embeddings := make([][]float32, len(r.Output.Embeddings))
 	for i, embedding := range r.Output.Embeddings {
+		indexes[i] = embedding.TextIndex
 		embeddings[i] = embedding.Embedding
 	}
-	return embeddings
+	return orderByIndex(indexes, embeddings)
+}
+
+// GetSparseEmbeddings returns the sparse vectors in input order. They are nil unless a sparse output type
+// was requested.
+func (r *AlibabaEmbedResponseWrapper) GetSparseEmbeddings() []SparseVector {
+	return r.Sparse
+}
+
+// unmarshalSparse reads the sparse vectors of data in input order.
+func unmarshalSparse(data []byte) ([]SparseVector, error) {
+	var wire struct {
+		Output struct {
+			Embeddings []struct {
+				TextIndex       int          `json:"text_index"`
+				SparseEmbedding SparseVector `json:"sparse_embedding"`
+			} `json:"embeddings"`
+		} `json:"output"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return nil, err
+	}
+	indexes := make([]int, len(wire.Output.Embeddings))
+	vectors := make([]SparseVector, len(wire.Output.Embeddings))
+	for i, embedding := range wire.Output.Embeddings {
+		indexes[i] = embedding.TextIndex
+		vectors[i] = embedding.SparseEmbedding
+	}
+	return orderByIndex(indexes, vectors), nil
+}
+
+func (r *AlibabaEmbedResponseWrapper) GetUsage() Usage {
+	return Usage{
+		PromptTokens: r.Usage.TotalTokens,
+		TotalTokens:  r.Usage.TotalTokens,
+	}
 }
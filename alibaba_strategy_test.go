@@ -30,7 +30,10 @@ func TestAlibabaStrategy_Chat(t *testing.T) {
 		require.NoError(t, err)
 
 		assert.Equal(t, "test-model", request["model"])
-		assert.Equal(t, []interface{}{map[string]interface{}{"role": "user", "content": "Hello"}}, request["messages"])
+		assert.Equal(t, map[string]interface{}{
+			"messages": []interface{}{map[string]interface{}{"role": "user", "content": "Hello"}},
+		}, request["input"])
+		assert.NotContains(t, request, "parameters")
 
 		response := `{"output":{"text":"Hi there"}}`
 		w.Write([]byte(response))
@@ -105,7 +108,8 @@ func TestAlibabaStrategy_Embed(t *testing.T) {
 func TestAlibabaChatResponse_GetContent(t *testing.T) {
 	resp := &AlibabaChatResponse{
 		Output: struct {
-			Text string `json:"text"`
+			Text         string `json:"text"`
+			FinishReason string `json:"finish_reason"`
 		}{
 			Text: "Hello, world!",
 		},
@@ -148,3 +152,152 @@ func TestAlibabaEmbedResponseWrapper_GetEmbeddings_Empty(t *testing.T) {
 
 	assert.Equal(t, [][]float32{}, resp.GetEmbeddings())
 }
+
+func TestAlibabaChatResponse_Usage(t *testing.T) {
+	var resp AlibabaChatResponse
+	err := json.Unmarshal([]byte(`{"output":{"text":"Hi","finish_reason":"stop"},"usage":{"input_tokens":9,"output_tokens":2}}`), &resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, Usage{PromptTokens: 9, CompletionTokens: 2, TotalTokens: 11}, resp.GetUsage())
+	assert.Equal(t, []string{"stop"}, resp.GetFinishReasons())
+}
+
+func TestAlibabaStrategy_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "enable", r.Header.Get("X-DashScope-SSE"))
+
+		var request map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"incremental_output": true}, request["parameters"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("id:1\nevent:result\ndata:{\"output\":{\"text\":\"Hi\",\"finish_reason\":\"null\"},\"usage\":{\"input_tokens\":5,\"output_tokens\":1}}\n\n"))
+		w.Write([]byte("id:2\nevent:result\ndata:{\"output\":{\"text\":\" there\",\"finish_reason\":\"stop\"},\"usage\":{\"input_tokens\":5,\"output_tokens\":2}}\n\n"))
+	}))
+	defer server.Close()
+
+	strategy, err := NewAlibabaStrategy(Config{APIKey: "test-api-key", ChatURL: server.URL})
+	require.NoError(t, err)
+
+	var deltas []string
+	options := &ChatOptions{
+		Model: "test-model",
+		StreamHandler: func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		},
+	}
+
+	resp, err := strategy.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}}, options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hi", " there"}, deltas)
+	assert.Equal(t, "Hi there", resp.GetContent())
+	assert.Equal(t, []string{"stop"}, FinishReasonsOf(resp))
+	usage, ok := UsageOf(resp)
+	require.True(t, ok)
+	assert.Equal(t, 7, usage.TotalTokens)
+}
+
+func TestAlibabaEmbedResponseWrapper_GetEmbeddings_Order(t *testing.T) {
+	var resp AlibabaEmbedResponseWrapper
+	err := json.Unmarshal([]byte(`{"output":{"embeddings":[{"text_index":1,"embedding":[0.4]},{"text_index":0,"embedding":[0.1]}]}}`), &resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]float32{{0.1}, {0.4}}, resp.GetEmbeddings())
+}
+
+func TestAlibabaStrategy_ExtraHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ws-123", r.Header.Get("X-DashScope-WorkSpace"))
+		if r.Header.Get("X-DashScope-SSE") == "enable" {
+			w.Write([]byte("data:{\"output\":{\"text\":\"Hi\",\"finish_reason\":\"stop\"}}\n\n"))
+			return
+		}
+		w.Write([]byte(`{"output":{"text":"Hi there"}}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewAlibabaStrategy(Config{
+		APIKey:       "test-api-key",
+		ChatURL:      server.URL,
+		ExtraHeaders: map[string]string{"X-DashScope-WorkSpace": "ws-123"},
+	})
+	require.NoError(t, err)
+
+	messages := []ChatMessage{{Role: "user", Content: "Hello"}}
+	_, err = strategy.Chat(context.Background(), messages, &ChatOptions{Model: "qwen-max"})
+	require.NoError(t, err)
+	resp, err := strategy.Chat(context.Background(), messages, &ChatOptions{Model: "qwen-max", StreamHandler: func(string) error { return nil }})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", resp.GetContent())
+}
+
+func TestAlibabaStrategy_EmbedParameters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, map[string]interface{}{"text_type": "query", "dimension": float64(512)}, request["parameters"])
+
+		w.Write([]byte(`{"output":{"embeddings":[{"text_index":0,"embedding":[0,3,4]}]}}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewAlibabaStrategy(Config{APIKey: "test-api-key", EmbedURL: server.URL})
+	require.NoError(t, err)
+
+	options := &EmbedOptions{Model: "text-embedding-v3", EmbeddingType: "query", Dimensions: 512, Normalize: true}
+	resp, err := strategy.Embed(context.Background(), []string{"text"}, options)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0, 0.6, 0.8}}, resp.GetEmbeddings())
+}
+
+func TestAlibabaStrategy_EmbedOmitsUnsupportedDimension(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		parameters, _ := request["parameters"].(map[string]interface{})
+		assert.NotContains(t, parameters, "dimension", "text-embedding-v2 has a fixed size")
+
+		w.Write([]byte(`{"output":{"embeddings":[{"text_index":0,"embedding":[3,4,12]}]}}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewAlibabaStrategy(Config{APIKey: "test-api-key", EmbedURL: server.URL})
+	require.NoError(t, err)
+
+	for _, dimensions := range []int{1536, 2} {
+		options := &EmbedOptions{Model: "text-embedding-v2", Dimensions: dimensions}
+		_, err := strategy.Embed(context.Background(), []string{"text"}, options)
+		require.NoError(t, err)
+	}
+}
+
+func TestAlibabaStrategy_EmbedSparse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "dense&sparse", request["parameters"].(map[string]interface{})["output_type"])
+
+		w.Write([]byte(`{"output":{"embeddings":[
+			{"text_index":1,"embedding":[0.4],"sparse_embedding":[{"index":7,"value":0.5,"token":"b"}]},
+			{"text_index":0,"embedding":[0.1],"sparse_embedding":[{"index":3,"value":0.25,"token":"a"},{"index":9,"value":1}]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewAlibabaStrategy(Config{APIKey: "test-api-key", EmbedURL: server.URL})
+	require.NoError(t, err)
+
+	options := &EmbedOptions{Model: "text-embedding-v3", OutputType: OutputTypeDenseAndSparse}
+	resp, err := strategy.Embed(context.Background(), []string{"a", "b"}, options)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1}, {0.4}}, resp.GetEmbeddings())
+
+	sparse, ok := SparseEmbeddingsOf(resp)
+	require.True(t, ok)
+	assert.Equal(t, []SparseVector{
+		{{Index: 3, Value: 0.25, Token: "a"}, {Index: 9, Value: 1}},
+		{{Index: 7, Value: 0.5, Token: "b"}},
+	}, sparse)
+}
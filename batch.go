@@ -0,0 +1,236 @@
+package llmconnector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BatchLimits bounds the texts sent in a single embedding request. Zero values mean unlimited.
+type BatchLimits struct {
+	MaxTexts  int
+	MaxTokens int
+}
+
+// defaultBatchLimits apply to models of a provider that are missing from the catalog.
+var defaultBatchLimits = map[string]BatchLimits{
+	"openai":  {MaxTexts: 2048, MaxTokens: 300000},
+	"alibaba": {MaxTexts: 10},
+}
+
+type batchConfig struct {
+	catalog     *Catalog
+	limits      map[catalogKey]BatchLimits
+	concurrency int
+	countTokens func(text string) int
+}
+
+// BatchOption configures BatchEmbed.
+type BatchOption func(c *batchConfig)
+
+// WithBatchCatalog sets the catalog the limits of a model are read from. Defaults to DefaultCatalog.
+func WithBatchCatalog(catalog *Catalog) BatchOption {
+	return func(c *batchConfig) {
+		c.catalog = catalog
+	}
+}
+
+// WithBatchLimits overrides the limits of model served by provider. An empty model sets the limits
+// of every model of provider missing from the catalog.
+func WithBatchLimits(provider, model string, limits BatchLimits) BatchOption {
+	return func(c *batchConfig) {
+		c.limits[catalogKey{provider, model}] = limits
+	}
+}
+
+// WithBatchConcurrency sets how many sub-batches are sent at once. Defaults to 4.
+func WithBatchConcurrency(concurrency int) BatchOption {
+	return func(c *batchConfig) {
+		c.concurrency = concurrency
+	}
+}
+
+// WithTokenCounter sets how the tokens of a text are counted against MaxTokens.
+// Defaults to the tokenizer of the model, see TokenizerFor.
+func WithTokenCounter(countTokens func(text string) int) BatchOption {
+	return func(c *batchConfig) {
+		c.countTokens = countTokens
+	}
+}
+
+// EstimateTokens is a conservative token count of text for tokenizers of the GPT and Qwen families:
+// a token per three bytes, which overestimates English text and matches most CJK characters.
+func EstimateTokens(text string) int {
+	return (len(text) + 2) / 3
+}
+
+// BatchEmbed returns a middleware splitting embed calls that exceed the batch limits of the provider and model
+// into sub-batches, sending them concurrently and reassembling the embeddings in input order with merged usage.
+// Limits are looked up in WithBatchLimits overrides, then in the catalog, then in the provider defaults.
+// Calls within the limits are passed through unchanged.
+func BatchEmbed(opts ...BatchOption) EmbedMiddleware {
+	c := batchConfig{
+		limits:      make(map[catalogKey]BatchLimits),
+		concurrency: 4,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.catalog == nil {
+		c.catalog = DefaultCatalog()
+	}
+	if c.concurrency < 1 {
+		c.concurrency = 1
+	}
+	return func(next EmbedHandler) EmbedHandler {
+		return func(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+			provider := ProviderFromContext(ctx)
+			countTokens := c.countTokens
+			if countTokens == nil {
+				countTokens = TokenizerFor(provider, options.Model).CountTokens
+			}
+			batches := splitBatches(texts, c.lookup(provider, options.Model), countTokens)
+			if len(batches) <= 1 {
+				return next(ctx, texts, options)
+			}
+			return c.embedBatches(ctx, next, texts, batches, options)
+		}
+	}
+}
+
+func (c *batchConfig) lookup(provider, model string) BatchLimits {
+	if limits, ok := c.limits[catalogKey{provider, model}]; ok {
+		return limits
+	}
+	if info, ok := c.catalog.Lookup(provider, model); ok && (info.MaxBatchSize > 0 || info.MaxBatchTokens > 0) {
+		return BatchLimits{MaxTexts: info.MaxBatchSize, MaxTokens: info.MaxBatchTokens}
+	}
+	if limits, ok := c.limits[catalogKey{provider, ""}]; ok {
+		return limits
+	}
+	return defaultBatchLimits[provider]
+}
+
+// batchRange is the half-open range of texts of a sub-batch.
+type batchRange struct {
+	start, end int
+}
+
+// splitBatches splits texts into consecutive ranges within limits. A single text exceeding MaxTokens
+// forms a batch of its own and is left for the provider to reject.
+func splitBatches(texts []string, limits BatchLimits, countTokens func(string) int) []batchRange {
+	var batches []batchRange
+	start, tokens := 0, 0
+	for i, text := range texts {
+		var textTokens int
+		if limits.MaxTokens > 0 {
+			textTokens = countTokens(text)
+		}
+		full := limits.MaxTexts > 0 && i-start >= limits.MaxTexts
+		if limits.MaxTokens > 0 && i > start && tokens+textTokens > limits.MaxTokens {
+			full = true
+		}
+		if full {
+			batches = append(batches, batchRange{start, i})
+			start, tokens = i, 0
+		}
+		tokens += textTokens
+	}
+	if start < len(texts) || len(texts) == 0 {
+		batches = append(batches, batchRange{start, len(texts)})
+	}
+	return batches
+}
+
+func (c *batchConfig) embedBatches(ctx context.Context, next EmbedHandler, texts []string, batches []batchRange, options *EmbedOptions) (EmbedResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &BatchEmbedResponse{
+		Embeddings: make([][]float32, len(texts)),
+		Responses:  make([]EmbedResponse, len(batches)),
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	semaphore := make(chan struct{}, c.concurrency)
+	for i, batch := range batches {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, batch batchRange) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			batchOptions := *options
+			resp, err := next(ctx, texts[batch.start:batch.end], &batchOptions)
+			if err == nil && len(resp.GetEmbeddings()) != batch.end-batch.start {
+				err = fmt.Errorf("batch of %d texts returned %d embeddings", batch.end-batch.start, len(resp.GetEmbeddings()))
+			}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			result.Responses[i] = resp
+			copy(result.Embeddings[batch.start:batch.end], resp.GetEmbeddings())
+		}(i, batch)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if options.wantsSparse() {
+		result.Sparse = make([]SparseVector, len(texts))
+	}
+	for i, resp := range result.Responses {
+		if sparse, ok := SparseEmbeddingsOf(resp); ok && result.Sparse != nil {
+			copy(result.Sparse[batches[i].start:batches[i].end], sparse)
+		}
+		if usage, ok := UsageOf(resp); ok {
+			result.Usage.PromptTokens += usage.PromptTokens
+			result.Usage.CompletionTokens += usage.CompletionTokens
+			result.Usage.TotalTokens += usage.TotalTokens
+		}
+	}
+	return result, nil
+}
+
+// BatchEmbedResponse is the merged response of an embed call split by BatchEmbed.
+type BatchEmbedResponse struct {
+	// Embeddings are in input order.
+	Embeddings [][]float32
+	// Usage is the sum of the usage of all sub-batches.
+	Usage Usage
+	// Sparse are the sparse vectors in input order, set when a sparse output type was requested.
+	Sparse []SparseVector
+	// Responses are the responses of the sub-batches in input order.
+	Responses []EmbedResponse
+}
+
+func (r *BatchEmbedResponse) GetEmbeddings() [][]float32 {
+	return r.Embeddings
+}
+
+func (r *BatchEmbedResponse) GetSparseEmbeddings() []SparseVector {
+	return r.Sparse
+}
+
+func (r *BatchEmbedResponse) GetUsage() Usage {
+	return r.Usage
+}
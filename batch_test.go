@@ -0,0 +1,190 @@
+package llmconnector
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchEmbedStrategy embeds every text as [index] and records the sizes of the batches it receives.
+type batchEmbedStrategy struct {
+	provider string
+	failOn   string
+
+	mu       sync.Mutex
+	batches  [][]string
+	inFlight int
+	peak     int
+}
+
+func (s *batchEmbedStrategy) ProviderName() string {
+	return s.provider
+}
+
+func (s *batchEmbedStrategy) Embed(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+	s.mu.Lock()
+	s.batches = append(s.batches, texts)
+	s.inFlight++
+	if s.inFlight > s.peak {
+		s.peak = s.inFlight
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	// Later batches finish first, so reassembly cannot rely on completion order.
+	index, _ := strconv.Atoi(texts[0])
+	select {
+	case <-time.After(time.Duration(100-index) * 100 * time.Microsecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	resp := &MockEmbedResponse{}
+	for _, text := range texts {
+		if text == s.failOn {
+			return nil, errors.New("batch failed")
+		}
+		index, _ := strconv.Atoi(text)
+		resp.Embeddings = append(resp.Embeddings, []float32{float32(index)})
+	}
+	return &usageEmbedResponse{MockEmbedResponse: resp, usage: Usage{PromptTokens: len(texts), TotalTokens: len(texts)}}, nil
+}
+
+type usageEmbedResponse struct {
+	*MockEmbedResponse
+	usage Usage
+}
+
+func (r *usageEmbedResponse) GetUsage() Usage {
+	return r.usage
+}
+
+func numberedTexts(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = strconv.Itoa(i)
+	}
+	return texts
+}
+
+func TestBatchEmbed_SplitsByCatalogLimits(t *testing.T) {
+	strategy := &batchEmbedStrategy{provider: "alibaba"}
+	mc := NewModelContext()
+	mc.SetEmbedStrategy(strategy)
+	mc.UseEmbed(BatchEmbed(WithBatchConcurrency(2)))
+
+	texts := numberedTexts(23)
+	resp, err := mc.Embed(context.Background(), texts, WithEmbedModel("text-embedding-v3"))
+	require.NoError(t, err)
+
+	assert.Len(t, strategy.batches, 3, "text-embedding-v3 accepts 10 texts per request")
+	assert.LessOrEqual(t, strategy.peak, 2)
+	embeddings := resp.GetEmbeddings()
+	require.Len(t, embeddings, len(texts))
+	for i, embedding := range embeddings {
+		assert.Equal(t, []float32{float32(i)}, embedding)
+	}
+	usage, ok := UsageOf(resp)
+	require.True(t, ok)
+	assert.Equal(t, Usage{PromptTokens: 23, TotalTokens: 23}, usage)
+}
+
+func TestBatchEmbed_SplitsByTokens(t *testing.T) {
+	strategy := &batchEmbedStrategy{provider: "custom"}
+	mc := NewModelContext()
+	mc.SetEmbedStrategy(strategy)
+	mc.UseEmbed(BatchEmbed(
+		WithBatchLimits("custom", "", BatchLimits{MaxTokens: 5}),
+		WithTokenCounter(func(string) int { return 2 }),
+	))
+
+	resp, err := mc.Embed(context.Background(), numberedTexts(5), WithEmbedModel("any"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"0", "1"}, {"2", "3"}, {"4"}}, sortedBatches(strategy.batches))
+	assert.Len(t, resp.GetEmbeddings(), 5)
+}
+
+func TestBatchEmbed_PassesThroughWithinLimits(t *testing.T) {
+	strategy := &batchEmbedStrategy{provider: "unknown"}
+	mc := NewModelContext()
+	mc.SetEmbedStrategy(strategy)
+	mc.UseEmbed(BatchEmbed())
+
+	resp, err := mc.Embed(context.Background(), numberedTexts(50), WithEmbedModel("any"))
+	require.NoError(t, err)
+	assert.Len(t, strategy.batches, 1)
+	assert.IsType(t, &usageEmbedResponse{}, resp)
+}
+
+func TestBatchEmbed_Error(t *testing.T) {
+	strategy := &batchEmbedStrategy{provider: "alibaba", failOn: "12"}
+	mc := NewModelContext()
+	mc.SetEmbedStrategy(strategy)
+	mc.UseEmbed(BatchEmbed())
+
+	_, err := mc.Embed(context.Background(), numberedTexts(30), WithEmbedModel("text-embedding-v3"))
+	assert.EqualError(t, err, "batch failed")
+}
+
+func TestSplitBatches(t *testing.T) {
+	count := func(text string) int { return len(text) }
+	assert.Equal(t, []batchRange{{0, 0}}, splitBatches(nil, BatchLimits{MaxTexts: 2}, count))
+	assert.Equal(t, []batchRange{{0, 3}}, splitBatches([]string{"a", "b", "c"}, BatchLimits{}, count))
+	assert.Equal(t, []batchRange{{0, 2}, {2, 3}}, splitBatches([]string{"a", "b", "c"}, BatchLimits{MaxTexts: 2}, count))
+	// A text over the token limit is sent alone.
+	assert.Equal(t, []batchRange{{0, 1}, {1, 2}, {2, 3}},
+		splitBatches([]string{"a", "toolong", "b"}, BatchLimits{MaxTokens: 3}, count))
+}
+
+func sortedBatches(batches [][]string) [][]string {
+	sorted := make([][]string, len(batches))
+	for _, batch := range batches {
+		index, _ := strconv.Atoi(batch[0])
+		sorted[index/2] = batch
+	}
+	return sorted
+}
+
+type sparseEmbedStrategy struct{}
+
+func (sparseEmbedStrategy) ProviderName() string {
+	return "alibaba"
+}
+
+func (sparseEmbedStrategy) Embed(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+	resp := &AlibabaEmbedResponseWrapper{}
+	for _, text := range texts {
+		index, _ := strconv.Atoi(text)
+		resp.Sparse = append(resp.Sparse, SparseVector{{Index: index, Value: 1}})
+		resp.Output.Embeddings = append(resp.Output.Embeddings, struct {
+			TextIndex int       `json:"text_index"`
+			Embedding []float32 `json:"embedding"`
+		}{TextIndex: len(resp.Output.Embeddings)})
+	}
+	return resp, nil
+}
+
+func TestBatchEmbed_MergesSparse(t *testing.T) {
+	mc := NewModelContext()
+	mc.SetEmbedStrategy(sparseEmbedStrategy{})
+	mc.UseEmbed(BatchEmbed())
+
+	resp, err := mc.Embed(context.Background(), numberedTexts(15),
+		WithEmbedModel("text-embedding-v3"), WithOutputType(OutputTypeSparse))
+	require.NoError(t, err)
+	sparse, ok := SparseEmbeddingsOf(resp)
+	require.True(t, ok)
+	require.Len(t, sparse, 15)
+	for i, vector := range sparse {
+		assert.Equal(t, SparseVector{{Index: i, Value: 1}}, vector)
+	}
+}
@@ -0,0 +1,369 @@
+// Package cassette records HTTP interactions of the llmconnector strategies into files and replays them,
+// so code using a ModelContext can be tested deterministically without network access.
+//
+// Plug a Recorder into a strategy through Config.Transport. In tests, New replays the cassette or records it
+// when it does not exist yet, and checks the recorder when the test ends:
+//
+//	recorder := cassette.New(t, "testdata/chat.json", cassette.WithSecrets(key))
+//	strategy, err := llmconnector.NewOpenAIStrategy(llmconnector.Config{APIKey: key, Transport: recorder})
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/simp-lee/llmconnector"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder talks to the network or serves recorded interactions.
+type Mode int
+
+const (
+	// ModeReplay serves recorded interactions and fails on requests that were not recorded.
+	ModeReplay Mode = iota
+	// ModeRecord forwards requests to the real transport and records every interaction.
+	ModeRecord
+	// ModeReplayOrRecord replays if the cassette file exists and records otherwise.
+	ModeReplayOrRecord
+)
+
+// ErrNoMatch is returned in replay mode for requests without a recorded interaction.
+// It is wrapped with backoff.Permanent, so strategies do not retry the request.
+var ErrNoMatch = errors.New("cassette: no recorded interaction matches request")
+
+const scrubbed = "[SCRUBBED]"
+
+// Cassette is the on-disk format of recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that records or replays interactions.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	secrets   []string
+	scrubbers []func(*Interaction)
+
+	mu        sync.Mutex
+	cassette  Cassette
+	used      []bool
+	unmatched []string
+}
+
+// Option configures a Recorder.
+type Option func(r *Recorder)
+
+// WithTransport sets the transport used in record mode. Defaults to http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithSecrets scrubs the given values, typically API keys, from recorded URLs, headers and bodies.
+func WithSecrets(secrets ...string) Option {
+	return func(r *Recorder) {
+		for _, secret := range secrets {
+			if secret != "" {
+				r.secrets = append(r.secrets, secret)
+			}
+		}
+	}
+}
+
+// WithScrubber adds a function that edits every interaction before it is saved.
+func WithScrubber(scrubber func(*Interaction)) Option {
+	return func(r *Recorder) {
+		r.scrubbers = append(r.scrubbers, scrubber)
+	}
+}
+
+// TB is the part of testing.TB used by New, so that importing the package does not link the testing package
+// into binaries.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// New returns a Recorder for the test t backed by the cassette file at path, in ModeReplayOrRecord: delete the
+// file to record it again. When the test ends, t fails on requests that matched no recorded interaction,
+// and recorded interactions are saved.
+func New(t TB, path string, opts ...Option) *Recorder {
+	t.Helper()
+	r, err := NewRecorder(path, ModeReplayOrRecord, opts...)
+	if err != nil {
+		t.Fatalf("%v", err)
+		return nil
+	}
+	t.Cleanup(func() {
+		if unmatched := r.Unmatched(); len(unmatched) > 0 {
+			t.Errorf("cassette: %d requests matched no interaction in %s:\n%s", len(unmatched), path, strings.Join(unmatched, "\n"))
+		}
+		if r.Mode() == ModeRecord {
+			if err := r.Save(); err != nil {
+				t.Errorf("%v", err)
+			}
+		}
+	})
+	return r
+}
+
+// NewRecorder creates a Recorder backed by the cassette file at path.
+// In replay mode the file must exist.
+func NewRecorder(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.mode == ModeReplayOrRecord {
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		} else {
+			r.mode = ModeRecord
+		}
+	}
+
+	if r.mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cassette: failed to read %s: %w", path, err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("cassette: failed to parse %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// Mode returns the effective mode of the recorder.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: failed to read request body: %w", err)
+		}
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	url := r.scrub(req.URL.String())
+	normalized := normalizeBody(r.scrub(string(body)))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Request.Method != req.Method || interaction.Request.URL != url {
+			continue
+		}
+		if normalizeBody(interaction.Request.Body) != normalized {
+			continue
+		}
+		r.used[i] = true
+		return interaction.Response.toHTTP(req), nil
+	}
+
+	description := fmt.Sprintf("%s %s %s", req.Method, url, normalized)
+	r.unmatched = append(r.unmatched, description)
+	return nil, backoff.Permanent(fmt.Errorf("%w: %s", ErrNoMatch, description))
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	outgoing := req.Clone(req.Context())
+	outgoing.Body = io.NopCloser(bytes.NewReader(body))
+	outgoing.ContentLength = int64(len(body))
+
+	resp, err := r.transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: failed to read response body: %w", err)
+	}
+
+	interaction := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: flattenHeaders(req.Header),
+			Body:    string(body),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Headers:    flattenHeaders(resp.Header),
+			Body:       string(respBody),
+		},
+	}
+	r.scrubInteraction(&interaction)
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the cassette file. It is a no-op in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("cassette: failed to encode: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("cassette: failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0o644); err != nil {
+		return fmt.Errorf("cassette: failed to write %s: %w", r.path, err)
+	}
+	return nil
+}
+
+// Unmatched returns the requests that could not be served in replay mode.
+func (r *Recorder) Unmatched() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.unmatched...)
+}
+
+// Unused returns the number of recorded interactions that were not replayed.
+func (r *Recorder) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, used := range r.used {
+		if !used {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Recorder) scrub(value string) string {
+	for _, secret := range r.secrets {
+		value = strings.ReplaceAll(value, secret, scrubbed)
+	}
+	return value
+}
+
+func (r *Recorder) scrubInteraction(interaction *Interaction) {
+	interaction.Request.URL = r.scrub(interaction.Request.URL)
+	interaction.Request.Body = r.scrub(interaction.Request.Body)
+	interaction.Response.Body = r.scrub(interaction.Response.Body)
+	for _, headers := range []map[string]string{interaction.Request.Headers, interaction.Response.Headers} {
+		for key, value := range headers {
+			if llmconnector.IsSensitiveHeader(key) {
+				headers[key] = scrubbed
+			} else {
+				headers[key] = r.scrub(value)
+			}
+		}
+	}
+	for _, scrubber := range r.scrubbers {
+		scrubber(interaction)
+	}
+}
+
+func (resp Response) toHTTP(req *http.Request) *http.Response {
+	header := make(http.Header, len(resp.Headers))
+	for key, value := range resp.Headers {
+		header.Set(key, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}
+}
+
+// normalizeBody re-encodes JSON bodies so that key order and whitespace do not affect matching.
+func normalizeBody(body string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		return body
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return string(normalized)
+}
+
+func flattenHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(header))
+	for key, values := range header {
+		out[key] = strings.Join(values, ",")
+	}
+	return out
+}
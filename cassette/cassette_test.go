@@ -0,0 +1,160 @@
+package cassette
+
+import (
+	"context"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi there"}}]}`))
+	}))
+}
+
+func chat(t *testing.T, recorder *Recorder, url string, content string) (llmconnector.ChatResponse, error) {
+	commonConfig := llmconnector.DefaultCommonConfig()
+	commonConfig.Retries = 1
+	strategy, err := llmconnector.NewOpenAIStrategy(llmconnector.Config{
+		APIKey:       "sk-secret-key",
+		ChatURL:      url,
+		Transport:    recorder,
+		CommonConfig: commonConfig,
+	})
+	require.NoError(t, err)
+
+	return strategy.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: content}},
+		&llmconnector.ChatOptions{Model: "test-model"})
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := newServer(t)
+	path := filepath.Join(t.TempDir(), "chat.json")
+
+	recorder, err := NewRecorder(path, ModeRecord, WithSecrets("sk-secret-key"))
+	require.NoError(t, err)
+	resp, err := chat(t, recorder, server.URL, "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", resp.GetContent())
+	require.NoError(t, recorder.Save())
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-secret-key")
+	assert.Contains(t, string(data), scrubbed)
+
+	replayer, err := NewRecorder(path, ModeReplay)
+	require.NoError(t, err)
+	resp, err = chat(t, replayer, server.URL, "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", resp.GetContent())
+	assert.Equal(t, 0, replayer.Unused())
+}
+
+func TestReplay_Unmatched(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "chat.json")
+
+	recorder, err := NewRecorder(path, ModeRecord)
+	require.NoError(t, err)
+	_, err = chat(t, recorder, server.URL, "Hello")
+	require.NoError(t, err)
+	require.NoError(t, recorder.Save())
+
+	replayer, err := NewRecorder(path, ModeReplay)
+	require.NoError(t, err)
+	_, err = chat(t, replayer, server.URL, "Goodbye")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ErrNoMatch.Error())
+	// Unmatched requests are not retried.
+	require.Len(t, replayer.Unmatched(), 1)
+	assert.Contains(t, replayer.Unmatched()[0], "Goodbye")
+	assert.Equal(t, 1, replayer.Unused())
+}
+
+func TestReplay_MissingCassette(t *testing.T) {
+	_, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), ModeReplay)
+	assert.Error(t, err)
+}
+
+func TestReplayOrRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.json")
+
+	recorder, err := NewRecorder(path, ModeReplayOrRecord)
+	require.NoError(t, err)
+	assert.Equal(t, ModeRecord, recorder.Mode())
+	require.NoError(t, recorder.Save())
+
+	replayer, err := NewRecorder(path, ModeReplayOrRecord)
+	require.NoError(t, err)
+	assert.Equal(t, ModeReplay, replayer.Mode())
+}
+
+var _ TB = testing.TB(nil)
+
+// fakeTB records the failures and cleanups of New.
+type fakeTB struct {
+	cleanups []func()
+	errors   []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Cleanup(cleanup func()) {
+	f.cleanups = append(f.cleanups, cleanup)
+}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatalf(format string, args ...interface{}) {
+	f.Errorf(format, args...)
+}
+
+func (f *fakeTB) end() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestNew(t *testing.T) {
+	server := newServer(t)
+	path := filepath.Join(t.TempDir(), "chat.json")
+
+	recording := &fakeTB{}
+	recorder := New(recording, path, WithSecrets("sk-secret-key"))
+	assert.Equal(t, ModeRecord, recorder.Mode())
+	_, err := chat(t, recorder, server.URL, "Hello")
+	require.NoError(t, err)
+	recording.end()
+	assert.Empty(t, recording.errors)
+	server.Close()
+
+	replaying := &fakeTB{}
+	replayer := New(replaying, path)
+	assert.Equal(t, ModeReplay, replayer.Mode())
+	_, err = chat(t, replayer, server.URL, "Hello")
+	require.NoError(t, err)
+	_, err = chat(t, replayer, server.URL, "Goodbye")
+	require.Error(t, err)
+	replaying.end()
+	require.Len(t, replaying.errors, 1)
+	assert.Contains(t, replaying.errors[0], "1 requests matched no interaction")
+	assert.Contains(t, replaying.errors[0], "Goodbye")
+}
+
+func TestNormalizeBody(t *testing.T) {
+	assert.Equal(t, normalizeBody(`{"b":1, "a":[1,2]}`), normalizeBody(`{"a":[1,2],"b":1}`))
+	assert.Equal(t, "not json", normalizeBody("not json"))
+}
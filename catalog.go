@@ -0,0 +1,300 @@
+package llmconnector
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+//go:embed catalog.json
+var builtinCatalog []byte
+
+// Model types of a catalog entry.
+const (
+	ModelTypeChat      = "chat"
+	ModelTypeEmbedding = "embedding"
+)
+
+// Capability is a feature a model supports.
+type Capability string
+
+const (
+	CapabilityStreaming Capability = "streaming"
+	CapabilityTools     Capability = "tools"
+	CapabilityVision    Capability = "vision"
+	CapabilityJSONMode  Capability = "json_mode"
+	// CapabilityDimensions marks embedding models that can return shortened vectors.
+	CapabilityDimensions Capability = "dimensions"
+	// CapabilitySparse marks embedding models that can return sparse vectors.
+	CapabilitySparse Capability = "sparse"
+)
+
+// ModelInfo describes a model. Prices are in USD per million tokens.
+type ModelInfo struct {
+	Provider string   `json:"provider"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Aliases  []string `json:"aliases,omitempty"`
+	// Tokenizer is the name of the encoding the model tokenizes text with, see RegisterTokenizer.
+	Tokenizer string `json:"tokenizer,omitempty"`
+
+	ContextWindow   int `json:"context_window,omitempty"`
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+
+	// EmbeddingDimensions is the default size of the vectors of an embedding model.
+	EmbeddingDimensions int `json:"embedding_dimensions,omitempty"`
+	// SupportedDimensions lists the sizes a model with CapabilityDimensions accepts.
+	// When empty, any size up to EmbeddingDimensions is accepted.
+	SupportedDimensions []int `json:"supported_dimensions,omitempty"`
+
+	Capabilities []Capability `json:"capabilities,omitempty"`
+
+	// MaxBatchSize is the maximum number of texts of an embedding request, 0 when unlimited.
+	MaxBatchSize int `json:"max_batch_size,omitempty"`
+	// MaxBatchTokens is the maximum number of tokens of all texts of an embedding request, 0 when unlimited.
+	MaxBatchTokens int `json:"max_batch_tokens,omitempty"`
+
+	InputPrice  float64 `json:"input_price,omitempty"`
+	OutputPrice float64 `json:"output_price,omitempty"`
+}
+
+// Has reports whether the model supports capability.
+func (m ModelInfo) Has(capability Capability) bool {
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsDimensions reports whether the embedding model can return vectors of the given size.
+func (m ModelInfo) SupportsDimensions(dimensions int) bool {
+	if dimensions == m.EmbeddingDimensions {
+		return true
+	}
+	if !m.Has(CapabilityDimensions) || dimensions <= 0 {
+		return false
+	}
+	if len(m.SupportedDimensions) == 0 {
+		return m.EmbeddingDimensions == 0 || dimensions <= m.EmbeddingDimensions
+	}
+	for _, supported := range m.SupportedDimensions {
+		if supported == dimensions {
+			return true
+		}
+	}
+	return false
+}
+
+// Cost returns the price of usage in USD.
+func (m ModelInfo) Cost(usage Usage) float64 {
+	return (float64(usage.PromptTokens)*m.InputPrice + float64(usage.CompletionTokens)*m.OutputPrice) / 1e6
+}
+
+type catalogKey struct {
+	provider string
+	name     string
+}
+
+// Catalog is a concurrency-safe set of model descriptions looked up by provider and model name or alias.
+type Catalog struct {
+	mu      sync.RWMutex
+	models  map[catalogKey]ModelInfo
+	aliases map[catalogKey]string
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{
+		models:  make(map[catalogKey]ModelInfo),
+		aliases: make(map[catalogKey]string),
+	}
+}
+
+var (
+	defaultCatalog     *Catalog
+	defaultCatalogOnce sync.Once
+)
+
+// DefaultCatalog returns the shared catalog of known OpenAI and Alibaba models. Entries added to it
+// override the built-in ones for every user of the default catalog.
+func DefaultCatalog() *Catalog {
+	defaultCatalogOnce.Do(func() {
+		defaultCatalog = NewCatalog()
+		if err := defaultCatalog.Load(bytes.NewReader(builtinCatalog)); err != nil {
+			panic("llmconnector: invalid built-in catalog: " + err.Error())
+		}
+	})
+	return defaultCatalog
+}
+
+// Load adds the models of a JSON document of the form {"models": [...]}, replacing existing entries.
+func (c *Catalog) Load(r io.Reader) error {
+	var document struct {
+		Models []ModelInfo `json:"models"`
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("failed to decode catalog: %w", err)
+	}
+	for _, model := range document.Models {
+		if err := c.Add(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add adds model, replacing an existing entry with the same provider and name.
+func (c *Catalog) Add(model ModelInfo) error {
+	if model.Provider == "" || model.Name == "" {
+		return fmt.Errorf("catalog entry needs a provider and a name: %+v", model)
+	}
+	if model.Type != ModelTypeChat && model.Type != ModelTypeEmbedding {
+		return fmt.Errorf("catalog entry %s/%s has unknown type %q", model.Provider, model.Name, model.Type)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.models[catalogKey{model.Provider, model.Name}] = model
+	for _, alias := range model.Aliases {
+		c.aliases[catalogKey{model.Provider, alias}] = model.Name
+	}
+	return nil
+}
+
+// Lookup returns the description of model served by provider. With an empty provider, all providers
+// are searched in name order.
+func (c *Catalog) Lookup(provider, model string) (ModelInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if provider == "" {
+		for _, candidate := range c.providers() {
+			if info, ok := c.lookup(candidate, model); ok {
+				return info, true
+			}
+		}
+		return ModelInfo{}, false
+	}
+	return c.lookup(provider, model)
+}
+
+func (c *Catalog) lookup(provider, model string) (ModelInfo, bool) {
+	if name, ok := c.aliases[catalogKey{provider, model}]; ok {
+		model = name
+	}
+	info, ok := c.models[catalogKey{provider, model}]
+	return info, ok
+}
+
+func (c *Catalog) providers() []string {
+	seen := make(map[string]bool)
+	for key := range c.models {
+		seen[key.provider] = true
+	}
+	providers := make([]string, 0, len(seen))
+	for provider := range seen {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// Models returns the models of provider, or of all providers when provider is empty, sorted by provider and name.
+func (c *Catalog) Models(provider string) []ModelInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var models []ModelInfo
+	for key, info := range c.models {
+		if provider == "" || key.provider == provider {
+			models = append(models, info)
+		}
+	}
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].Provider != models[j].Provider {
+			return models[i].Provider < models[j].Provider
+		}
+		return models[i].Name < models[j].Name
+	})
+	return models
+}
+
+// ErrInvalidOptions is returned, wrapped, when options are not supported by the catalog entry of the model.
+var ErrInvalidOptions = errors.New("options not supported by model")
+
+// ValidateChat checks options against the catalog entry of their model. Models missing from the catalog pass.
+func (c *Catalog) ValidateChat(provider string, options *ChatOptions) error {
+	info, ok := c.Lookup(provider, options.Model)
+	if !ok {
+		return nil
+	}
+	if info.Type != ModelTypeChat {
+		return fmt.Errorf("%w: %s is not a chat model", ErrInvalidOptions, info.Name)
+	}
+	if options.MaxTokens != nil && info.MaxOutputTokens > 0 && *options.MaxTokens > info.MaxOutputTokens {
+		return fmt.Errorf("%w: max tokens %d exceeds the %d output tokens of %s",
+			ErrInvalidOptions, *options.MaxTokens, info.MaxOutputTokens, info.Name)
+	}
+	if options.StreamHandler != nil && !info.Has(CapabilityStreaming) {
+		return fmt.Errorf("%w: %s does not support streaming", ErrInvalidOptions, info.Name)
+	}
+	return nil
+}
+
+// ValidateEmbed checks options against the catalog entry of their model. Models missing from the catalog pass.
+func (c *Catalog) ValidateEmbed(provider string, options *EmbedOptions) error {
+	info, ok := c.Lookup(provider, options.Model)
+	if !ok {
+		return nil
+	}
+	if info.Type != ModelTypeEmbedding {
+		return fmt.Errorf("%w: %s is not an embedding model", ErrInvalidOptions, info.Name)
+	}
+	if options.Dimensions > 0 && info.EmbeddingDimensions > 0 && options.Dimensions > info.EmbeddingDimensions {
+		return fmt.Errorf("%w: %d dimensions exceed the %d of %s",
+			ErrInvalidOptions, options.Dimensions, info.EmbeddingDimensions, info.Name)
+	}
+	if options.wantsSparse() && !info.Has(CapabilitySparse) {
+		return fmt.Errorf("%w: %s does not support sparse vectors", ErrInvalidOptions, info.Name)
+	}
+	return nil
+}
+
+// ValidateChatOptions returns a middleware rejecting calls whose options the catalog entry of the model
+// does not support, before anything is sent. A nil catalog means DefaultCatalog.
+func ValidateChatOptions(catalog *Catalog) ChatMiddleware {
+	if catalog == nil {
+		catalog = DefaultCatalog()
+	}
+	return func(next ChatHandler) ChatHandler {
+		return func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+			if err := catalog.ValidateChat(ProviderFromContext(ctx), options); err != nil {
+				return nil, err
+			}
+			return next(ctx, chatMessages, options)
+		}
+	}
+}
+
+// ValidateEmbedOptions returns a middleware rejecting calls whose options the catalog entry of the model
+// does not support, before anything is sent. A nil catalog means DefaultCatalog.
+func ValidateEmbedOptions(catalog *Catalog) EmbedMiddleware {
+	if catalog == nil {
+		catalog = DefaultCatalog()
+	}
+	return func(next EmbedHandler) EmbedHandler {
+		return func(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+			if err := catalog.ValidateEmbed(ProviderFromContext(ctx), options); err != nil {
+				return nil, err
+			}
+			return next(ctx, texts, options)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package llmconnector
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	catalog := DefaultCatalog()
+
+	info, ok := catalog.Lookup("openai", "gpt-4o-2024-08-06")
+	require.True(t, ok)
+	assert.Equal(t, "gpt-4o", info.Name)
+	assert.Equal(t, 128000, info.ContextWindow)
+	assert.True(t, info.Has(CapabilityTools))
+
+	info, ok = catalog.Lookup("", "text-embedding-v3")
+	require.True(t, ok)
+	assert.Equal(t, "alibaba", info.Provider)
+	assert.True(t, info.SupportsDimensions(512))
+	assert.False(t, info.SupportsDimensions(300))
+
+	info, ok = catalog.Lookup("openai", "text-embedding-3-small")
+	require.True(t, ok)
+	assert.True(t, info.SupportsDimensions(300))
+	assert.False(t, info.SupportsDimensions(2048))
+
+	_, ok = catalog.Lookup("alibaba", "gpt-4o")
+	assert.False(t, ok)
+
+	for _, model := range catalog.Models("") {
+		assert.NotEmpty(t, model.Type, model.Name)
+		if model.Type == ModelTypeChat {
+			// Every built-in chat model streams, so catalog validation never rejects a StreamHandler for them.
+			assert.True(t, model.Has(CapabilityStreaming), model.Name)
+		}
+	}
+	assert.NotEmpty(t, catalog.Models("alibaba"))
+}
+
+func TestCatalog_Load(t *testing.T) {
+	catalog := NewCatalog()
+	err := catalog.Load(strings.NewReader(`{"models":[{"provider":"custom","name":"m1","type":"chat","max_output_tokens":100,"input_price":1,"output_price":2}]}`))
+	require.NoError(t, err)
+
+	info, ok := catalog.Lookup("custom", "m1")
+	require.True(t, ok)
+	assert.InDelta(t, 0.000005, info.Cost(Usage{PromptTokens: 1, CompletionTokens: 2}), 1e-12)
+
+	require.NoError(t, catalog.Add(ModelInfo{Provider: "custom", Name: "m1", Type: ModelTypeChat, MaxOutputTokens: 200}))
+	info, _ = catalog.Lookup("custom", "m1")
+	assert.Equal(t, 200, info.MaxOutputTokens)
+
+	assert.Error(t, catalog.Load(strings.NewReader(`{"models":[{"provider":"custom","name":"m2","type":"audio"}]}`)))
+	assert.Error(t, catalog.Load(strings.NewReader(`{"models":[{"provider":"custom","name":"m3","type":"chat","price":1}]}`)))
+}
+
+func TestCatalog_Validate(t *testing.T) {
+	catalog := DefaultCatalog()
+	maxTokens := 100000
+
+	err := catalog.ValidateChat("openai", &ChatOptions{Model: "gpt-4o", MaxTokens: &maxTokens})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	err = catalog.ValidateChat("openai", &ChatOptions{Model: "text-embedding-3-small"})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	stream := func(string) error { return nil }
+	assert.NoError(t, catalog.ValidateChat("openai", &ChatOptions{Model: "o1", StreamHandler: stream}))
+	custom := NewCatalog()
+	require.NoError(t, custom.Add(ModelInfo{Provider: "custom", Name: "batch-only", Type: ModelTypeChat}))
+	err = custom.ValidateChat("custom", &ChatOptions{Model: "batch-only", StreamHandler: stream})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+	assert.NoError(t, catalog.ValidateChat("openai", &ChatOptions{Model: "unknown-model", MaxTokens: &maxTokens}))
+
+	assert.ErrorIs(t, catalog.ValidateEmbed("alibaba", &EmbedOptions{Model: "qwen-max"}), ErrInvalidOptions)
+	assert.NoError(t, catalog.ValidateEmbed("alibaba", &EmbedOptions{Model: "text-embedding-v2"}))
+}
+
+func TestValidateChatOptions(t *testing.T) {
+	ctx := NewModelContext()
+	ctx.SetChatStrategy(&MockChatStrategy{})
+	ctx.UseChat(ValidateChatOptions(nil))
+
+	_, err := ctx.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}},
+		WithChatModel("gpt-4"), WithMaxTokens(10000))
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	_, err = ctx.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}},
+		WithChatModel("gpt-4"), WithMaxTokens(1000))
+	assert.NoError(t, err)
+}
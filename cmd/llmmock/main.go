@@ -0,0 +1,54 @@
+// Command llmmock runs a local mock of the OpenAI and DashScope HTTP APIs for integration tests.
+//
+// Usage:
+//
+//	llmmock -addr :8089 -api-key test -latency 50ms -error-rate 0.05 -script replies.json
+//
+// Point OpenAIStrategy at http://localhost:8089/v1/chat/completions and /v1/embeddings, or
+// AlibabaStrategy at http://localhost:8089/api/v1/services/chat/completions and
+// /api/v1/services/embeddings/text-embedding.
+package main
+
+import (
+	"flag"
+	"github.com/simp-lee/llmconnector/llmmock"
+	"log"
+	"net/http"
+	"os"
+)
+
+func main() {
+	addr := flag.String("addr", ":8089", "address to listen on")
+	apiKey := flag.String("api-key", "", "bearer token required on every request, if set")
+	latency := flag.Duration("latency", 0, "latency added to every response")
+	errorRate := flag.Float64("error-rate", 0, "probability in [0, 1] of answering with -error-status")
+	errorStatus := flag.Int("error-status", http.StatusInternalServerError, "HTTP status of injected errors")
+	dimensions := flag.Int("dimensions", 0, "size of generated embeddings")
+	seed := flag.Int64("seed", 0, "seed for injected errors")
+	script := flag.String("script", "", "JSON file with scripted chat and embed replies")
+	flag.Parse()
+
+	server := llmmock.New(llmmock.Options{
+		APIKey:      *apiKey,
+		Latency:     *latency,
+		ErrorRate:   *errorRate,
+		ErrorStatus: *errorStatus,
+		Dimensions:  *dimensions,
+		Seed:        *seed,
+	})
+
+	if *script != "" {
+		file, err := os.Open(*script)
+		if err != nil {
+			log.Fatalf("failed to open script: %v", err)
+		}
+		err = server.LoadScript(file)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	log.Printf("llmmock listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
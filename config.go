@@ -1,24 +1,68 @@
 package llmconnector
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/simp-lee/gohttpclient"
+	"log/slog"
+	"net/http"
 	"net/url"
+	"sync/atomic"
 	"time"
 )
 
 // Config is a set of configuration options for all clients.
 type Config struct {
-	APIKey   string
+	APIKey string
+	// Credentials, when set, supplies the API key for every request instead of APIKey.
+	Credentials CredentialProvider
+
 	ChatURL  string
 	EmbedURL string
+
+	// ChatModel and EmbedModel are sent by calls that do not set a model.
+	ChatModel  string
+	EmbedModel string
+
+	// HTTPClient, when set, provides the transport, timeout, cookie jar and redirect policy of the chat and
+	// embed clients, e.g. for mTLS or a corporate proxy. Retries and rate limits of CommonConfig still apply.
+	HTTPClient *http.Client
+	// Transport replaces the HTTP transport of the chat and embed clients, e.g. for recording or replaying traffic.
+	// It takes precedence over the transport of HTTPClient.
+	// Proxy and connection pool settings of CommonConfig do not apply to a custom transport.
+	Transport http.RoundTripper
+	// ExtraHeaders are sent with every request, e.g. OpenAI-Organization, OpenAI-Project or X-DashScope-WorkSpace.
+	// They override the default headers of the same name.
+	ExtraHeaders map[string]string
 	CommonConfig
 }
 
+// requestHeaders returns the headers sent with every request: the static API key and ExtraHeaders.
+// Keys of a CredentialProvider are set per attempt by authorize.
+func (c *Config) requestHeaders() map[string]string {
+	headers := make(map[string]string)
+	if c.Credentials == nil {
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", c.APIKey)
+	}
+	for key, value := range c.ExtraHeaders {
+		headers[http.CanonicalHeaderKey(key)] = value
+	}
+	return headers
+}
+
 // CommonConfig is a set of common configuration options for all clients.
 type CommonConfig struct {
 	Timeout time.Duration
+	// Retries is the number of times a failed request is retried, with exponential backoff. Streams are not retried.
 	Retries int
+	// RetryInterval is the wait before the first retry, growing exponentially for the next ones. Defaults to 500ms.
+	RetryInterval time.Duration
+	// StreamIdleTimeout limits the wait for the first byte of a stream and between its events. Timeout, which
+	// limits whole requests, does not apply to streams. Defaults to the timeout of the client.
+	StreamIdleTimeout time.Duration
 
 	// the maximum number of requests allowed per second.
 	MaxNumRequestPerSecond float64
@@ -30,6 +74,13 @@ type CommonConfig struct {
 	MaxIdleConns    int
 	MaxConnsPerHost int
 	IdleConnTimeout time.Duration
+
+	// Logger receives request logs. Nothing is logged when it is nil.
+	Logger *slog.Logger
+	// LogLevel is the level of request start and end logs. Failed requests are always logged at error level.
+	LogLevel slog.Level
+	// LogBodies logs request and response bodies at debug level. Credentials are redacted.
+	LogBodies bool
 }
 
 // DefaultCommonConfig returns a default set of common configuration options.
@@ -46,16 +97,20 @@ func DefaultCommonConfig() CommonConfig {
 }
 
 // createClient creates a new HTTP client with the given configuration options.
-func createClient(config CommonConfig, apiKey string) (*gohttpclient.Client, error) {
+func createClient(config Config, logger *requestLogger) (*gohttpclient.Client, error) {
 	var options []gohttpclient.ClientOption
 
+	if logger != nil {
+		options = append(options, gohttpclient.WithLogger(&slogAdapter{logger: logger.logger, redact: logger.redact}))
+	}
+
 	if config.Timeout > 0 {
 		options = append(options, gohttpclient.WithTimeout(config.Timeout))
 	}
 
-	if config.Retries > 0 {
-		options = append(options, gohttpclient.WithRetries(config.Retries))
-	}
+	// Retries are made by post with a backoff per request, see newBackOff. gohttpclient shares a single backoff
+	// between the concurrent requests of a client.
+	options = append(options, gohttpclient.WithRetries(0), gohttpclient.WithBackoff(&backoff.StopBackOff{}))
 
 	if config.MaxNumRequestPerLimit > 0 && config.MaxNumRequestPerSecond > 0 {
 		options = append(options, gohttpclient.WithRateLimit(config.MaxNumRequestPerSecond, config.MaxNumRequestPerLimit))
@@ -82,8 +137,89 @@ func createClient(config CommonConfig, apiKey string) (*gohttpclient.Client, err
 	}
 
 	client := gohttpclient.NewClient(options...)
-	client.SetHeader("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	if config.HTTPClient != nil {
+		if config.HTTPClient.Transport != nil {
+			client.Transport = config.HTTPClient.Transport
+		}
+		if config.HTTPClient.Timeout > 0 {
+			client.Timeout = config.HTTPClient.Timeout
+		}
+		client.Jar = config.HTTPClient.Jar
+		client.CheckRedirect = config.HTTPClient.CheckRedirect
+	}
+	if config.Transport != nil {
+		client.Transport = config.Transport
+	}
 	client.SetHeader("Content-Type", "application/json")
+	for key, value := range config.requestHeaders() {
+		client.SetHeader(key, value)
+	}
+	client.AddRequestInterceptor(countAttempt)
+	if config.Credentials != nil {
+		client.AddRequestInterceptor(config.authorize)
+		client.AddResponseInterceptor(config.invalidateOnUnauthorized)
+	}
+	if logger != nil {
+		client.AddRequestInterceptor(logger.logHTTPRequest)
+		client.AddResponseInterceptor(logger.logHTTPResponse)
+	}
 
 	return client, nil
 }
+
+type attemptsContextKey struct{}
+
+// TrackAttempts returns a context in which HTTP attempts made by the built-in strategies are counted,
+// and a function reporting the number of attempts so far. Retries of a call are attempts minus one.
+func TrackAttempts(ctx context.Context) (context.Context, func() int) {
+	counter := new(int64)
+	return context.WithValue(ctx, attemptsContextKey{}, counter), func() int {
+		return int(atomic.LoadInt64(counter))
+	}
+}
+
+// countAttempt is a request interceptor that runs once per attempt, including retries.
+func countAttempt(req *http.Request) error {
+	if counter, ok := req.Context().Value(attemptsContextKey{}).(*int64); ok {
+		atomic.AddInt64(counter, 1)
+	}
+	return nil
+}
+
+// newBackOff returns the backoff between the attempts of one request: exponential, starting at c.RetryInterval,
+// and limited to c.Retries retries.
+func (c *Config) newBackOff(ctx context.Context) backoff.BackOff {
+	exponential := backoff.NewExponentialBackOff()
+	if c.RetryInterval > 0 {
+		exponential.InitialInterval = c.RetryInterval
+	}
+	return backoff.WithContext(backoff.WithMaxRetries(exponential, uint64(max(c.Retries, 0))), ctx)
+}
+
+// post sends request as JSON through client, retrying failed attempts with a backoff of its own.
+// Errors of a transport wrapped with backoff.Permanent are not retried.
+func post(ctx context.Context, client *gohttpclient.Client, config *Config, url string, request interface{}) ([]byte, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var resp []byte
+	err = backoff.Retry(func() error {
+		resp, err = client.Post(ctx, url, body)
+		if isPermanent(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, config.newBackOff(ctx))
+	return resp, err
+}
+
+// isPermanent reports whether the transport marked the failure of an attempt as permanent.
+func isPermanent(err error) bool {
+	var clientErr *gohttpclient.ClientError
+	if !errors.As(err, &clientErr) {
+		return false
+	}
+	var permanent *backoff.PermanentError
+	return errors.As(clientErr.Err, &permanent)
+}
@@ -0,0 +1,356 @@
+package llmconnector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EnvPrefix prefixes the environment variables that override a loaded FileConfig:
+// LLM_DEFAULT_PROVIDER and, for a provider named "openai", LLM_OPENAI_API_KEY, LLM_OPENAI_CHAT_URL,
+// LLM_OPENAI_EMBED_URL, LLM_OPENAI_CHAT_MODEL and LLM_OPENAI_EMBED_MODEL. An API key override also
+// replaces api_key_file and api_key_command.
+const EnvPrefix = "LLM_"
+
+// Duration is a time.Duration read from configuration files as a string such as "30s".
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// FileConfig describes named providers, as read from a YAML or JSON configuration file:
+//
+//	default_provider: openai
+//	providers:
+//	  openai:
+//	    type: openai
+//	    api_key_env: OPENAI_API_KEY
+//	    chat_model: gpt-4o
+//	    timeout: 30s
+//	  qwen:
+//	    type: alibaba
+//	    api_key_env: DASHSCOPE_API_KEY
+//	    chat_model: qwen-max
+type FileConfig struct {
+	DefaultProvider string                    `json:"default_provider" yaml:"default_provider"`
+	Providers       map[string]ProviderConfig `json:"providers" yaml:"providers"`
+}
+
+// ProviderConfig configures one provider of a FileConfig. Zero values keep the defaults of the strategy
+// and of DefaultCommonConfig.
+type ProviderConfig struct {
+	// Type is the registered provider type of the strategy to create, e.g. "openai" or "alibaba".
+	Type string `json:"type" yaml:"type"`
+	// APIKey is the API key. Prefer APIKeyEnv so that keys stay out of configuration files.
+	APIKey string `json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	// APIKeyEnv names the environment variable holding the API key.
+	APIKeyEnv string `json:"api_key_env,omitempty" yaml:"api_key_env,omitempty"`
+	// APIKeyFile names a file holding the API key. It is reloaded when it changes.
+	APIKeyFile string `json:"api_key_file,omitempty" yaml:"api_key_file,omitempty"`
+	// APIKeyCommand is a command printing the API key, run again after APIKeyTTL (default 15m) or a 401 response.
+	APIKeyCommand []string `json:"api_key_command,omitempty" yaml:"api_key_command,omitempty"`
+	APIKeyTTL     Duration `json:"api_key_ttl,omitempty" yaml:"api_key_ttl,omitempty"`
+	ChatURL       string   `json:"chat_url,omitempty" yaml:"chat_url,omitempty"`
+	EmbedURL      string   `json:"embed_url,omitempty" yaml:"embed_url,omitempty"`
+	// Headers are sent with every request, see Config.ExtraHeaders.
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+
+	// ChatModel and EmbedModel are used by calls that do not set a model.
+	ChatModel  string `json:"chat_model,omitempty" yaml:"chat_model,omitempty"`
+	EmbedModel string `json:"embed_model,omitempty" yaml:"embed_model,omitempty"`
+
+	Timeout                Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	StreamIdleTimeout      Duration `json:"stream_idle_timeout,omitempty" yaml:"stream_idle_timeout,omitempty"`
+	Retries                int      `json:"retries,omitempty" yaml:"retries,omitempty"`
+	MaxNumRequestPerSecond float64  `json:"max_requests_per_second,omitempty" yaml:"max_requests_per_second,omitempty"`
+	MaxNumRequestPerLimit  int      `json:"max_concurrent_requests,omitempty" yaml:"max_concurrent_requests,omitempty"`
+	ProxyURL               string   `json:"proxy_url,omitempty" yaml:"proxy_url,omitempty"`
+	MaxIdleConns           int      `json:"max_idle_conns,omitempty" yaml:"max_idle_conns,omitempty"`
+	MaxConnsPerHost        int      `json:"max_conns_per_host,omitempty" yaml:"max_conns_per_host,omitempty"`
+	IdleConnTimeout        Duration `json:"idle_conn_timeout,omitempty" yaml:"idle_conn_timeout,omitempty"`
+}
+
+// LoadConfigFile reads a YAML (.yaml, .yml) or JSON (.json) configuration file,
+// applies environment overrides and validates the result.
+func LoadConfigFile(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var format string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		format = "yaml"
+	case ".json":
+		format = "json"
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q", ext)
+	}
+
+	config, err := ParseConfig(data, format)
+	if err != nil {
+		return nil, err
+	}
+	config.ApplyEnv(os.LookupEnv)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ParseConfig decodes a configuration in the given format, "yaml" or "json". Unknown fields are rejected.
+// The result is neither overridden by the environment nor validated.
+func ParseConfig(data []byte, format string) (*FileConfig, error) {
+	config := &FileConfig{}
+	switch format {
+	case "yaml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to decode YAML config: %w", err)
+		}
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(config); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON config: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+	return config, nil
+}
+
+// ApplyEnv resolves APIKeyEnv references and applies the EnvPrefix overrides read through lookup,
+// which is usually os.LookupEnv.
+func (c *FileConfig) ApplyEnv(lookup func(key string) (string, bool)) {
+	if value, ok := lookup(EnvPrefix + "DEFAULT_PROVIDER"); ok {
+		c.DefaultProvider = value
+	}
+	for name, provider := range c.Providers {
+		if provider.APIKeyEnv != "" {
+			if value, ok := lookup(provider.APIKeyEnv); ok {
+				provider.APIKey = value
+			}
+		}
+
+		prefix := EnvPrefix + envName(name) + "_"
+		for suffix, field := range map[string]*string{
+			"API_KEY":     &provider.APIKey,
+			"CHAT_URL":    &provider.ChatURL,
+			"EMBED_URL":   &provider.EmbedURL,
+			"CHAT_MODEL":  &provider.ChatModel,
+			"EMBED_MODEL": &provider.EmbedModel,
+		} {
+			if value, ok := lookup(prefix + suffix); ok {
+				*field = value
+			}
+		}
+		if _, ok := lookup(prefix + "API_KEY"); ok {
+			// The override replaces the key source of the file, which would otherwise take precedence.
+			provider.APIKeyFile, provider.APIKeyCommand = "", nil
+		}
+		c.Providers[name] = provider
+	}
+}
+
+// envName turns a provider name into the environment variable form, e.g. "azure-openai" into "AZURE_OPENAI".
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Validate checks that every provider can be created. With a single provider and no default provider,
+// that provider becomes the default.
+func (c *FileConfig) Validate() error {
+	if len(c.Providers) == 0 {
+		return fmt.Errorf("config defines no providers")
+	}
+	if c.DefaultProvider == "" && len(c.Providers) == 1 {
+		for name := range c.Providers {
+			c.DefaultProvider = name
+		}
+	}
+	if c.DefaultProvider != "" {
+		if _, ok := c.Providers[c.DefaultProvider]; !ok {
+			return fmt.Errorf("default provider %q is not defined", c.DefaultProvider)
+		}
+	}
+
+	var errs []error
+	for _, name := range c.providerNames() {
+		if err := c.Providers[name].validate(); err != nil {
+			errs = append(errs, fmt.Errorf("provider %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p ProviderConfig) validate() error {
+	if p.Type == "" {
+		return fmt.Errorf("type is required")
+	}
+	if _, ok := lookupProvider(p.Type); !ok {
+		return fmt.Errorf("unknown type %q, registered types are %s", p.Type, strings.Join(ProviderTypes(), ", "))
+	}
+	if p.APIKeyFile != "" && len(p.APIKeyCommand) > 0 {
+		return fmt.Errorf("api_key_file and api_key_command are mutually exclusive")
+	}
+	if p.APIKey == "" && p.APIKeyFile == "" && len(p.APIKeyCommand) == 0 {
+		if p.APIKeyEnv != "" {
+			return fmt.Errorf("API key environment variable %s is not set", p.APIKeyEnv)
+		}
+		return fmt.Errorf("API key is required")
+	}
+	for _, field := range []struct{ name, value string }{
+		{"chat_url", p.ChatURL}, {"embed_url", p.EmbedURL}, {"proxy_url", p.ProxyURL},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if parsed, err := url.Parse(field.value); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("%s %q is not an absolute URL", field.name, field.value)
+		}
+	}
+	if p.Timeout < 0 || p.StreamIdleTimeout < 0 || p.IdleConnTimeout < 0 || p.APIKeyTTL < 0 || p.Retries < 0 || p.MaxNumRequestPerSecond < 0 ||
+		p.MaxNumRequestPerLimit < 0 || p.MaxIdleConns < 0 || p.MaxConnsPerHost < 0 {
+		return fmt.Errorf("numeric settings must not be negative")
+	}
+	return nil
+}
+
+func (c *FileConfig) providerNames() []string {
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Config returns the strategy configuration. Unset common options fall back to DefaultCommonConfig.
+func (p ProviderConfig) Config() Config {
+	common := DefaultCommonConfig()
+	if p.Timeout > 0 {
+		common.Timeout = time.Duration(p.Timeout)
+	}
+	if p.StreamIdleTimeout > 0 {
+		common.StreamIdleTimeout = time.Duration(p.StreamIdleTimeout)
+	}
+	if p.Retries > 0 {
+		common.Retries = p.Retries
+	}
+	if p.MaxNumRequestPerSecond > 0 {
+		common.MaxNumRequestPerSecond = p.MaxNumRequestPerSecond
+	}
+	if p.MaxNumRequestPerLimit > 0 {
+		common.MaxNumRequestPerLimit = p.MaxNumRequestPerLimit
+	}
+	if p.ProxyURL != "" {
+		common.ProxyURL = p.ProxyURL
+	}
+	if p.MaxIdleConns > 0 {
+		common.MaxIdleConns = p.MaxIdleConns
+	}
+	if p.MaxConnsPerHost > 0 {
+		common.MaxConnsPerHost = p.MaxConnsPerHost
+	}
+	if p.IdleConnTimeout > 0 {
+		common.IdleConnTimeout = time.Duration(p.IdleConnTimeout)
+	}
+	return Config{
+		APIKey:       p.APIKey,
+		Credentials:  p.credentials(),
+		ChatURL:      p.ChatURL,
+		EmbedURL:     p.EmbedURL,
+		ChatModel:    p.ChatModel,
+		EmbedModel:   p.EmbedModel,
+		ExtraHeaders: p.Headers,
+		CommonConfig: common,
+	}
+}
+
+func (p ProviderConfig) credentials() CredentialProvider {
+	switch {
+	case p.APIKeyFile != "":
+		return NewFileCredential(p.APIKeyFile)
+	case len(p.APIKeyCommand) > 0:
+		ttl := 15 * time.Minute
+		if p.APIKeyTTL > 0 {
+			ttl = time.Duration(p.APIKeyTTL)
+		}
+		return CommandCredential(ttl, p.APIKeyCommand[0], p.APIKeyCommand[1:]...)
+	}
+	return nil
+}
+
+// NewStrategy creates the strategy described by p with the factory registered for its type.
+func (p ProviderConfig) NewStrategy() (interface{}, error) {
+	return NewProvider(p.Type, p.Config())
+}
+
+// Registry creates the strategies of all providers, registered under their names, with the default provider set.
+func (c *FileConfig) Registry() (*Registry, error) {
+	registry := NewRegistry()
+	for _, name := range c.providerNames() {
+		strategy, err := c.Providers[name].NewStrategy()
+		if err != nil {
+			return nil, fmt.Errorf("provider %q: %w", name, err)
+		}
+		if err := registry.Register(name, strategy); err != nil {
+			return nil, err
+		}
+	}
+	if c.DefaultProvider != "" {
+		if err := registry.SetDefault(c.DefaultProvider); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// ModelContext returns a ModelContext routing "provider:model" addresses to all providers and other calls
+// to the default provider. Calls that set no model use the chat_model or embed_model of the provider serving them.
+func (c *FileConfig) ModelContext() (*ModelContext, error) {
+	if c.DefaultProvider == "" {
+		return nil, fmt.Errorf("no default provider configured")
+	}
+	if _, ok := c.Providers[c.DefaultProvider]; !ok {
+		return nil, fmt.Errorf("default provider %q is not defined", c.DefaultProvider)
+	}
+	registry, err := c.Registry()
+	if err != nil {
+		return nil, err
+	}
+
+	modelContext := NewModelContext()
+	modelContext.SetRegistry(registry)
+	return modelContext, nil
+}
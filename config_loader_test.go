@@ -0,0 +1,205 @@
+package llmconnector
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testYAMLConfig = `
+default_provider: openai
+providers:
+  openai:
+    type: openai
+    api_key_env: TEST_OPENAI_KEY
+    chat_model: gpt-4o
+    timeout: 5s
+    stream_idle_timeout: 2m
+    retries: 2
+  qwen:
+    type: alibaba
+    api_key: qwen-key
+    embed_model: text-embedding-v2
+    idle_conn_timeout: 1m
+`
+
+func TestParseConfig_YAML(t *testing.T) {
+	config, err := ParseConfig([]byte(testYAMLConfig), "yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, "openai", config.DefaultProvider)
+	require.Len(t, config.Providers, 2)
+	openai := config.Providers["openai"]
+	assert.Equal(t, "TEST_OPENAI_KEY", openai.APIKeyEnv)
+	assert.Equal(t, Duration(5*time.Second), openai.Timeout)
+	assert.Equal(t, 2*time.Minute, openai.Config().StreamIdleTimeout)
+
+	common := config.Providers["qwen"].Config().CommonConfig
+	assert.Equal(t, time.Minute, common.IdleConnTimeout)
+	assert.Equal(t, DefaultCommonConfig().Timeout, common.Timeout)
+}
+
+func TestParseConfig_JSON(t *testing.T) {
+	config, err := ParseConfig([]byte(`{"providers":{"openai":{"type":"openai","api_key":"key","timeout":"2s"}}}`), "json")
+	require.NoError(t, err)
+	assert.Equal(t, Duration(2*time.Second), config.Providers["openai"].Timeout)
+
+	_, err = ParseConfig([]byte(`{"providers":{"openai":{"type":"openai","apikey":"key"}}}`), "json")
+	assert.Error(t, err)
+}
+
+func TestFileConfig_ApplyEnv(t *testing.T) {
+	config, err := ParseConfig([]byte(testYAMLConfig), "yaml")
+	require.NoError(t, err)
+
+	env := map[string]string{
+		"TEST_OPENAI_KEY":      "from-reference",
+		"LLM_QWEN_API_KEY":     "from-override",
+		"LLM_OPENAI_CHAT_URL":  "http://localhost:8089/v1/chat/completions",
+		"LLM_DEFAULT_PROVIDER": "qwen",
+	}
+	config.ApplyEnv(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+
+	assert.Equal(t, "qwen", config.DefaultProvider)
+	assert.Equal(t, "from-reference", config.Providers["openai"].APIKey)
+	assert.Equal(t, "http://localhost:8089/v1/chat/completions", config.Providers["openai"].ChatURL)
+	assert.Equal(t, "from-override", config.Providers["qwen"].APIKey)
+}
+
+func TestFileConfig_ApplyEnvOverridesKeySource(t *testing.T) {
+	config, err := ParseConfig([]byte(`{"providers":{
+		"openai":{"type":"openai","api_key_file":"/run/secrets/openai"},
+		"qwen":{"type":"alibaba","api_key_command":["pass","qwen"]}}}`), "json")
+	require.NoError(t, err)
+
+	env := map[string]string{"LLM_OPENAI_API_KEY": "openai-override", "LLM_QWEN_API_KEY": "qwen-override"}
+	config.ApplyEnv(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	require.NoError(t, config.Validate())
+
+	for name, want := range map[string]string{"openai": "openai-override", "qwen": "qwen-override"} {
+		provider := config.Providers[name]
+		assert.Empty(t, provider.APIKeyFile)
+		assert.Empty(t, provider.APIKeyCommand)
+
+		strategyConfig := provider.Config()
+		assert.Nil(t, strategyConfig.Credentials, name)
+		assert.Equal(t, want, strategyConfig.APIKey, name)
+	}
+}
+
+func TestFileConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errMsg string
+	}{
+		{"no providers", `{}`, "no providers"},
+		{"unknown default", `{"default_provider":"x","providers":{"a":{"type":"openai","api_key":"k"}}}`, `default provider "x"`},
+		{"missing type", `{"providers":{"a":{"api_key":"k"}}}`, "type is required"},
+		{"unknown type", `{"providers":{"a":{"type":"gemini","api_key":"k"}}}`, `unknown type "gemini"`},
+		{"unset key env", `{"providers":{"a":{"type":"openai","api_key_env":"TEST_UNSET_KEY"}}}`, "TEST_UNSET_KEY is not set"},
+		{"relative URL", `{"providers":{"a":{"type":"openai","api_key":"k","chat_url":"/v1/chat"}}}`, "chat_url"},
+		{"file and command", `{"providers":{"a":{"type":"openai","api_key_file":"/run/key","api_key_command":["vault"]}}}`, "mutually exclusive"},
+		{"negative retries", `{"providers":{"a":{"type":"openai","api_key":"k","retries":-1}}}`, "negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseConfig([]byte(tt.config), "json")
+			require.NoError(t, err)
+			err = config.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+
+	config, err := ParseConfig([]byte(`{"providers":{"only":{"type":"openai","api_key":"k"}}}`), "json")
+	require.NoError(t, err)
+	require.NoError(t, config.Validate())
+	assert.Equal(t, "only", config.DefaultProvider)
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer env-key", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi there"}}]}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "llm.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testYAMLConfig), 0o600))
+	t.Setenv("TEST_OPENAI_KEY", "env-key")
+	t.Setenv("LLM_OPENAI_CHAT_URL", server.URL)
+
+	config, err := LoadConfigFile(path)
+	require.NoError(t, err)
+
+	registry, err := config.Registry()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openai", "qwen"}, registry.Names())
+	_, ok := registry.EmbedStrategy("qwen")
+	assert.True(t, ok)
+
+	modelContext, err := config.ModelContext()
+	require.NoError(t, err)
+	var model string
+	modelContext.UseChat(func(next ChatHandler) ChatHandler {
+		return func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+			model = options.Model
+			return next(ctx, chatMessages, options)
+		}
+	})
+	resp, err := modelContext.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", resp.GetContent())
+	assert.Equal(t, "gpt-4o", model)
+
+	_, err = LoadConfigFile(filepath.Join(t.TempDir(), "llm.toml"))
+	assert.Error(t, err)
+}
+
+func TestFileConfig_ModelContextDefaultModels(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		models = append(models, body.Model)
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi there"}}]}`))
+	}))
+	defer server.Close()
+
+	config, err := ParseConfig([]byte(`{"default_provider":"primary","providers":{
+		"primary":{"type":"openai","api_key":"k","chat_url":"`+server.URL+`","chat_model":"gpt-4o"},
+		"secondary":{"type":"openai","api_key":"k","chat_url":"`+server.URL+`","chat_model":"gpt-4o-mini"}}}`), "json")
+	require.NoError(t, err)
+	modelContext, err := config.ModelContext()
+	require.NoError(t, err)
+
+	// Every provider uses its own default model; the default provider's model never leaks to another provider.
+	messages := []ChatMessage{{Role: "user", Content: "Hello"}}
+	for _, model := range []string{"", "secondary:", "primary:o1", "secondary:o1"} {
+		_, err := modelContext.Chat(context.Background(), messages, WithChatModel(model))
+		require.NoError(t, err)
+	}
+	registry, err := config.Registry()
+	require.NoError(t, err)
+	secondary, ok := registry.ChatStrategy("secondary")
+	require.True(t, ok)
+	_, err = secondary.Chat(context.Background(), messages, &ChatOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini", "o1", "o1", "gpt-4o-mini"}, models)
+}
@@ -0,0 +1,184 @@
+package llmconnector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialProvider supplies the API key of a strategy. It is consulted for every request attempt,
+// so keys can be rotated without recreating the strategy. Implementations must be safe for concurrent use.
+type CredentialProvider interface {
+	Credential(ctx context.Context) (string, error)
+}
+
+// CredentialInvalidator is implemented by providers that cache credentials.
+// Strategies call Invalidate when the provider answers 401 Unauthorized, so the next attempt fetches a new key.
+type CredentialInvalidator interface {
+	Invalidate()
+}
+
+// CredentialFunc adapts a function, e.g. a call to a secret manager, to CredentialProvider.
+// Wrap it with CachedCredential to avoid calling it for every request.
+type CredentialFunc func(ctx context.Context) (string, error)
+
+func (f CredentialFunc) Credential(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticCredential returns a provider always supplying key.
+func StaticCredential(key string) CredentialProvider {
+	return CredentialFunc(func(ctx context.Context) (string, error) {
+		return key, nil
+	})
+}
+
+// EnvCredential returns a provider reading the environment variable name for every request.
+func EnvCredential(name string) CredentialProvider {
+	return CredentialFunc(func(ctx context.Context) (string, error) {
+		key := os.Getenv(name)
+		if key == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return key, nil
+	})
+}
+
+// FileCredential supplies the trimmed content of a file and reloads it when the file changes,
+// e.g. a secret mounted by Kubernetes or written by a rotation agent.
+type FileCredential struct {
+	path string
+
+	mu      sync.Mutex
+	key     string
+	modTime time.Time
+	size    int64
+}
+
+func NewFileCredential(path string) *FileCredential {
+	return &FileCredential{path: path}
+}
+
+func (f *FileCredential) Credential(ctx context.Context) (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read credential file: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.key != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.key, nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read credential file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("credential file %s is empty", f.path)
+	}
+	f.key, f.modTime, f.size = key, info.ModTime(), info.Size()
+	return key, nil
+}
+
+// Invalidate forces the file to be read again.
+func (f *FileCredential) Invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.key = ""
+}
+
+// CommandCredential returns a provider running a command, e.g. a CLI of a secret manager, and using its
+// trimmed standard output as the key. The output is cached for ttl.
+func CommandCredential(ttl time.Duration, name string, args ...string) *CachedCredential {
+	return NewCachedCredential(CredentialFunc(func(ctx context.Context) (string, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("credential command %s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		key := strings.TrimSpace(stdout.String())
+		if key == "" {
+			return "", fmt.Errorf("credential command %s printed no key", name)
+		}
+		return key, nil
+	}), ttl)
+}
+
+// CachedCredential caches the key of another provider for a time to live.
+type CachedCredential struct {
+	provider CredentialProvider
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	key     string
+	expires time.Time
+}
+
+// NewCachedCredential caches the keys of provider for ttl. A ttl of 0 caches until Invalidate is called.
+func NewCachedCredential(provider CredentialProvider, ttl time.Duration) *CachedCredential {
+	return &CachedCredential{provider: provider, ttl: ttl}
+}
+
+func (c *CachedCredential) Credential(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.key != "" && (c.ttl == 0 || time.Now().Before(c.expires)) {
+		return c.key, nil
+	}
+	key, err := c.provider.Credential(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.key, c.expires = key, time.Now().Add(c.ttl)
+	return key, nil
+}
+
+// Invalidate drops the cached key, and invalidates the wrapped provider if it caches too.
+func (c *CachedCredential) Invalidate() {
+	c.mu.Lock()
+	c.key = ""
+	c.mu.Unlock()
+	if invalidator, ok := c.provider.(CredentialInvalidator); ok {
+		invalidator.Invalidate()
+	}
+}
+
+// hasCredentials reports whether a key is configured, statically or through a provider.
+func (c *Config) hasCredentials() bool {
+	return c.APIKey != "" || c.Credentials != nil
+}
+
+// authorize is a request interceptor setting the Authorization header from Credentials for every attempt.
+func (c *Config) authorize(req *http.Request) error {
+	if c.Credentials == nil {
+		return nil
+	}
+	key, err := c.Credentials.Credential(req.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get credential: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+	return nil
+}
+
+// invalidateOnUnauthorized is a response interceptor dropping cached credentials rejected by the provider.
+// Retried attempts then fetch a fresh key.
+func (c *Config) invalidateOnUnauthorized(resp *http.Response) error {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+	if invalidator, ok := c.Credentials.(CredentialInvalidator); ok {
+		invalidator.Invalidate()
+	}
+	return nil
+}
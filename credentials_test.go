@@ -0,0 +1,117 @@
+package llmconnector
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStaticAndEnvCredential(t *testing.T) {
+	key, err := StaticCredential("static-key").Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "static-key", key)
+
+	t.Setenv("TEST_LLM_KEY", "env-key")
+	key, err = EnvCredential("TEST_LLM_KEY").Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "env-key", key)
+
+	_, err = EnvCredential("TEST_LLM_UNSET_KEY").Credential(context.Background())
+	assert.Error(t, err)
+}
+
+func TestFileCredential(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte("first-key\n"), 0o600))
+
+	credential := NewFileCredential(path)
+	key, err := credential.Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "first-key", key)
+
+	require.NoError(t, os.WriteFile(path, []byte("second-key-rotated\n"), 0o600))
+	key, err = credential.Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "second-key-rotated", key)
+
+	require.NoError(t, os.Remove(path))
+	_, err = credential.Credential(context.Background())
+	assert.Error(t, err)
+}
+
+func TestCommandCredential(t *testing.T) {
+	credential := CommandCredential(time.Minute, "echo", "command-key")
+	key, err := credential.Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "command-key", key)
+
+	_, err = CommandCredential(time.Minute, "false").Credential(context.Background())
+	assert.Error(t, err)
+}
+
+func TestCachedCredential(t *testing.T) {
+	var calls int32
+	credential := NewCachedCredential(CredentialFunc(func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "cached-key", nil
+	}), time.Hour)
+
+	for i := 0; i < 3; i++ {
+		key, err := credential.Credential(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "cached-key", key)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	credential.Invalidate()
+	_, err := credential.Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestOpenAIStrategy_CredentialRefreshOnUnauthorized(t *testing.T) {
+	var rotated atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new-key" {
+			rotated.Store(true)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\ndata: [DONE]\n\n"))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi there"}}]}`))
+	}))
+	defer server.Close()
+
+	// The secret store hands out the old key until the provider has rejected it.
+	credentials := NewCachedCredential(CredentialFunc(func(ctx context.Context) (string, error) {
+		if rotated.Load() {
+			return "new-key", nil
+		}
+		return "old-key", nil
+	}), time.Hour)
+
+	strategy, err := NewOpenAIStrategy(Config{Credentials: credentials, ChatURL: server.URL})
+	require.NoError(t, err)
+
+	messages := []ChatMessage{{Role: "user", Content: "Hello"}}
+	resp, err := strategy.Chat(context.Background(), messages, &ChatOptions{Model: "test-model"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", resp.GetContent())
+
+	resp, err = strategy.Chat(context.Background(), messages, &ChatOptions{Model: "test-model", StreamHandler: func(string) error { return nil }})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", resp.GetContent())
+
+	_, err = NewOpenAIStrategy(Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package llmconnector
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Encoding formats of EmbedOptions.EncodingFormat.
+const (
+	EncodingFormatFloat  = "float"
+	EncodingFormatBase64 = "base64"
+)
+
+// Output types of EmbedOptions.OutputType, supported by DashScope text-embedding-v3.
+const (
+	OutputTypeDense          = "dense"
+	OutputTypeSparse         = "sparse"
+	OutputTypeDenseAndSparse = "dense&sparse"
+)
+
+// wantsSparse reports whether options ask for sparse vectors.
+func (o *EmbedOptions) wantsSparse() bool {
+	return o.OutputType == OutputTypeSparse || o.OutputType == OutputTypeDenseAndSparse
+}
+
+// SparseEntry is a non-zero weight of a sparse vector: the vocabulary index of a token and its weight.
+type SparseEntry struct {
+	Index int     `json:"index"`
+	Value float32 `json:"value"`
+	// Token is the text of the token, when the provider reports it.
+	Token string `json:"token,omitempty"`
+}
+
+// SparseVector is a sparse embedding as index/value pairs.
+type SparseVector []SparseEntry
+
+// SparseEmbeddingReporter is implemented by embed responses that carry sparse vectors.
+type SparseEmbeddingReporter interface {
+	// GetSparseEmbeddings returns the sparse vectors in input order.
+	GetSparseEmbeddings() []SparseVector
+}
+
+// SparseEmbeddingsOf returns the sparse vectors of an embed response, if the response carries them.
+func SparseEmbeddingsOf(resp EmbedResponse) ([]SparseVector, bool) {
+	if reporter, ok := resp.(SparseEmbeddingReporter); ok {
+		return reporter.GetSparseEmbeddings(), true
+	}
+	return nil, false
+}
+
+// EmbeddingVector is a vector in a provider response. It decodes from a JSON array of numbers
+// or from a base64 string of little-endian float32 values.
+type EmbeddingVector []float32
+
+func (v *EmbeddingVector) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '"' {
+		var values []float32
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		*v = values
+		return nil
+	}
+
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("failed to decode base64 embedding: %w", err)
+	}
+	if len(raw)%4 != 0 {
+		return fmt.Errorf("base64 embedding has %d bytes, not a multiple of 4", len(raw))
+	}
+	values := make([]float32, len(raw)/4)
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+// nativeDimensions reports whether the dimensions parameter is sent for a call: the model must be able to shorten
+// its vectors to the requested size, which must differ from its native size. Models missing from the default
+// catalog are assumed to support it. Otherwise shapeEmbedding truncates the vectors client-side.
+func nativeDimensions(provider string, options *EmbedOptions) bool {
+	if options.Dimensions <= 0 {
+		return false
+	}
+	info, ok := DefaultCatalog().Lookup(provider, options.Model)
+	if !ok {
+		return true
+	}
+	return info.Has(CapabilityDimensions) && options.Dimensions != info.EmbeddingDimensions &&
+		info.SupportsDimensions(options.Dimensions)
+}
+
+// shapeEmbedding applies the client-side options to a vector: Matryoshka truncation to Dimensions,
+// which renormalizes the shortened vector, and L2 normalization.
+func shapeEmbedding(vector []float32, options *EmbedOptions) []float32 {
+	truncated := options.Dimensions > 0 && len(vector) > options.Dimensions
+	if truncated {
+		vector = vector[:options.Dimensions:options.Dimensions]
+	}
+	if truncated || options.Normalize {
+		normalize(vector)
+	}
+	return vector
+}
+
+// normalize scales vector to unit length in place. Zero vectors are left unchanged.
+func normalize(vector []float32) {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i, value := range vector {
+		vector[i] = float32(float64(value) / norm)
+	}
+}
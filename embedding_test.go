@@ -0,0 +1,37 @@
+package llmconnector
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEmbeddingVector_UnmarshalJSON(t *testing.T) {
+	var vectors []EmbeddingVector
+	require.NoError(t, json.Unmarshal([]byte(`[[0.5, -1], "AAAAPwAAgL8="]`), &vectors))
+	assert.Equal(t, []EmbeddingVector{{0.5, -1}, {0.5, -1}}, vectors)
+
+	var vector EmbeddingVector
+	assert.Error(t, json.Unmarshal([]byte(`"AAAA"`), &vector), "3 bytes are not a float32")
+	assert.Error(t, json.Unmarshal([]byte(`"not base64"`), &vector))
+}
+
+func TestShapeEmbedding(t *testing.T) {
+	assert.Equal(t, []float32{3, 4, 12}, shapeEmbedding([]float32{3, 4, 12}, &EmbedOptions{}))
+	assert.Equal(t, []float32{0.6, 0.8}, shapeEmbedding([]float32{3, 4, 12}, &EmbedOptions{Dimensions: 2}))
+	assert.Equal(t, []float32{0.6, 0.8}, shapeEmbedding([]float32{3, 4}, &EmbedOptions{Normalize: true}))
+	assert.Equal(t, []float32{0, 0}, shapeEmbedding([]float32{0, 0}, &EmbedOptions{Normalize: true}))
+}
+
+func TestCatalog_ValidateEmbedDimensions(t *testing.T) {
+	catalog := DefaultCatalog()
+	assert.NoError(t, catalog.ValidateEmbed("openai", &EmbedOptions{Model: "text-embedding-3-small", Dimensions: 512}))
+	assert.ErrorIs(t, catalog.ValidateEmbed("openai", &EmbedOptions{Model: "text-embedding-3-small", Dimensions: 4096}), ErrInvalidOptions)
+}
+
+func TestCatalog_ValidateEmbedSparse(t *testing.T) {
+	catalog := DefaultCatalog()
+	assert.NoError(t, catalog.ValidateEmbed("alibaba", &EmbedOptions{Model: "text-embedding-v3", OutputType: OutputTypeSparse}))
+	assert.ErrorIs(t, catalog.ValidateEmbed("alibaba", &EmbedOptions{Model: "text-embedding-v2", OutputType: OutputTypeSparse}), ErrInvalidOptions)
+}
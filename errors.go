@@ -0,0 +1,75 @@
+package llmconnector
+
+import (
+	"context"
+	"errors"
+	"github.com/simp-lee/gohttpclient"
+	"net"
+	"net/http"
+)
+
+// ErrorClass is a provider independent category of a failed request.
+type ErrorClass string
+
+const (
+	ErrorClassNone           ErrorClass = ""
+	ErrorClassAuth           ErrorClass = "auth"
+	ErrorClassRateLimit      ErrorClass = "rate_limit"
+	ErrorClassInvalidRequest ErrorClass = "invalid_request"
+	ErrorClassServer         ErrorClass = "server"
+	ErrorClassTimeout        ErrorClass = "timeout"
+	ErrorClassCanceled       ErrorClass = "canceled"
+	ErrorClassNetwork        ErrorClass = "network"
+	ErrorClassUnknown        ErrorClass = "unknown"
+)
+
+// StatusCode returns the HTTP status code of a failed provider request, or 0 if no response was received.
+func StatusCode(err error) int {
+	var clientErr *gohttpclient.ClientError
+	if errors.As(err, &clientErr) {
+		return clientErr.Code
+	}
+	return 0
+}
+
+// ClassifyError returns the category of err. It returns ErrorClassNone for a nil error.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassNone
+	}
+
+	switch code := StatusCode(err); {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ErrorClassAuth
+	case code == http.StatusTooManyRequests:
+		return ErrorClassRateLimit
+	case code == http.StatusRequestTimeout || code == http.StatusGatewayTimeout:
+		return ErrorClassTimeout
+	case code >= 500:
+		return ErrorClassServer
+	case code >= 400:
+		return ErrorClassInvalidRequest
+	}
+
+	// gohttpclient.ClientError does not unwrap, so look at the error it carries as well.
+	cause := err
+	var clientErr *gohttpclient.ClientError
+	if errors.As(err, &clientErr) {
+		cause = clientErr.Err
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(cause, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled) || errors.Is(cause, context.Canceled):
+		return ErrorClassCanceled
+	}
+
+	var netErr net.Error
+	if errors.As(cause, &netErr) {
+		if netErr.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassNetwork
+	}
+	return ErrorClassUnknown
+}
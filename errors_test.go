@@ -0,0 +1,47 @@
+package llmconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/simp-lee/gohttpclient"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestStatusCode(t *testing.T) {
+	err := fmt.Errorf("chat failed: %w", &gohttpclient.ClientError{Op: "Do", Err: errors.New("bad request"), Code: 400})
+	assert.Equal(t, 400, StatusCode(err))
+	assert.Equal(t, 0, StatusCode(errors.New("boom")))
+	assert.Equal(t, 0, StatusCode(nil))
+}
+
+func TestClassifyError(t *testing.T) {
+	clientErr := func(code int, err error) error {
+		return &gohttpclient.ClientError{Op: "Do", Err: err, Code: code}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"nil", nil, ErrorClassNone},
+		{"unauthorized", clientErr(401, errors.New("unauthorized")), ErrorClassAuth},
+		{"forbidden", clientErr(403, errors.New("forbidden")), ErrorClassAuth},
+		{"rate limit", clientErr(429, errors.New("slow down")), ErrorClassRateLimit},
+		{"gateway timeout", clientErr(504, errors.New("timeout")), ErrorClassTimeout},
+		{"server", clientErr(503, errors.New("unavailable")), ErrorClassServer},
+		{"invalid request", clientErr(400, errors.New("bad request")), ErrorClassInvalidRequest},
+		{"canceled", clientErr(0, context.Canceled), ErrorClassCanceled},
+		{"deadline", context.DeadlineExceeded, ErrorClassTimeout},
+		{"network", clientErr(0, &net.OpError{Op: "dial", Err: errors.New("connection refused")}), ErrorClassNetwork},
+		{"unknown", errors.New("boom"), ErrorClassUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyError(tt.err))
+		})
+	}
+}
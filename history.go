@@ -0,0 +1,243 @@
+package llmconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrContextWindowExceeded is returned, wrapped, when the system prompt and the last turn of a conversation
+// alone do not fit the context window.
+var ErrContextWindowExceeded = errors.New("messages exceed the context window")
+
+type historyConfig struct {
+	catalog        *Catalog
+	contextWindow  int
+	tokenizer      Tokenizer
+	keepLastTurns  int
+	summarizer     ChatStrategy
+	summaryOptions []ChatOption
+	summaryTokens  int
+}
+
+// HistoryOption configures FitHistory and TrimHistory.
+type HistoryOption func(c *historyConfig)
+
+// WithHistoryCatalog sets the catalog the context window of a model is read from. Defaults to DefaultCatalog.
+func WithHistoryCatalog(catalog *Catalog) HistoryOption {
+	return func(c *historyConfig) {
+		c.catalog = catalog
+	}
+}
+
+// WithContextWindow sets the context window in tokens, overriding the catalog.
+func WithContextWindow(tokens int) HistoryOption {
+	return func(c *historyConfig) {
+		c.contextWindow = tokens
+	}
+}
+
+// WithHistoryTokenizer sets how the tokens of messages are counted. Defaults to the tokenizer of the model,
+// see TokenizerFor.
+func WithHistoryTokenizer(tokenizer Tokenizer) HistoryOption {
+	return func(c *historyConfig) {
+		c.tokenizer = tokenizer
+	}
+}
+
+// WithKeepLastTurns keeps at most the last n turns of the conversation besides the system prompt, even when more
+// would fit. A turn is a user message and the replies to it.
+func WithKeepLastTurns(n int) HistoryOption {
+	return func(c *historyConfig) {
+		c.keepLastTurns = n
+	}
+}
+
+// WithSummarizer replaces the dropped turns with a system message summarizing them, written by a call to strategy
+// with opts. The summary is limited to the tokens set with WithMaxTokens in opts, or else with WithSummaryTokens.
+// It is left out when only the last turn fits.
+func WithSummarizer(strategy ChatStrategy, opts ...ChatOption) HistoryOption {
+	return func(c *historyConfig) {
+		c.summarizer = strategy
+		c.summaryOptions = opts
+	}
+}
+
+// WithSummaryTokens sets the maximum length of the summary of dropped turns. Defaults to 512 tokens.
+func WithSummaryTokens(tokens int) HistoryOption {
+	return func(c *historyConfig) {
+		c.summaryTokens = tokens
+	}
+}
+
+func newHistoryConfig(opts []HistoryOption) *historyConfig {
+	c := &historyConfig{summaryTokens: 512}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.catalog == nil {
+		c.catalog = DefaultCatalog()
+	}
+	return c
+}
+
+// FitHistory returns chatMessages trimmed to fit the context window of options.Model served by provider, leaving
+// room for options.MaxTokens of completion. The leading system messages and the last turn are always kept; the
+// oldest turns are dropped first, and optionally summarized, see WithSummarizer. chatMessages is returned unchanged
+// when it fits, or when the context window is unknown and WithKeepLastTurns is not set.
+func FitHistory(ctx context.Context, provider string, chatMessages []ChatMessage, options *ChatOptions, opts ...HistoryOption) ([]ChatMessage, error) {
+	return newHistoryConfig(opts).fit(ctx, provider, chatMessages, options)
+}
+
+// TrimHistory returns a middleware fitting the messages of every chat call into the context window of its model
+// with FitHistory.
+func TrimHistory(opts ...HistoryOption) ChatMiddleware {
+	c := newHistoryConfig(opts)
+	return func(next ChatHandler) ChatHandler {
+		return func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+			chatMessages, err := c.fit(ctx, ProviderFromContext(ctx), chatMessages, options)
+			if err != nil {
+				return nil, err
+			}
+			return next(ctx, chatMessages, options)
+		}
+	}
+}
+
+// budget returns the prompt tokens available to the call, or 0 when the context window is unknown.
+func (c *historyConfig) budget(provider string, options *ChatOptions) int {
+	window := c.contextWindow
+	if window == 0 {
+		if info, ok := c.catalog.Lookup(provider, options.Model); ok {
+			window = info.ContextWindow
+		}
+	}
+	if window == 0 {
+		return 0
+	}
+	if options.MaxTokens != nil {
+		window -= *options.MaxTokens
+	}
+	return max(window, 1)
+}
+
+func (c *historyConfig) fit(ctx context.Context, provider string, chatMessages []ChatMessage, options *ChatOptions) ([]ChatMessage, error) {
+	system, turns := splitTurns(chatMessages)
+	if len(turns) == 0 {
+		return chatMessages, nil
+	}
+	first := 0
+	if c.keepLastTurns > 0 && len(turns) > c.keepLastTurns {
+		first = len(turns) - c.keepLastTurns
+	}
+	summarize := c.summarizer != nil
+
+	if budget := c.budget(provider, options); budget > 0 {
+		tokenizer := c.tokenizer
+		if tokenizer == nil {
+			tokenizer = TokenizerFor(provider, options.Model)
+		}
+		format := chatFormatOf(provider)
+		total := CountMessageTokens(tokenizer, provider, system)
+		turnTokens := make([]int, len(turns))
+		for i, turn := range turns {
+			for _, message := range turn {
+				turnTokens[i] += format.count(tokenizer, message)
+			}
+			if i >= first {
+				total += turnTokens[i]
+			}
+		}
+		reserve := 0
+		for {
+			if first > 0 && summarize {
+				reserve = *c.summaryChatOptions().MaxTokens + format.count(tokenizer, ChatMessage{Role: "system", Content: summaryPrefix})
+			}
+			if total+reserve <= budget {
+				break
+			}
+			if first == len(turns)-1 {
+				if total <= budget {
+					// Only the last turn fits, without room for a summary.
+					summarize = false
+					break
+				}
+				return nil, fmt.Errorf("%w: the system prompt and last turn take %d tokens, %d available",
+					ErrContextWindowExceeded, total, budget)
+			}
+			total -= turnTokens[first]
+			first++
+		}
+	}
+	if first == 0 {
+		return chatMessages, nil
+	}
+
+	fitted := append([]ChatMessage(nil), system...)
+	if summarize {
+		summary, err := c.summarize(ctx, turns[:first])
+		if err != nil {
+			return nil, err
+		}
+		fitted = append(fitted, ChatMessage{Role: "system", Content: summaryPrefix + summary})
+	}
+	for _, turn := range turns[first:] {
+		fitted = append(fitted, turn...)
+	}
+	return fitted, nil
+}
+
+const (
+	summaryPrefix = "Summary of the earlier conversation:\n"
+	summaryPrompt = "Summarize the following conversation in a few sentences. Keep the facts, names, decisions " +
+		"and open questions needed to continue it."
+)
+
+func (c *historyConfig) summarize(ctx context.Context, turns [][]ChatMessage) (string, error) {
+	var transcript strings.Builder
+	for _, turn := range turns {
+		for _, message := range turn {
+			fmt.Fprintf(&transcript, "%s: %s\n\n", message.Role, message.Content)
+		}
+	}
+	resp, err := c.summarizer.Chat(ctx, []ChatMessage{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: strings.TrimSpace(transcript.String())},
+	}, c.summaryChatOptions())
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize history: %w", err)
+	}
+	return strings.TrimSpace(resp.GetContent()), nil
+}
+
+// summaryChatOptions returns the options of the summary call, limited to the summary tokens unless the summarizer
+// options set MaxTokens.
+func (c *historyConfig) summaryChatOptions() *ChatOptions {
+	options := &ChatOptions{}
+	for _, opt := range c.summaryOptions {
+		opt(options)
+	}
+	if options.MaxTokens == nil {
+		maxTokens := c.summaryTokens
+		options.MaxTokens = &maxTokens
+	}
+	return options
+}
+
+// splitTurns splits messages into the leading system messages and the turns after them. A turn starts at every
+// user message.
+func splitTurns(messages []ChatMessage) (system []ChatMessage, turns [][]ChatMessage) {
+	i := 0
+	for i < len(messages) && messages[i].Role == "system" {
+		i++
+	}
+	system = messages[:i]
+	for ; i < len(messages); i++ {
+		if len(turns) == 0 || messages[i].Role == "user" {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], messages[i])
+	}
+	return system, turns
+}
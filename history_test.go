@@ -0,0 +1,124 @@
+package llmconnector
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// countWords counts every word as a token, so that in the openai format a message of n words takes n+4 tokens.
+var countWords = TokenizerFunc(func(text string) int { return len(strings.Fields(text)) })
+
+func conversation() []ChatMessage {
+	return []ChatMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "one"},
+		{Role: "assistant", Content: "reply one"},
+		{Role: "user", Content: "two"},
+		{Role: "assistant", Content: "reply two"},
+		{Role: "user", Content: "three"},
+	}
+}
+
+func TestFitHistory_DropsOldestTurns(t *testing.T) {
+	ctx := context.Background()
+	messages := conversation()
+	// system 6, turns 5+6, 5+6 and 5 tokens, plus 3 priming the reply.
+	assert.Equal(t, 3+6+11+11+5, CountMessageTokens(countWords, "openai", messages))
+
+	fitted, err := FitHistory(ctx, "openai", messages, &ChatOptions{}, WithContextWindow(36), WithHistoryTokenizer(countWords))
+	require.NoError(t, err)
+	assert.Equal(t, messages, fitted)
+
+	fitted, err = FitHistory(ctx, "openai", messages, &ChatOptions{}, WithContextWindow(35), WithHistoryTokenizer(countWords))
+	require.NoError(t, err)
+	assert.Equal(t, []ChatMessage{messages[0], messages[3], messages[4], messages[5]}, fitted)
+
+	// MaxTokens is reserved for the completion.
+	maxTokens := 20
+	fitted, err = FitHistory(ctx, "openai", messages, &ChatOptions{MaxTokens: &maxTokens}, WithContextWindow(35), WithHistoryTokenizer(countWords))
+	require.NoError(t, err)
+	assert.Equal(t, []ChatMessage{messages[0], messages[5]}, fitted)
+
+	_, err = FitHistory(ctx, "openai", messages, &ChatOptions{}, WithContextWindow(10), WithHistoryTokenizer(countWords))
+	assert.ErrorIs(t, err, ErrContextWindowExceeded)
+	assert.Len(t, messages, 6, "the caller's slice is untouched")
+}
+
+func TestFitHistory_KeepLastTurns(t *testing.T) {
+	messages := conversation()
+	fitted, err := FitHistory(context.Background(), "openai", messages, &ChatOptions{Model: "unknown"}, WithKeepLastTurns(2))
+	require.NoError(t, err)
+	assert.Equal(t, []ChatMessage{messages[0], messages[3], messages[4], messages[5]}, fitted)
+
+	fitted, err = FitHistory(context.Background(), "openai", messages, &ChatOptions{Model: "unknown"})
+	require.NoError(t, err)
+	assert.Equal(t, messages, fitted, "unknown context windows are not enforced")
+}
+
+func TestFitHistory_Summarize(t *testing.T) {
+	messages := conversation()
+	summarizer := &recordingChatStrategy{}
+	// Dropping the first turn leaves 25 tokens, and the summary takes up to 1+9.
+	fitted, err := FitHistory(context.Background(), "openai", messages, &ChatOptions{},
+		WithContextWindow(35), WithHistoryTokenizer(countWords),
+		WithSummarizer(summarizer, WithChatModel("qwen-turbo")), WithSummaryTokens(1))
+	require.NoError(t, err)
+	assert.Equal(t, []ChatMessage{
+		messages[0],
+		{Role: "system", Content: summaryPrefix + "Mock response"},
+		messages[3], messages[4], messages[5],
+	}, fitted)
+
+	require.Len(t, summarizer.messages, 2)
+	assert.Equal(t, "user: one\n\nassistant: reply one", summarizer.messages[1].Content)
+	assert.Equal(t, "qwen-turbo", summarizer.options.Model)
+	assert.Equal(t, 1, *summarizer.options.MaxTokens)
+
+	summarizer.err = errors.New("boom")
+	_, err = FitHistory(context.Background(), "openai", messages, &ChatOptions{},
+		WithKeepLastTurns(1), WithSummarizer(summarizer))
+	assert.ErrorContains(t, err, "boom")
+}
+
+func TestFitHistory_SummaryLeftOutWhenOnlyLastTurnFits(t *testing.T) {
+	messages := conversation()
+	summarizer := &recordingChatStrategy{}
+	// The last turn takes 14 tokens and fits, but not together with the 1+9 tokens of a summary.
+	fitted, err := FitHistory(context.Background(), "openai", messages, &ChatOptions{},
+		WithContextWindow(20), WithHistoryTokenizer(countWords),
+		WithSummarizer(summarizer), WithSummaryTokens(1))
+	require.NoError(t, err)
+	assert.Equal(t, []ChatMessage{messages[0], messages[5]}, fitted)
+	assert.Nil(t, summarizer.messages, "no summary is written")
+}
+
+func TestFitHistory_SummaryMaxTokens(t *testing.T) {
+	messages := conversation()
+	summarizer := &recordingChatStrategy{}
+	// Dropping the first turn leaves 25 tokens, and the summary takes up to 1+9 rather than the default 512+9.
+	fitted, err := FitHistory(context.Background(), "openai", messages, &ChatOptions{},
+		WithContextWindow(35), WithHistoryTokenizer(countWords),
+		WithSummarizer(summarizer, WithMaxTokens(1)))
+	require.NoError(t, err)
+	assert.Equal(t, []ChatMessage{
+		messages[0],
+		{Role: "system", Content: summaryPrefix + "Mock response"},
+		messages[3], messages[4], messages[5],
+	}, fitted)
+	assert.Equal(t, 1, *summarizer.options.MaxTokens)
+}
+
+func TestTrimHistory(t *testing.T) {
+	strategy := &recordingChatStrategy{}
+	modelContext := NewModelContext()
+	modelContext.SetChatStrategy(strategy)
+	modelContext.UseChat(TrimHistory(WithKeepLastTurns(1)))
+
+	_, err := modelContext.Chat(context.Background(), conversation())
+	require.NoError(t, err)
+	assert.Equal(t, []ChatMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: "three"}}, strategy.messages)
+}
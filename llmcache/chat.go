@@ -0,0 +1,304 @@
+package llmcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type bypassContextKey struct{}
+
+// Bypass returns a context whose calls skip the chat caches: they are neither served from nor stored in a cache.
+func Bypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassContextKey{}, true)
+}
+
+func bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassContextKey{}).(bool)
+	return bypass
+}
+
+// Deterministic is the default condition of a ChatStrategy, caching only calls with a temperature of 0,
+// whose completions are expected to be repeatable.
+func Deterministic(chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) bool {
+	return options.Temperature != nil && *options.Temperature == 0
+}
+
+// Always is a condition for WithCondition caching every call, whatever its temperature.
+func Always(chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) bool {
+	return true
+}
+
+// Stats counts the outcomes of cached calls.
+type Stats struct {
+	Hits   int64
+	Misses int64
+	// Shared counts calls that waited for an identical call in flight instead of sending their own.
+	Shared int64
+}
+
+type stats struct {
+	hits, misses, shared atomic.Int64
+}
+
+func (s *stats) snapshot() Stats {
+	return Stats{Hits: s.hits.Load(), Misses: s.misses.Load(), Shared: s.shared.Load()}
+}
+
+// ChatStrategy is an llmconnector.ChatStrategy serving identical requests from a store. Requests are identified
+// by a hash of the provider, the messages and the options. Concurrent identical requests share one upstream call.
+type ChatStrategy struct {
+	next      llmconnector.ChatStrategy
+	store     ChatStore
+	ttl       time.Duration
+	condition func(chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) bool
+	onError   func(ctx context.Context, err error)
+
+	stats   stats
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is an upstream call shared by identical requests.
+type flight struct {
+	done chan struct{}
+	resp llmconnector.ChatResponse
+	err  error
+}
+
+// ChatOption configures a ChatStrategy.
+type ChatOption func(s *ChatStrategy)
+
+// WithTTL sets how long responses are served from the store. Defaults to 24 hours; 0 keeps them until evicted.
+func WithTTL(ttl time.Duration) ChatOption {
+	return func(s *ChatStrategy) {
+		s.ttl = ttl
+	}
+}
+
+// WithCondition restricts caching to the calls condition accepts. Other calls pass through. Defaults to
+// Deterministic; use Always to cache calls sampled at any temperature.
+func WithCondition(condition func(chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) bool) ChatOption {
+	return func(s *ChatStrategy) {
+		s.condition = condition
+	}
+}
+
+// WithChatErrorHandler receives store errors, which never fail a call. They are dropped by default.
+func WithChatErrorHandler(handler func(ctx context.Context, err error)) ChatOption {
+	return func(s *ChatStrategy) {
+		s.onError = handler
+	}
+}
+
+// NewChatStrategy caches the responses of next in store.
+func NewChatStrategy(next llmconnector.ChatStrategy, store ChatStore, opts ...ChatOption) *ChatStrategy {
+	s := &ChatStrategy{
+		next:      next,
+		store:     store,
+		ttl:       24 * time.Hour,
+		condition: Deterministic,
+		onError:   func(context.Context, error) {},
+		flights:   make(map[string]*flight),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ProviderName reports the provider of the wrapped strategy.
+func (s *ChatStrategy) ProviderName() string {
+	if namer, ok := s.next.(llmconnector.ProviderNamer); ok {
+		return namer.ProviderName()
+	}
+	return ""
+}
+
+// DefaultChatModel reports the default model of the wrapped strategy.
+func (s *ChatStrategy) DefaultChatModel() string {
+	return defaultChatModel(s.next)
+}
+
+// Stats returns the counts of hits, misses and shared calls so far.
+func (s *ChatStrategy) Stats() Stats {
+	return s.stats.snapshot()
+}
+
+// Chat implements llmconnector.ChatStrategy. Hits of streamed calls deliver the cached content as a single delta.
+// A call shared by identical requests runs until it completes even if the requests waiting for it are canceled,
+// so that its response is stored; its duration is bounded by the timeouts of the wrapped strategy.
+func (s *ChatStrategy) Chat(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (llmconnector.ChatResponse, error) {
+	if bypassed(ctx) || (s.condition != nil && !s.condition(chatMessages, options)) {
+		return s.next.Chat(ctx, chatMessages, options)
+	}
+	key, err := chatKey(s.ProviderName(), chatMessages, keyedChatOptions(s.next, options))
+	if err != nil {
+		return nil, err
+	}
+
+	if cached, ok := s.lookup(ctx, key); ok {
+		s.stats.hits.Add(1)
+		if options.StreamHandler != nil {
+			if err := options.StreamHandler(cached.Content); err != nil {
+				return nil, err
+			}
+		}
+		return cached, nil
+	}
+
+	// Streamed calls need their own deltas and cannot share a call in flight.
+	if options.StreamHandler != nil {
+		s.stats.misses.Add(1)
+		resp, err := s.next.Chat(ctx, chatMessages, options)
+		if err == nil {
+			s.save(ctx, key, resp)
+		}
+		return resp, err
+	}
+
+	s.mu.Lock()
+	f, ok := s.flights[key]
+	if ok {
+		s.stats.shared.Add(1)
+	} else {
+		f = &flight{done: make(chan struct{})}
+		s.flights[key] = f
+		s.stats.misses.Add(1)
+		go s.fly(context.WithoutCancel(ctx), key, f, append([]llmconnector.ChatMessage(nil), chatMessages...), *options)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.resp, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fly sends the upstream call of f and stores its response. It runs detached from the caller that started it,
+// so that caller giving up does not fail the others waiting for the call.
+func (s *ChatStrategy) fly(ctx context.Context, key string, f *flight, chatMessages []llmconnector.ChatMessage, options llmconnector.ChatOptions) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.resp, f.err = nil, fmt.Errorf("chat cache: upstream call panicked: %v", r)
+		}
+		s.mu.Lock()
+		delete(s.flights, key)
+		s.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.resp, f.err = s.next.Chat(ctx, chatMessages, &options)
+	if f.err == nil {
+		s.save(ctx, key, f.resp)
+	}
+}
+
+func (s *ChatStrategy) lookup(ctx context.Context, key string) (*CachedChatResponse, bool) {
+	entry, ok, err := s.store.Get(ctx, key)
+	if err != nil {
+		s.onError(ctx, fmt.Errorf("failed to read chat cache: %w", err))
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	return entry.response(), true
+}
+
+func (s *ChatStrategy) save(ctx context.Context, key string, resp llmconnector.ChatResponse) {
+	if err := s.store.Set(ctx, key, newChatEntry(resp), s.ttl); err != nil {
+		s.onError(ctx, fmt.Errorf("failed to write chat cache: %w", err))
+	}
+}
+
+func defaultChatModel(strategy llmconnector.ChatStrategy) string {
+	if defaulter, ok := strategy.(llmconnector.DefaultChatModeler); ok {
+		return defaulter.DefaultChatModel()
+	}
+	return ""
+}
+
+// keyedChatOptions returns options with the default model of next filled in, so that calls relying on
+// different default models never share a key.
+func keyedChatOptions(next llmconnector.ChatStrategy, options *llmconnector.ChatOptions) *llmconnector.ChatOptions {
+	if options.Model != "" {
+		return options
+	}
+	keyed := *options
+	keyed.Model = defaultChatModel(next)
+	return &keyed
+}
+
+// chatKey hashes the canonical JSON encoding of a request. Stream handlers are not encoded.
+func chatKey(provider string, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (string, error) {
+	encoded, err := json.Marshal(struct {
+		Provider string                     `json:"provider"`
+		Messages []llmconnector.ChatMessage `json:"messages"`
+		Options  *llmconnector.ChatOptions  `json:"options"`
+	}{provider, chatMessages, options})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode chat request: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChatEntry is the serializable part of a chat response kept by a ChatStore.
+type ChatEntry struct {
+	Content       string             `json:"content"`
+	Usage         llmconnector.Usage `json:"usage"`
+	FinishReasons []string           `json:"finish_reasons,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+func newChatEntry(resp llmconnector.ChatResponse) ChatEntry {
+	usage, _ := llmconnector.UsageOf(resp)
+	return ChatEntry{
+		Content:       resp.GetContent(),
+		Usage:         usage,
+		FinishReasons: llmconnector.FinishReasonsOf(resp),
+		CreatedAt:     time.Now(),
+	}
+}
+
+func (e ChatEntry) response() *CachedChatResponse {
+	return &CachedChatResponse{
+		Content:       e.Content,
+		OriginalUsage: e.Usage,
+		FinishReasons: e.FinishReasons,
+		CreatedAt:     e.CreatedAt,
+	}
+}
+
+// CachedChatResponse is returned for cache hits. It reports no usage, since nothing was sent to the provider,
+// so cost tracking counts only real calls.
+type CachedChatResponse struct {
+	Content string
+	// OriginalUsage is the usage of the call that produced the response.
+	OriginalUsage llmconnector.Usage
+	FinishReasons []string
+	CreatedAt     time.Time
+	// Similarity is the cosine similarity of the prompt to the cached one for hits of a SemanticChatStrategy.
+	Similarity float64
+}
+
+func (r *CachedChatResponse) GetContent() string {
+	return r.Content
+}
+
+func (r *CachedChatResponse) GetUsage() llmconnector.Usage {
+	return llmconnector.Usage{}
+}
+
+func (r *CachedChatResponse) GetFinishReasons() []string {
+	return r.FinishReasons
+}
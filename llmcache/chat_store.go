@@ -0,0 +1,56 @@
+package llmcache
+
+import (
+	"context"
+	"time"
+)
+
+// ChatStore keeps chat responses by key. Implementations must be safe for concurrent use and should not return
+// entries whose ttl has elapsed.
+type ChatStore interface {
+	Get(ctx context.Context, key string) (ChatEntry, bool, error)
+	// Set stores entry for ttl. A ttl of 0 means no expiry.
+	Set(ctx context.Context, key string, entry ChatEntry, ttl time.Duration) error
+}
+
+// MemoryChatStore keeps the most recently used responses in memory.
+type MemoryChatStore struct {
+	entries *lru[memoryChatEntry]
+	now     func() time.Time
+}
+
+type memoryChatEntry struct {
+	entry   ChatEntry
+	expires time.Time
+}
+
+// NewMemoryChatStore creates a store keeping at most capacity responses. A capacity of 0 keeps all responses.
+func NewMemoryChatStore(capacity int) *MemoryChatStore {
+	return &MemoryChatStore{entries: newLRU[memoryChatEntry](capacity), now: time.Now}
+}
+
+func (s *MemoryChatStore) Get(ctx context.Context, key string) (ChatEntry, bool, error) {
+	stored, ok := s.entries.get(key)
+	if !ok {
+		return ChatEntry{}, false, nil
+	}
+	if !stored.expires.IsZero() && !s.now().Before(stored.expires) {
+		s.entries.delete(key)
+		return ChatEntry{}, false, nil
+	}
+	return stored.entry, true, nil
+}
+
+func (s *MemoryChatStore) Set(ctx context.Context, key string, entry ChatEntry, ttl time.Duration) error {
+	stored := memoryChatEntry{entry: entry}
+	if ttl > 0 {
+		stored.expires = s.now().Add(ttl)
+	}
+	s.entries.set(key, stored)
+	return nil
+}
+
+// Len returns the number of stored responses, including expired ones not yet evicted.
+func (s *MemoryChatStore) Len() int {
+	return s.entries.len()
+}
@@ -0,0 +1,202 @@
+package llmcache
+
+import (
+	"context"
+	"errors"
+	"github.com/simp-lee/llmconnector"
+	"github.com/simp-lee/llmconnector/llmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+var hello = []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}}
+
+func TestChatStrategy_ServesHits(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetProviderName("openai").
+		SetDefault(llmtest.Reply{Content: "Hi", Usage: llmconnector.Usage{PromptTokens: 3, TotalTokens: 4}, FinishReason: "stop"})
+	cache := NewChatStrategy(fake, NewMemoryChatStore(0), WithCondition(Always))
+	options := &llmconnector.ChatOptions{Model: "gpt-4o"}
+
+	first, err := cache.Chat(context.Background(), hello, options)
+	require.NoError(t, err)
+	second, err := cache.Chat(context.Background(), hello, options)
+	require.NoError(t, err)
+
+	llmtest.AssertChatCalls(t, fake, 1)
+	assert.Equal(t, first.GetContent(), second.GetContent())
+	require.IsType(t, &CachedChatResponse{}, second)
+	assert.Equal(t, llmconnector.Usage{PromptTokens: 3, TotalTokens: 4}, second.(*CachedChatResponse).OriginalUsage)
+	usage, _ := llmconnector.UsageOf(second)
+	assert.Zero(t, usage)
+	assert.Equal(t, []string{"stop"}, llmconnector.FinishReasonsOf(second))
+	assert.Equal(t, Stats{Hits: 1, Misses: 1}, cache.Stats())
+
+	// Different options are a different request.
+	_, err = cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{Model: "gpt-4o-mini"})
+	require.NoError(t, err)
+	llmtest.AssertChatCalls(t, fake, 2)
+}
+
+func TestChatStrategy_BypassAndCondition(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetDefault(llmtest.Reply{Content: "Hi"})
+	cache := NewChatStrategy(fake, NewMemoryChatStore(0))
+
+	zero, one := 0.0, 1.0
+	deterministic := &llmconnector.ChatOptions{Model: "m", Temperature: &zero}
+	for i := 0; i < 2; i++ {
+		_, err := cache.Chat(Bypass(context.Background()), hello, deterministic)
+		require.NoError(t, err)
+		// Only temperature-0 calls are cached by default.
+		_, err = cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{Model: "m"})
+		require.NoError(t, err)
+		_, err = cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{Model: "m", Temperature: &one})
+		require.NoError(t, err)
+	}
+	llmtest.AssertChatCalls(t, fake, 6)
+	assert.Zero(t, cache.Stats())
+
+	all := NewChatStrategy(fake, NewMemoryChatStore(0), WithCondition(Always))
+	for i := 0; i < 2; i++ {
+		_, err := all.Chat(context.Background(), hello, &llmconnector.ChatOptions{Model: "m", Temperature: &one})
+		require.NoError(t, err)
+	}
+	llmtest.AssertChatCalls(t, fake, 7)
+}
+
+func TestChatStrategy_TTL(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetDefault(llmtest.Reply{Content: "Hi"})
+	store := NewMemoryChatStore(0)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	cache := NewChatStrategy(fake, store, WithTTL(time.Minute), WithCondition(Always))
+
+	_, err := cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{})
+	require.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	_, err = cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{})
+	require.NoError(t, err)
+	llmtest.AssertChatCalls(t, fake, 2)
+}
+
+func TestChatStrategy_Singleflight(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetDefault(llmtest.Reply{Content: "Hi", Latency: 50 * time.Millisecond})
+	cache := NewChatStrategy(fake, NewMemoryChatStore(0), WithCondition(Always))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, "Hi", resp.GetContent())
+		}()
+	}
+	wg.Wait()
+	llmtest.AssertChatCalls(t, fake, 1)
+	assert.Equal(t, int64(4), cache.Stats().Shared)
+}
+
+func TestChatStrategy_SingleflightOutlivesCanceledCaller(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetDefault(llmtest.Reply{Content: "Hi", Latency: 50 * time.Millisecond})
+	cache := NewChatStrategy(fake, NewMemoryChatStore(0), WithCondition(Always))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := cache.Chat(ctx, hello, &llmconnector.ChatOptions{})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	// The first caller gives up, but the call it started still serves the caller waiting for it and the store.
+	resp, err := cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", resp.GetContent())
+	wg.Wait()
+
+	_, err = cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{})
+	require.NoError(t, err)
+	llmtest.AssertChatCalls(t, fake, 1)
+	assert.Equal(t, Stats{Hits: 1, Misses: 1, Shared: 1}, cache.Stats())
+}
+
+type panickingChatStrategy struct{}
+
+func (panickingChatStrategy) Chat(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (llmconnector.ChatResponse, error) {
+	panic("boom")
+}
+
+func TestChatStrategy_PanicClosesFlight(t *testing.T) {
+	cache := NewChatStrategy(panickingChatStrategy{}, NewMemoryChatStore(0), WithCondition(Always))
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "boom")
+	}
+	assert.Empty(t, cache.flights)
+}
+
+// defaultModelChatStrategy reports a default model, like a provider strategy with Config.ChatModel set.
+type defaultModelChatStrategy struct {
+	llmconnector.ChatStrategy
+	model string
+}
+
+func (s defaultModelChatStrategy) DefaultChatModel() string {
+	return s.model
+}
+
+func TestChatStrategy_KeysDefaultModel(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetDefault(llmtest.Reply{Content: "Hi"})
+	store := NewMemoryChatStore(0)
+	mini := NewChatStrategy(defaultModelChatStrategy{fake, "gpt-4o-mini"}, store, WithCondition(Always))
+	full := NewChatStrategy(defaultModelChatStrategy{fake, "gpt-4o"}, store, WithCondition(Always))
+	assert.Equal(t, "gpt-4o", full.DefaultChatModel())
+
+	// Calls without a model are keyed by the default model they are sent with.
+	for _, cache := range []*ChatStrategy{mini, full, full} {
+		_, err := cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{})
+		require.NoError(t, err)
+	}
+	llmtest.AssertChatCalls(t, fake, 2)
+	_, err := mini.Chat(context.Background(), hello, &llmconnector.ChatOptions{Model: "gpt-4o-mini"})
+	require.NoError(t, err)
+	llmtest.AssertChatCalls(t, fake, 2)
+}
+
+func TestChatStrategy_ErrorsAreNotCached(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().EnqueueError(errors.New("unavailable")).EnqueueContent("Hi")
+	cache := NewChatStrategy(fake, NewMemoryChatStore(0), WithCondition(Always))
+
+	_, err := cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{})
+	assert.EqualError(t, err, "unavailable")
+	resp, err := cache.Chat(context.Background(), hello, &llmconnector.ChatOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", resp.GetContent())
+}
+
+func TestChatStrategy_StreamedHit(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetDefault(llmtest.Reply{Chunks: []string{"H", "i"}})
+	cache := NewChatStrategy(fake, NewMemoryChatStore(0), WithCondition(Always))
+
+	var deltas []string
+	options := &llmconnector.ChatOptions{StreamHandler: func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	}}
+	_, err := cache.Chat(context.Background(), hello, options)
+	require.NoError(t, err)
+	_, err = cache.Chat(context.Background(), hello, options)
+	require.NoError(t, err)
+
+	llmtest.AssertChatCalls(t, fake, 1)
+	assert.Equal(t, []string{"H", "i", "Hi"}, deltas)
+}
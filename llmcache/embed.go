@@ -0,0 +1,164 @@
+// Package llmcache caches the results of llmconnector strategies: embeddings by text, chat responses by request,
+// and chat responses by the meaning of the prompt.
+package llmcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+)
+
+// EmbedStrategy is an llmconnector.EmbedStrategy serving embeddings from a store and sending only the texts
+// it misses to the wrapped strategy. Entries are keyed by provider, embed options and a hash of the text,
+// so vectors of different models or dimensions never mix.
+type EmbedStrategy struct {
+	next    llmconnector.EmbedStrategy
+	store   EmbeddingStore
+	onError func(ctx context.Context, err error)
+}
+
+// EmbedOption configures an EmbedStrategy.
+type EmbedOption func(s *EmbedStrategy)
+
+// WithEmbedErrorHandler receives store errors, which never fail a call: failed reads are treated as misses
+// and failed writes are skipped. They are dropped by default.
+func WithEmbedErrorHandler(handler func(ctx context.Context, err error)) EmbedOption {
+	return func(s *EmbedStrategy) {
+		s.onError = handler
+	}
+}
+
+// NewEmbedStrategy caches the embeddings of next in store.
+func NewEmbedStrategy(next llmconnector.EmbedStrategy, store EmbeddingStore, opts ...EmbedOption) *EmbedStrategy {
+	s := &EmbedStrategy{
+		next:    next,
+		store:   store,
+		onError: func(context.Context, error) {},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ProviderName reports the provider of the wrapped strategy, so telemetry and pricing are unaffected by caching.
+func (s *EmbedStrategy) ProviderName() string {
+	if namer, ok := s.next.(llmconnector.ProviderNamer); ok {
+		return namer.ProviderName()
+	}
+	return ""
+}
+
+// DefaultEmbedModel reports the default model of the wrapped strategy.
+func (s *EmbedStrategy) DefaultEmbedModel() string {
+	if defaulter, ok := s.next.(llmconnector.DefaultEmbedModeler); ok {
+		return defaulter.DefaultEmbedModel()
+	}
+	return ""
+}
+
+// Embed implements llmconnector.EmbedStrategy. Calls asking for sparse vectors are not cached, nor are calls
+// setting no model to a strategy that reports no default model, since the model of their vectors is unknown.
+func (s *EmbedStrategy) Embed(ctx context.Context, texts []string, options *llmconnector.EmbedOptions) (llmconnector.EmbedResponse, error) {
+	keyOptions := *options
+	if keyOptions.Model == "" {
+		keyOptions.Model = s.DefaultEmbedModel()
+	}
+	if keyOptions.Model == "" || options.OutputType == llmconnector.OutputTypeSparse || options.OutputType == llmconnector.OutputTypeDenseAndSparse {
+		return s.next.Embed(ctx, texts, options)
+	}
+	prefix, err := s.keyPrefix(&keyOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EmbedResponse{Embeddings: make([][]float32, len(texts))}
+	keys := make([]string, len(texts))
+	// missing maps the key of every text to fetch to the positions it fills; duplicates are fetched once.
+	missing := make(map[string][]int)
+	var missTexts, missKeys []string
+	for i, text := range texts {
+		keys[i] = embeddingKey(prefix, text)
+		if positions, ok := missing[keys[i]]; ok {
+			missing[keys[i]] = append(positions, i)
+			continue
+		}
+		vector, ok, err := s.store.Get(ctx, keys[i])
+		if err != nil {
+			s.onError(ctx, fmt.Errorf("failed to read embedding cache: %w", err))
+		}
+		if ok {
+			result.Embeddings[i] = vector
+			result.Hits++
+			continue
+		}
+		missing[keys[i]] = []int{i}
+		missTexts = append(missTexts, text)
+		missKeys = append(missKeys, keys[i])
+	}
+	if len(missTexts) == 0 {
+		return result, nil
+	}
+
+	missOptions := *options
+	resp, err := s.next.Embed(ctx, missTexts, &missOptions)
+	if err != nil {
+		return nil, err
+	}
+	embeddings := resp.GetEmbeddings()
+	if len(embeddings) != len(missTexts) {
+		return nil, fmt.Errorf("embedding cache: %d texts returned %d embeddings", len(missTexts), len(embeddings))
+	}
+	result.Usage, _ = llmconnector.UsageOf(resp)
+	for i, key := range missKeys {
+		// Repeated texts get copies, so that changing one returned vector never changes another.
+		for j, position := range missing[key] {
+			if j == 0 {
+				result.Embeddings[position] = embeddings[i]
+			} else {
+				result.Embeddings[position] = append([]float32(nil), embeddings[i]...)
+			}
+		}
+		result.Misses += len(missing[key])
+		if err := s.store.Set(ctx, key, embeddings[i]); err != nil {
+			s.onError(ctx, fmt.Errorf("failed to write embedding cache: %w", err))
+		}
+	}
+	return result, nil
+}
+
+// keyPrefix identifies the vector space of a call: the provider, the model and every option shaping the vectors.
+func (s *EmbedStrategy) keyPrefix(options *llmconnector.EmbedOptions) (string, error) {
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode embed options: %w", err)
+	}
+	return s.ProviderName() + "\x00" + string(encoded) + "\x00", nil
+}
+
+func embeddingKey(prefix, text string) string {
+	sum := sha256.Sum256([]byte(prefix + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// EmbedResponse is the response of an EmbedStrategy.
+type EmbedResponse struct {
+	// Embeddings are in input order.
+	Embeddings [][]float32
+	// Usage is the usage of the call for the missed texts, zero when every text was cached.
+	Usage llmconnector.Usage
+	// Hits and Misses count the texts served from the store and sent to the wrapped strategy.
+	Hits   int
+	Misses int
+}
+
+func (r *EmbedResponse) GetEmbeddings() [][]float32 {
+	return r.Embeddings
+}
+
+func (r *EmbedResponse) GetUsage() llmconnector.Usage {
+	return r.Usage
+}
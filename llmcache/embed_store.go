@@ -0,0 +1,118 @@
+package llmcache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// EmbeddingStore keeps vectors by key. Implementations must be safe for concurrent use.
+type EmbeddingStore interface {
+	// Get returns the vector of key, or false when it is not stored.
+	Get(ctx context.Context, key string) ([]float32, bool, error)
+	Set(ctx context.Context, key string, vector []float32) error
+}
+
+// MemoryEmbeddingStore keeps the most recently used vectors in memory. It stores and returns copies,
+// so callers may modify the vectors they pass and receive.
+type MemoryEmbeddingStore struct {
+	entries *lru[[]float32]
+}
+
+// NewMemoryEmbeddingStore creates a store keeping at most capacity vectors. A capacity of 0 keeps all vectors.
+func NewMemoryEmbeddingStore(capacity int) *MemoryEmbeddingStore {
+	return &MemoryEmbeddingStore{entries: newLRU[[]float32](capacity)}
+}
+
+func (s *MemoryEmbeddingStore) Get(ctx context.Context, key string) ([]float32, bool, error) {
+	vector, ok := s.entries.get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]float32(nil), vector...), true, nil
+}
+
+func (s *MemoryEmbeddingStore) Set(ctx context.Context, key string, vector []float32) error {
+	s.entries.set(key, append([]float32(nil), vector...))
+	return nil
+}
+
+// Len returns the number of stored vectors.
+func (s *MemoryEmbeddingStore) Len() int {
+	return s.entries.len()
+}
+
+// FileEmbeddingStore keeps vectors on disk, one file per key under a directory, so they survive restarts
+// and can be shared by processes on the same machine. Files are written atomically.
+type FileEmbeddingStore struct {
+	dir string
+}
+
+// NewFileEmbeddingStore creates a store in dir, creating the directory if needed.
+func NewFileEmbeddingStore(dir string) (*FileEmbeddingStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create embedding cache directory: %w", err)
+	}
+	return &FileEmbeddingStore{dir: dir}, nil
+}
+
+// path spreads the files over subdirectories named after the first two characters of the key.
+func (s *FileEmbeddingStore) path(key string) string {
+	if len(key) < 3 {
+		return filepath.Join(s.dir, key)
+	}
+	return filepath.Join(s.dir, key[:2], key)
+}
+
+func (s *FileEmbeddingStore) Get(ctx context.Context, key string) ([]float32, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data)%4 != 0 {
+		return nil, false, fmt.Errorf("corrupt embedding cache entry %s", key)
+	}
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector, true, nil
+}
+
+func (s *FileEmbeddingStore) Set(ctx context.Context, key string, vector []float32) error {
+	data := make([]byte, len(vector)*4)
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+	}
+
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
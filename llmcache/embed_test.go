@@ -0,0 +1,148 @@
+package llmcache
+
+import (
+	"context"
+	"errors"
+	"github.com/simp-lee/llmconnector"
+	"github.com/simp-lee/llmconnector/llmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEmbedStrategy_OnlySendsMisses(t *testing.T) {
+	fake := llmtest.NewFakeEmbedStrategy().SetProviderName("openai")
+	cache := NewEmbedStrategy(fake, NewMemoryEmbeddingStore(0))
+	options := &llmconnector.EmbedOptions{Model: "text-embedding-3-small"}
+
+	first, err := cache.Embed(context.Background(), []string{"a", "b"}, options)
+	require.NoError(t, err)
+	assert.Equal(t, 2, first.(*EmbedResponse).Misses)
+
+	second, err := cache.Embed(context.Background(), []string{"c", "b", "a", "c"}, options)
+	require.NoError(t, err)
+
+	calls := fake.Calls()
+	require.Len(t, calls, 2)
+	llmtest.AssertTexts(t, calls[1], "c")
+	assert.Equal(t, 2, second.(*EmbedResponse).Hits)
+	assert.Equal(t, 2, second.(*EmbedResponse).Misses)
+
+	var hash llmtest.HashEmbedder
+	assert.Equal(t, [][]float32{hash.Vector("c"), hash.Vector("b"), hash.Vector("a"), hash.Vector("c")}, second.GetEmbeddings())
+	assert.Equal(t, "openai", cache.ProviderName())
+}
+
+func TestEmbedStrategy_KeyedByOptions(t *testing.T) {
+	fake := llmtest.NewFakeEmbedStrategy()
+	cache := NewEmbedStrategy(fake, NewMemoryEmbeddingStore(0))
+
+	_, err := cache.Embed(context.Background(), []string{"a"}, &llmconnector.EmbedOptions{Model: "m1"})
+	require.NoError(t, err)
+	_, err = cache.Embed(context.Background(), []string{"a"}, &llmconnector.EmbedOptions{Model: "m2"})
+	require.NoError(t, err)
+	_, err = cache.Embed(context.Background(), []string{"a"}, &llmconnector.EmbedOptions{Model: "m1", EmbeddingType: "query"})
+	require.NoError(t, err)
+	llmtest.AssertEmbedCalls(t, fake, 3)
+}
+
+// defaultModelEmbedStrategy reports a default model, like a provider strategy with Config.EmbedModel set.
+type defaultModelEmbedStrategy struct {
+	llmconnector.EmbedStrategy
+	model string
+}
+
+func (s defaultModelEmbedStrategy) DefaultEmbedModel() string {
+	return s.model
+}
+
+func TestEmbedStrategy_KeyedByDefaultModel(t *testing.T) {
+	fake := llmtest.NewFakeEmbedStrategy()
+	store := NewMemoryEmbeddingStore(0)
+	v2 := NewEmbedStrategy(defaultModelEmbedStrategy{fake, "text-embedding-v2"}, store)
+	v3 := NewEmbedStrategy(defaultModelEmbedStrategy{fake, "text-embedding-v3"}, store)
+	assert.Equal(t, "text-embedding-v3", v3.DefaultEmbedModel())
+
+	for _, cache := range []*EmbedStrategy{v2, v3, v3} {
+		_, err := cache.Embed(context.Background(), []string{"a"}, &llmconnector.EmbedOptions{})
+		require.NoError(t, err)
+	}
+	llmtest.AssertEmbedCalls(t, fake, 2)
+	_, err := v2.Embed(context.Background(), []string{"a"}, &llmconnector.EmbedOptions{Model: "text-embedding-v2"})
+	require.NoError(t, err)
+	llmtest.AssertEmbedCalls(t, fake, 2)
+
+	// Without any model the vector space is unknown, so nothing is cached.
+	unknown := NewEmbedStrategy(fake, store)
+	for i := 0; i < 2; i++ {
+		_, err := unknown.Embed(context.Background(), []string{"a"}, &llmconnector.EmbedOptions{})
+		require.NoError(t, err)
+	}
+	llmtest.AssertEmbedCalls(t, fake, 4)
+	assert.Equal(t, 2, store.Len())
+}
+
+func TestEmbedStrategy_ReturnsIndependentVectors(t *testing.T) {
+	fake := llmtest.NewFakeEmbedStrategy()
+	cache := NewEmbedStrategy(fake, NewMemoryEmbeddingStore(0))
+	options := &llmconnector.EmbedOptions{Model: "m"}
+
+	first, err := cache.Embed(context.Background(), []string{"a", "a"}, options)
+	require.NoError(t, err)
+	first.GetEmbeddings()[0][0] = 42
+	assert.NotEqual(t, float32(42), first.GetEmbeddings()[1][0])
+
+	second, err := cache.Embed(context.Background(), []string{"a"}, options)
+	require.NoError(t, err)
+	assert.NotEqual(t, float32(42), second.GetEmbeddings()[0][0])
+	second.GetEmbeddings()[0][0] = 42
+
+	third, err := cache.Embed(context.Background(), []string{"a"}, options)
+	require.NoError(t, err)
+	assert.NotEqual(t, float32(42), third.GetEmbeddings()[0][0])
+}
+
+func TestEmbedStrategy_Error(t *testing.T) {
+	fake := llmtest.NewFakeEmbedStrategy().EnqueueError(errors.New("unavailable"))
+	store := NewMemoryEmbeddingStore(0)
+	cache := NewEmbedStrategy(fake, store)
+
+	_, err := cache.Embed(context.Background(), []string{"a"}, &llmconnector.EmbedOptions{Model: "m"})
+	assert.EqualError(t, err, "unavailable")
+	assert.Zero(t, store.Len())
+}
+
+func TestMemoryEmbeddingStore_Evicts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEmbeddingStore(2)
+	require.NoError(t, store.Set(ctx, "a", []float32{1}))
+	require.NoError(t, store.Set(ctx, "b", []float32{2}))
+	_, _, _ = store.Get(ctx, "a")
+	require.NoError(t, store.Set(ctx, "c", []float32{3}))
+
+	_, ok, _ := store.Get(ctx, "b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	vector, ok, _ := store.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []float32{1}, vector)
+}
+
+func TestFileEmbeddingStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileEmbeddingStore(dir)
+	require.NoError(t, err)
+
+	_, ok, err := store.Get(ctx, "abcdef")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Set(ctx, "abcdef", []float32{0.5, -1.25, 3}))
+
+	reopened, err := NewFileEmbeddingStore(dir)
+	require.NoError(t, err)
+	vector, ok, err := reopened.Get(ctx, "abcdef")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []float32{0.5, -1.25, 3}, vector)
+}
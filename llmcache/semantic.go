@@ -0,0 +1,255 @@
+package llmcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"github.com/simp-lee/llmconnector/vector"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SemanticChatStrategy is an llmconnector.ChatStrategy serving responses to prompts that mean the same as an earlier
+// one. It embeds the last user message and returns the response of the most similar cached prompt when their
+// cosine similarity reaches the threshold. Prompts only match within the same provider and options and after the
+// same earlier messages, system prompts included.
+type SemanticChatStrategy struct {
+	next         llmconnector.ChatStrategy
+	embedder     llmconnector.EmbedStrategy
+	embedOptions llmconnector.EmbedOptions
+	threshold    float64
+	capacity     int
+	ttl          time.Duration
+	condition    func(chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) bool
+	onError      func(ctx context.Context, err error)
+	now          func() time.Time
+
+	stats stats
+	mu    sync.RWMutex
+	// entries are the cached prompts in insertion order, indexed by scope and ID.
+	entries  []*semanticEntry
+	byID     map[string]*semanticEntry
+	indexes  map[string]*vector.FlatIndex
+	sequence int
+}
+
+type semanticEntry struct {
+	id      string
+	scope   string
+	entry   ChatEntry
+	expires time.Time
+}
+
+// SemanticOption configures a SemanticChatStrategy.
+type SemanticOption func(s *SemanticChatStrategy)
+
+// WithSimilarityThreshold sets the minimum cosine similarity of a hit. Defaults to 0.95.
+func WithSimilarityThreshold(threshold float64) SemanticOption {
+	return func(s *SemanticChatStrategy) {
+		s.threshold = threshold
+	}
+}
+
+// WithIndexCapacity sets how many prompts are kept. The oldest are dropped first. Defaults to 1000.
+func WithIndexCapacity(capacity int) SemanticOption {
+	return func(s *SemanticChatStrategy) {
+		s.capacity = capacity
+	}
+}
+
+// WithSemanticTTL sets how long responses are served. Defaults to 24 hours; 0 keeps them until dropped.
+func WithSemanticTTL(ttl time.Duration) SemanticOption {
+	return func(s *SemanticChatStrategy) {
+		s.ttl = ttl
+	}
+}
+
+// WithSemanticCondition restricts caching to the calls condition accepts. Other calls pass through.
+func WithSemanticCondition(condition func(chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) bool) SemanticOption {
+	return func(s *SemanticChatStrategy) {
+		s.condition = condition
+	}
+}
+
+// WithSemanticEmbedOptions sets the options of the calls embedding prompts, e.g. the embedding model.
+func WithSemanticEmbedOptions(opts ...llmconnector.EmbedOption) SemanticOption {
+	return func(s *SemanticChatStrategy) {
+		for _, opt := range opts {
+			opt(&s.embedOptions)
+		}
+	}
+}
+
+// WithSemanticErrorHandler receives embedding errors, which never fail a call: the call is sent uncached.
+// They are dropped by default.
+func WithSemanticErrorHandler(handler func(ctx context.Context, err error)) SemanticOption {
+	return func(s *SemanticChatStrategy) {
+		s.onError = handler
+	}
+}
+
+// NewSemanticChatStrategy caches the responses of next, embedding prompts with embedder.
+func NewSemanticChatStrategy(next llmconnector.ChatStrategy, embedder llmconnector.EmbedStrategy, opts ...SemanticOption) *SemanticChatStrategy {
+	s := &SemanticChatStrategy{
+		next:      next,
+		embedder:  embedder,
+		threshold: 0.95,
+		capacity:  1000,
+		ttl:       24 * time.Hour,
+		onError:   func(context.Context, error) {},
+		now:       time.Now,
+		byID:      make(map[string]*semanticEntry),
+		indexes:   make(map[string]*vector.FlatIndex),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ProviderName reports the provider of the wrapped strategy.
+func (s *SemanticChatStrategy) ProviderName() string {
+	if namer, ok := s.next.(llmconnector.ProviderNamer); ok {
+		return namer.ProviderName()
+	}
+	return ""
+}
+
+// DefaultChatModel reports the default model of the wrapped strategy.
+func (s *SemanticChatStrategy) DefaultChatModel() string {
+	return defaultChatModel(s.next)
+}
+
+// Stats returns the counts of hits and misses so far. Shared is always 0.
+func (s *SemanticChatStrategy) Stats() Stats {
+	return s.stats.snapshot()
+}
+
+// Len returns the number of cached prompts.
+func (s *SemanticChatStrategy) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Chat implements llmconnector.ChatStrategy. Calls whose last message is not from the user pass through.
+func (s *SemanticChatStrategy) Chat(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (llmconnector.ChatResponse, error) {
+	if bypassed(ctx) || len(chatMessages) == 0 || chatMessages[len(chatMessages)-1].Role != "user" ||
+		(s.condition != nil && !s.condition(chatMessages, options)) {
+		return s.next.Chat(ctx, chatMessages, options)
+	}
+
+	// Everything but the last user message must be equal for two prompts to share a response.
+	scope, err := chatKey(s.ProviderName(), chatMessages[:len(chatMessages)-1], keyedChatOptions(s.next, options))
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := chatMessages[len(chatMessages)-1].Content
+	embedOptions := s.embedOptions
+	resp, err := s.embedder.Embed(ctx, []string{prompt}, &embedOptions)
+	if err == nil && len(resp.GetEmbeddings()) != 1 {
+		err = fmt.Errorf("embedding the prompt returned %d embeddings", len(resp.GetEmbeddings()))
+	}
+	if err != nil {
+		s.onError(ctx, fmt.Errorf("failed to embed prompt for semantic cache: %w", err))
+		return s.next.Chat(ctx, chatMessages, options)
+	}
+	embedding := resp.GetEmbeddings()[0]
+
+	if cached, ok := s.search(ctx, scope, embedding); ok {
+		s.stats.hits.Add(1)
+		if options.StreamHandler != nil {
+			if err := options.StreamHandler(cached.Content); err != nil {
+				return nil, err
+			}
+		}
+		return cached, nil
+	}
+
+	s.stats.misses.Add(1)
+	chatResp, err := s.next.Chat(ctx, chatMessages, options)
+	if err != nil {
+		return nil, err
+	}
+	s.add(ctx, &semanticEntry{scope: scope, entry: newChatEntry(chatResp)}, embedding)
+	return chatResp, nil
+}
+
+func (s *SemanticChatStrategy) search(ctx context.Context, scope string, embedding []float32) (*CachedChatResponse, bool) {
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index, ok := s.indexes[scope]
+	if !ok {
+		return nil, false
+	}
+	matches, err := index.Search(embedding, 1, func(id string, metadata vector.Metadata) bool {
+		expires := s.byID[id].expires
+		return expires.IsZero() || now.Before(expires)
+	})
+	if err != nil {
+		s.onError(ctx, fmt.Errorf("failed to search semantic cache: %w", err))
+		return nil, false
+	}
+	if len(matches) == 0 || float64(matches[0].Score) < s.threshold {
+		return nil, false
+	}
+	resp := s.byID[matches[0].ID].entry.response()
+	resp.Similarity = float64(matches[0].Score)
+	return resp, true
+}
+
+func (s *SemanticChatStrategy) add(ctx context.Context, entry *semanticEntry, embedding []float32) {
+	now := s.now()
+	if s.ttl > 0 {
+		entry.expires = now.Add(s.ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Entries are appended in time order, so expired and excess entries are at the front.
+	drop := 0
+	for drop < len(s.entries) && !s.entries[drop].expires.IsZero() && !now.Before(s.entries[drop].expires) {
+		drop++
+	}
+	if excess := len(s.entries) - drop + 1 - s.capacity; s.capacity > 0 && excess > 0 {
+		drop += excess
+	}
+	for _, dropped := range s.entries[:drop] {
+		s.remove(dropped)
+	}
+	if drop > 0 {
+		s.entries = append(s.entries[:0:0], s.entries[drop:]...)
+	}
+
+	s.sequence++
+	entry.id = strconv.Itoa(s.sequence)
+	index, ok := s.indexes[entry.scope]
+	if !ok {
+		index = vector.NewFlatIndex(vector.MetricCosine)
+		s.indexes[entry.scope] = index
+	}
+	if err := index.Add(entry.id, embedding, nil); err != nil {
+		s.onError(ctx, fmt.Errorf("failed to add prompt to semantic cache: %w", err))
+		if index.Len() == 0 {
+			delete(s.indexes, entry.scope)
+		}
+		return
+	}
+	s.byID[entry.id] = entry
+	s.entries = append(s.entries, entry)
+}
+
+// remove deletes entry from its index. The caller holds s.mu and removes it from s.entries.
+func (s *SemanticChatStrategy) remove(entry *semanticEntry) {
+	delete(s.byID, entry.id)
+	if index, ok := s.indexes[entry.scope]; ok {
+		index.Delete(entry.id)
+		if index.Len() == 0 {
+			delete(s.indexes, entry.scope)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package llmcache
+
+import (
+	"context"
+	"errors"
+	"github.com/simp-lee/llmconnector"
+	"github.com/simp-lee/llmconnector/llmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// synonymEmbedder embeds texts as fixed vectors, so tests control which prompts are similar.
+type synonymEmbedder map[string][]float32
+
+func (e synonymEmbedder) Embed(ctx context.Context, texts []string, options *llmconnector.EmbedOptions) (llmconnector.EmbedResponse, error) {
+	resp := &llmtest.EmbedResponse{}
+	for _, text := range texts {
+		vector, ok := e[text]
+		if !ok {
+			return nil, errors.New("unknown text")
+		}
+		resp.Embeddings = append(resp.Embeddings, vector)
+	}
+	return resp, nil
+}
+
+var synonyms = synonymEmbedder{
+	"What is the capital of France?":  {1, 0, 0},
+	"what's the capital of France":    {0.99, 0.1, 0},
+	"What is the capital of Germany?": {0.6, 0.8, 0},
+}
+
+func userMessages(system, prompt string) []llmconnector.ChatMessage {
+	var messages []llmconnector.ChatMessage
+	if system != "" {
+		messages = append(messages, llmconnector.ChatMessage{Role: "system", Content: system})
+	}
+	return append(messages, llmconnector.ChatMessage{Role: "user", Content: prompt})
+}
+
+func TestSemanticChatStrategy_ServesSimilarPrompts(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().EnqueueContent("Paris", "Berlin")
+	cache := NewSemanticChatStrategy(fake, synonyms, WithSimilarityThreshold(0.9))
+	options := &llmconnector.ChatOptions{Model: "qwen-max"}
+	ctx := context.Background()
+
+	_, err := cache.Chat(ctx, userMessages("", "What is the capital of France?"), options)
+	require.NoError(t, err)
+
+	resp, err := cache.Chat(ctx, userMessages("", "what's the capital of France"), options)
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", resp.GetContent())
+	require.IsType(t, &CachedChatResponse{}, resp)
+	assert.InDelta(t, 0.995, resp.(*CachedChatResponse).Similarity, 0.001)
+
+	resp, err = cache.Chat(ctx, userMessages("", "What is the capital of Germany?"), options)
+	require.NoError(t, err)
+	assert.Equal(t, "Berlin", resp.GetContent())
+
+	llmtest.AssertChatCalls(t, fake, 2)
+	assert.Equal(t, Stats{Hits: 1, Misses: 2}, cache.Stats())
+}
+
+func TestSemanticChatStrategy_Scopes(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetDefault(llmtest.Reply{Content: "Paris"})
+	cache := NewSemanticChatStrategy(fake, synonyms)
+	ctx := context.Background()
+	prompt := "What is the capital of France?"
+
+	for _, call := range []struct {
+		system, model string
+	}{
+		{"", "qwen-max"},
+		{"Answer in French.", "qwen-max"},
+		{"", "qwen-plus"},
+	} {
+		_, err := cache.Chat(ctx, userMessages(call.system, prompt), &llmconnector.ChatOptions{Model: call.model})
+		require.NoError(t, err)
+	}
+	llmtest.AssertChatCalls(t, fake, 3)
+
+	_, err := cache.Chat(ctx, userMessages("Answer in French.", prompt), &llmconnector.ChatOptions{Model: "qwen-max"})
+	require.NoError(t, err)
+	llmtest.AssertChatCalls(t, fake, 3)
+
+	// Sampling options and earlier turns are part of the scope too.
+	maxTokens := 10
+	_, err = cache.Chat(ctx, userMessages("", prompt), &llmconnector.ChatOptions{Model: "qwen-max", MaxTokens: &maxTokens})
+	require.NoError(t, err)
+	llmtest.AssertChatCalls(t, fake, 4)
+
+	conversation := []llmconnector.ChatMessage{
+		{Role: "user", Content: "Let's talk about Italy."},
+		{Role: "assistant", Content: "Sure."},
+		{Role: "user", Content: prompt},
+	}
+	_, err = cache.Chat(ctx, conversation, &llmconnector.ChatOptions{Model: "qwen-max"})
+	require.NoError(t, err)
+	llmtest.AssertChatCalls(t, fake, 5)
+	_, err = cache.Chat(ctx, conversation, &llmconnector.ChatOptions{Model: "qwen-max"})
+	require.NoError(t, err)
+	llmtest.AssertChatCalls(t, fake, 5)
+}
+
+func TestSemanticChatStrategy_CapacityAndTTL(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetDefault(llmtest.Reply{Content: "answer"})
+	cache := NewSemanticChatStrategy(fake, synonyms, WithIndexCapacity(1), WithSemanticTTL(time.Minute))
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, _ = cache.Chat(ctx, userMessages("", "What is the capital of France?"), &llmconnector.ChatOptions{})
+	_, _ = cache.Chat(ctx, userMessages("", "What is the capital of Germany?"), &llmconnector.ChatOptions{})
+	assert.Equal(t, 1, cache.Len())
+
+	now = now.Add(2 * time.Minute)
+	_, _ = cache.Chat(ctx, userMessages("", "What is the capital of Germany?"), &llmconnector.ChatOptions{})
+	llmtest.AssertChatCalls(t, fake, 3)
+}
+
+func TestSemanticChatStrategy_EmbeddingFailurePassesThrough(t *testing.T) {
+	fake := llmtest.NewFakeChatStrategy().SetDefault(llmtest.Reply{Content: "answer"})
+	var embedErr error
+	cache := NewSemanticChatStrategy(fake, synonyms, WithSemanticErrorHandler(func(ctx context.Context, err error) {
+		embedErr = err
+	}))
+
+	resp, err := cache.Chat(context.Background(), userMessages("", "unknown prompt"), &llmconnector.ChatOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "answer", resp.GetContent())
+	assert.Error(t, embedErr)
+	assert.Zero(t, cache.Len())
+}
@@ -0,0 +1,205 @@
+package llmcost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Action is what happens to a call when its budget is exhausted.
+type Action int
+
+const (
+	// ActionBlock fails the call with a *BudgetExceededError.
+	ActionBlock Action = iota
+	// ActionWarn lets the call through after notifying Budget.OnExceeded.
+	ActionWarn
+)
+
+// Budget limits the spend of the calls matching Tags over a rolling Window.
+// Budgets are checked before a call, so the call exhausting a budget still completes.
+// Limit and Window must be positive, and Window must not exceed the retention of a MemoryStore.
+type Budget struct {
+	Name string
+	// Tags selects the calls the budget applies to: all tags must be set to these values in the call context.
+	// An empty Tags applies to every call.
+	Tags   map[string]string
+	Limit  float64
+	Window time.Duration
+	Action Action
+	// OnExceeded, when set, is called for every call checked against an exhausted budget.
+	OnExceeded func(ctx context.Context, status BudgetStatus)
+}
+
+// BudgetStatus is the spend of a budget within its current window.
+type BudgetStatus struct {
+	Budget Budget
+	Spent  float64
+}
+
+// Exceeded reports whether the budget is exhausted.
+func (s BudgetStatus) Exceeded() bool {
+	return s.Spent >= s.Budget.Limit
+}
+
+// ErrBudgetExceeded is matched by every *BudgetExceededError.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetExceededError is returned by calls blocked by a budget.
+type BudgetExceededError struct {
+	Budget string
+	Spent  float64
+	Limit  float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("llmcost: budget %q exceeded: spent %.4f of %.4f", e.Budget, e.Spent, e.Limit)
+}
+
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+func (b Budget) matches(tags map[string]string) bool {
+	for key, value := range b.Tags {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (b Budget) validate(retention time.Duration) error {
+	switch {
+	case b.Limit <= 0:
+		return fmt.Errorf("llmcost: budget %q: limit must be positive", b.Name)
+	case b.Window <= 0:
+		return fmt.Errorf("llmcost: budget %q: window must be positive", b.Name)
+	case retention > 0 && b.Window > retention:
+		return fmt.Errorf("llmcost: budget %q: window %s exceeds the store retention %s", b.Name, b.Window, retention)
+	}
+	return nil
+}
+
+// budgetSpend is the running spend of a budget: the costs within its window, oldest first, and their sum.
+type budgetSpend struct {
+	budget Budget
+	costs  []timedCost
+	spent  float64
+}
+
+type timedCost struct {
+	time time.Time
+	cost float64
+}
+
+func (s *budgetSpend) add(t time.Time, cost float64) {
+	s.costs = append(s.costs, timedCost{time: t, cost: cost})
+	s.spent += cost
+}
+
+// total returns the spend within the window ending at now, dropping the older costs.
+func (s *budgetSpend) total(now time.Time) float64 {
+	since := now.Add(-s.budget.Window)
+	i := 0
+	for i < len(s.costs) && s.costs[i].time.Before(since) {
+		s.spent -= s.costs[i].cost
+		i++
+	}
+	if i > 0 {
+		s.costs = append(s.costs[:0:0], s.costs[i:]...)
+	}
+	if len(s.costs) == 0 {
+		s.spent = 0 // drop the rounding errors of the subtractions
+	}
+	return s.spent
+}
+
+// budgetSpends tracks the spend of the budgets of an Accountant as calls are recorded, so checking them does not
+// read the store.
+type budgetSpends struct {
+	mu     sync.Mutex
+	spends []*budgetSpend
+}
+
+func newBudgetSpends(budgets []Budget) *budgetSpends {
+	spends := make([]*budgetSpend, len(budgets))
+	for i, budget := range budgets {
+		spends[i] = &budgetSpend{budget: budget}
+	}
+	return &budgetSpends{spends: spends}
+}
+
+// load adds the records the store keeps within the longest window, e.g. from a previous process.
+func (b *budgetSpends) load(ctx context.Context, store Store, now time.Time) error {
+	var longest time.Duration
+	for _, spend := range b.spends {
+		longest = max(longest, spend.budget.Window)
+	}
+	records, err := store.Records(ctx, now.Add(-longest))
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	for _, record := range records {
+		b.add(record)
+	}
+	return nil
+}
+
+func (b *budgetSpends) add(record Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, spend := range b.spends {
+		if spend.budget.matches(record.Tags) {
+			spend.add(record.Time, record.Cost)
+		}
+	}
+}
+
+// exceeded returns the status of the budgets matching tags that are exhausted at now.
+func (b *budgetSpends) exceeded(tags map[string]string, now time.Time) []BudgetStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var exceeded []BudgetStatus
+	for _, spend := range b.spends {
+		if !spend.budget.matches(tags) {
+			continue
+		}
+		if status := (BudgetStatus{Budget: spend.budget, Spent: spend.total(now)}); status.Exceeded() {
+			exceeded = append(exceeded, status)
+		}
+	}
+	return exceeded
+}
+
+// Status returns the spend of budget within its window ending now, read from the store.
+func (a *Accountant) Status(ctx context.Context, budget Budget) (BudgetStatus, error) {
+	records, err := a.store.Records(ctx, a.now().Add(-budget.Window))
+	if err != nil {
+		return BudgetStatus{}, err
+	}
+	status := BudgetStatus{Budget: budget}
+	for _, record := range records {
+		if budget.matches(record.Tags) {
+			status.Spent += record.Cost
+		}
+	}
+	return status, nil
+}
+
+func (a *Accountant) checkBudgets(ctx context.Context) error {
+	for _, status := range a.spends.exceeded(TagsFromContext(ctx), a.now()) {
+		budget := status.Budget
+		if budget.OnExceeded != nil {
+			budget.OnExceeded(ctx, status)
+		}
+		if budget.Action == ActionBlock {
+			return &BudgetExceededError{Budget: budget.Name, Spent: status.Spent, Limit: budget.Limit}
+		}
+	}
+	return nil
+}
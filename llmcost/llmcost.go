@@ -0,0 +1,275 @@
+// Package llmcost computes the cost of llmconnector.ModelContext calls from token usage and a pricing table,
+// aggregates spend by provider, model and caller tags, and enforces budgets over rolling windows.
+package llmcost
+
+import (
+	"context"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"sort"
+	"time"
+)
+
+const (
+	OperationChat  = "chat"
+	OperationEmbed = "embed"
+)
+
+type tagsContextKey struct{}
+
+// WithTags returns a context whose calls are attributed to tags, e.g. {"team": "search"}.
+// Tags are merged with those already in ctx, overriding equal keys.
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	for key, value := range TagsFromContext(ctx) {
+		merged[key] = value
+	}
+	for key, value := range tags {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, tagsContextKey{}, merged)
+}
+
+// TagsFromContext returns the tags set with WithTags. The map must not be modified.
+func TagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsContextKey{}).(map[string]string)
+	return tags
+}
+
+// Record is the cost of a single call.
+type Record struct {
+	Time      time.Time          `json:"time"`
+	Provider  string             `json:"provider"`
+	Model     string             `json:"model"`
+	Operation string             `json:"operation"`
+	Tags      map[string]string  `json:"tags,omitempty"`
+	Usage     llmconnector.Usage `json:"usage"`
+	// Cost is in the currency of the pricing table, USD for the default catalog.
+	Cost float64 `json:"cost"`
+	// Priced is false when the model was missing from the pricing table and Cost is 0.
+	Priced bool `json:"priced"`
+}
+
+// Pricing computes the cost of usage of a model.
+type Pricing interface {
+	Price(provider, model string, usage llmconnector.Usage) (cost float64, ok bool)
+}
+
+// CatalogPricing prices usage with the per-token prices of a model catalog.
+type CatalogPricing struct {
+	Catalog *llmconnector.Catalog
+}
+
+func (p CatalogPricing) Price(provider, model string, usage llmconnector.Usage) (float64, bool) {
+	info, ok := p.Catalog.Lookup(provider, model)
+	if !ok {
+		return 0, false
+	}
+	return info.Cost(usage), true
+}
+
+type config struct {
+	pricing Pricing
+	store   Store
+	budgets []Budget
+	now     func() time.Time
+	onError func(ctx context.Context, err error)
+}
+
+// Option configures an Accountant.
+type Option func(c *config)
+
+// WithPricing sets the pricing table. Defaults to the prices of llmconnector.DefaultCatalog.
+func WithPricing(pricing Pricing) Option {
+	return func(c *config) {
+		c.pricing = pricing
+	}
+}
+
+// WithStore sets where records are kept. Defaults to a MemoryStore keeping records for 31 days.
+func WithStore(store Store) Option {
+	return func(c *config) {
+		c.store = store
+	}
+}
+
+// WithBudgets adds budgets checked before every call. Their spend is loaded from the store by New and then kept
+// up to date with the calls of the Accountant; records appended to the store by others are not counted.
+func WithBudgets(budgets ...Budget) Option {
+	return func(c *config) {
+		c.budgets = append(c.budgets, budgets...)
+	}
+}
+
+// WithClock sets the source of the current time, e.g. for tests.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// WithErrorHandler receives store errors, which never fail a call. They are dropped by default.
+func WithErrorHandler(handler func(ctx context.Context, err error)) Option {
+	return func(c *config) {
+		c.onError = handler
+	}
+}
+
+// Accountant records the cost of calls and enforces budgets.
+type Accountant struct {
+	config
+	spends *budgetSpends
+}
+
+// New creates an Accountant. It fails when a budget is invalid or its spend cannot be loaded from the store.
+func New(opts ...Option) (*Accountant, error) {
+	c := config{
+		now:     time.Now,
+		onError: func(context.Context, error) {},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.pricing == nil {
+		c.pricing = CatalogPricing{Catalog: llmconnector.DefaultCatalog()}
+	}
+	if c.store == nil {
+		c.store = NewMemoryStore(31 * 24 * time.Hour)
+	}
+
+	var retention time.Duration
+	if store, ok := c.store.(interface{ Retention() time.Duration }); ok {
+		retention = store.Retention()
+	}
+	for _, budget := range c.budgets {
+		if err := budget.validate(retention); err != nil {
+			return nil, err
+		}
+	}
+	a := &Accountant{config: c, spends: newBudgetSpends(c.budgets)}
+	if len(c.budgets) > 0 {
+		if err := a.spends.load(context.Background(), c.store, c.now()); err != nil {
+			return nil, fmt.Errorf("llmcost: failed to load budget spend: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// ChatMiddleware returns a middleware enforcing budgets and recording the cost of every ModelContext.Chat call.
+func (a *Accountant) ChatMiddleware() llmconnector.ChatMiddleware {
+	return func(next llmconnector.ChatHandler) llmconnector.ChatHandler {
+		return func(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (llmconnector.ChatResponse, error) {
+			if err := a.checkBudgets(ctx); err != nil {
+				return nil, err
+			}
+			resp, err := next(ctx, chatMessages, options)
+			if err == nil {
+				a.record(ctx, OperationChat, options.Model, resp)
+			}
+			return resp, err
+		}
+	}
+}
+
+// EmbedMiddleware returns a middleware enforcing budgets and recording the cost of every ModelContext.Embed call.
+func (a *Accountant) EmbedMiddleware() llmconnector.EmbedMiddleware {
+	return func(next llmconnector.EmbedHandler) llmconnector.EmbedHandler {
+		return func(ctx context.Context, texts []string, options *llmconnector.EmbedOptions) (llmconnector.EmbedResponse, error) {
+			if err := a.checkBudgets(ctx); err != nil {
+				return nil, err
+			}
+			resp, err := next(ctx, texts, options)
+			if err == nil {
+				a.record(ctx, OperationEmbed, options.Model, resp)
+			}
+			return resp, err
+		}
+	}
+}
+
+func (a *Accountant) record(ctx context.Context, operation, model string, resp interface{}) {
+	usage, ok := llmconnector.UsageOf(resp)
+	if !ok {
+		return
+	}
+	record := Record{
+		Time:      a.now(),
+		Provider:  llmconnector.ProviderFromContext(ctx),
+		Model:     model,
+		Operation: operation,
+		Tags:      TagsFromContext(ctx),
+		Usage:     usage,
+	}
+	record.Cost, record.Priced = a.pricing.Price(record.Provider, model, usage)
+	a.spends.add(record)
+	if err := a.store.Append(ctx, record); err != nil {
+		a.onError(ctx, err)
+	}
+}
+
+// Total is the aggregated spend of a group of calls.
+type Total struct {
+	Calls int
+	Usage llmconnector.Usage
+	Cost  float64
+}
+
+func (t *Total) add(record Record) {
+	t.Calls++
+	t.Usage.PromptTokens += record.Usage.PromptTokens
+	t.Usage.CompletionTokens += record.Usage.CompletionTokens
+	t.Usage.TotalTokens += record.Usage.TotalTokens
+	t.Cost += record.Cost
+}
+
+// Summary aggregates spend by provider, by model and by tag.
+type Summary struct {
+	Total Total
+	// ByProvider is keyed by provider name.
+	ByProvider map[string]Total
+	// ByModel is keyed by "provider:model".
+	ByModel map[string]Total
+	// ByTag is keyed by tag key, then tag value.
+	ByTag map[string]map[string]Total
+}
+
+// Summary aggregates the records since the given time.
+func (a *Accountant) Summary(ctx context.Context, since time.Time) (Summary, error) {
+	records, err := a.store.Records(ctx, since)
+	if err != nil {
+		return Summary{}, err
+	}
+	summary := Summary{
+		ByProvider: make(map[string]Total),
+		ByModel:    make(map[string]Total),
+		ByTag:      make(map[string]map[string]Total),
+	}
+	for _, record := range records {
+		summary.Total.add(record)
+		addTo(summary.ByProvider, record.Provider, record)
+		addTo(summary.ByModel, record.Provider+":"+record.Model, record)
+		for key, value := range record.Tags {
+			if summary.ByTag[key] == nil {
+				summary.ByTag[key] = make(map[string]Total)
+			}
+			addTo(summary.ByTag[key], value, record)
+		}
+	}
+	return summary, nil
+}
+
+func addTo(totals map[string]Total, key string, record Record) {
+	total := totals[key]
+	total.add(record)
+	totals[key] = total
+}
+
+// Records returns the records since the given time, oldest first.
+func (a *Accountant) Records(ctx context.Context, since time.Time) ([]Record, error) {
+	records, err := a.store.Records(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
@@ -0,0 +1,175 @@
+package llmcost
+
+import (
+	"context"
+	"errors"
+	"github.com/simp-lee/llmconnector"
+	"github.com/simp-lee/llmconnector/llmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var hello = []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newModelContext(accountant *Accountant) *llmconnector.ModelContext {
+	chat := llmtest.NewFakeChatStrategy().SetProviderName("openai").
+		SetDefault(llmtest.Reply{Content: "Hi", Usage: llmconnector.Usage{PromptTokens: 1000000, CompletionTokens: 100000}})
+	embed := llmtest.NewFakeEmbedStrategy(llmtest.EmbedReply{Usage: llmconnector.Usage{PromptTokens: 500000}}).
+		SetProviderName("openai")
+
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(chat)
+	modelContext.SetEmbedStrategy(embed)
+	modelContext.UseChat(accountant.ChatMiddleware())
+	modelContext.UseEmbed(accountant.EmbedMiddleware())
+	return modelContext
+}
+
+func TestAccountant_Summary(t *testing.T) {
+	accountant, err := New()
+	require.NoError(t, err)
+	modelContext := newModelContext(accountant)
+
+	ctx := WithTags(context.Background(), map[string]string{"team": "search"})
+	_, err = modelContext.Chat(ctx, hello, llmconnector.WithChatModel("gpt-4o"))
+	require.NoError(t, err)
+	_, err = modelContext.Embed(WithTags(ctx, map[string]string{"env": "prod"}), []string{"text1"},
+		llmconnector.WithEmbedModel("text-embedding-3-small"))
+	require.NoError(t, err)
+	_, err = modelContext.Chat(context.Background(), hello, llmconnector.WithChatModel("unknown-model"))
+	require.NoError(t, err)
+
+	summary, err := accountant.Summary(context.Background(), time.Time{})
+	require.NoError(t, err)
+
+	// gpt-4o: 1M input tokens at $2.50 and 100k output tokens at $10 per million.
+	assert.InDelta(t, 3.5, summary.ByModel["openai:gpt-4o"].Cost, 1e-9)
+	assert.InDelta(t, 0.01, summary.ByModel["openai:text-embedding-3-small"].Cost, 1e-9)
+	assert.InDelta(t, 3.51, summary.Total.Cost, 1e-9)
+	assert.Equal(t, 3, summary.Total.Calls)
+	assert.Equal(t, 3, summary.ByProvider["openai"].Calls)
+	assert.Equal(t, 2, summary.ByTag["team"]["search"].Calls)
+	assert.Equal(t, 1, summary.ByTag["env"]["prod"].Calls)
+
+	records, err := accountant.Records(context.Background(), time.Time{})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, OperationEmbed, records[1].Operation)
+	assert.Equal(t, map[string]string{"team": "search", "env": "prod"}, records[1].Tags)
+	assert.False(t, records[2].Priced)
+}
+
+func TestAccountant_Budgets(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var warnings []BudgetStatus
+	accountant, err := New(
+		WithClock(clock.Now),
+		WithBudgets(
+			Budget{Name: "search-hourly", Tags: map[string]string{"team": "search"}, Limit: 5, Window: time.Hour},
+			Budget{Name: "global-daily", Limit: 6, Window: 24 * time.Hour, Action: ActionWarn,
+				OnExceeded: func(ctx context.Context, status BudgetStatus) { warnings = append(warnings, status) }},
+		),
+	)
+	require.NoError(t, err)
+	modelContext := newModelContext(accountant)
+	search := WithTags(context.Background(), map[string]string{"team": "search"})
+
+	// Each call costs $3.50, so the second call exhausts the hourly budget of $5.
+	for i := 0; i < 2; i++ {
+		_, err := modelContext.Chat(search, hello, llmconnector.WithChatModel("gpt-4o"))
+		require.NoError(t, err)
+	}
+	_, err = modelContext.Chat(search, hello, llmconnector.WithChatModel("gpt-4o"))
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, "search-hourly", budgetErr.Budget)
+	assert.InDelta(t, 7, budgetErr.Spent, 1e-9)
+
+	// Other teams only hit the global budget, which warns.
+	_, err = modelContext.Chat(context.Background(), hello, llmconnector.WithChatModel("gpt-4o"))
+	require.NoError(t, err)
+	require.NotEmpty(t, warnings)
+	assert.Equal(t, "global-daily", warnings[0].Budget.Name)
+
+	// The hourly window rolls over.
+	clock.now = clock.now.Add(time.Hour + time.Second)
+	_, err = modelContext.Chat(search, hello, llmconnector.WithChatModel("gpt-4o"))
+	require.NoError(t, err)
+}
+
+// countingStore counts the reads of a MemoryStore.
+type countingStore struct {
+	*MemoryStore
+	reads int
+}
+
+func (s *countingStore) Records(ctx context.Context, since time.Time) ([]Record, error) {
+	s.reads++
+	return s.MemoryStore.Records(ctx, since)
+}
+
+func TestAccountant_BudgetsLoadStoreOnce(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := &countingStore{MemoryStore: NewMemoryStore(24 * time.Hour)}
+	ctx := context.Background()
+	require.NoError(t, store.Append(ctx, Record{Time: clock.now.Add(-2 * time.Hour), Cost: 100}))
+	require.NoError(t, store.Append(ctx, Record{Time: clock.now.Add(-time.Minute), Cost: 4}))
+
+	accountant, err := New(WithClock(clock.Now), WithStore(store), WithBudgets(Budget{Name: "hourly", Limit: 5, Window: time.Hour}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.reads)
+	modelContext := newModelContext(accountant)
+
+	// $4 of the previous process count within the window, so the $3.50 call exhausts the budget.
+	_, err = modelContext.Chat(ctx, hello, llmconnector.WithChatModel("gpt-4o"))
+	require.NoError(t, err)
+	_, err = modelContext.Chat(ctx, hello, llmconnector.WithChatModel("gpt-4o"))
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr))
+	assert.InDelta(t, 7.5, budgetErr.Spent, 1e-9)
+	assert.Equal(t, 1, store.reads, "budgets are checked without reading the store")
+
+	clock.now = clock.now.Add(time.Hour)
+	_, err = modelContext.Chat(ctx, hello, llmconnector.WithChatModel("gpt-4o"))
+	require.NoError(t, err, "the $4 left the window")
+}
+
+func TestNew_InvalidBudgets(t *testing.T) {
+	for name, budget := range map[string]Budget{
+		"zero limit":       {Name: "b", Window: time.Hour},
+		"zero window":      {Name: "b", Limit: 1},
+		"beyond retention": {Name: "b", Limit: 1, Window: 2 * time.Hour},
+		"negative window":  {Name: "b", Limit: 1, Window: -time.Hour},
+	} {
+		_, err := New(WithStore(NewMemoryStore(time.Hour)), WithBudgets(budget))
+		assert.Error(t, err, name)
+	}
+
+	_, err := New(WithStore(NewMemoryStore(0)), WithBudgets(Budget{Name: "b", Limit: 1, Window: 365 * 24 * time.Hour}))
+	assert.NoError(t, err, "a store keeping all records fits every window")
+}
+
+func TestMemoryStore_Retention(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	require.NoError(t, store.Append(ctx, Record{Time: start, Cost: 1}))
+	require.NoError(t, store.Append(ctx, Record{Time: start.Add(30 * time.Minute), Cost: 2}))
+	require.NoError(t, store.Append(ctx, Record{Time: start.Add(2 * time.Hour), Cost: 3}))
+
+	records, err := store.Records(ctx, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 3.0, records[0].Cost)
+}
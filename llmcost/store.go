@@ -0,0 +1,65 @@
+package llmcost
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store persists records. Implementations must be safe for concurrent use.
+type Store interface {
+	Append(ctx context.Context, record Record) error
+	// Records returns the records made at or after since.
+	Records(ctx context.Context, since time.Time) ([]Record, error)
+}
+
+// MemoryStore keeps records in memory for a retention period.
+type MemoryStore struct {
+	retention time.Duration
+
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewMemoryStore creates a MemoryStore dropping records older than retention. A retention of 0 keeps all records.
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{retention: retention}
+}
+
+// Retention returns how long records are kept, 0 for ever.
+func (s *MemoryStore) Retention() time.Duration {
+	return s.retention
+}
+
+func (s *MemoryStore) Append(ctx context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	if s.retention > 0 {
+		s.prune(record.Time.Add(-s.retention))
+	}
+	return nil
+}
+
+func (s *MemoryStore) Records(ctx context.Context, since time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []Record
+	for _, record := range s.records {
+		if !record.Time.Before(since) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// prune drops the leading records older than cutoff. Records are appended roughly in time order.
+func (s *MemoryStore) prune(cutoff time.Time) {
+	i := 0
+	for i < len(s.records) && s.records[i].Time.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		s.records = append(s.records[:0:0], s.records[i:]...)
+	}
+}
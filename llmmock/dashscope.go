@@ -0,0 +1,191 @@
+package llmmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// dashScopeChatRequest is the body of a DashScope generation request, with the messages, or a prompt,
+// under input and the sampling parameters under parameters.
+type dashScopeChatRequest struct {
+	Model string `json:"model"`
+	Input struct {
+		Messages []llmconnector.ChatMessage `json:"messages"`
+		Prompt   string                     `json:"prompt"`
+	} `json:"input"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+func (s *Server) handleDashScopeChat(w http.ResponseWriter, r *http.Request) {
+	var request dashScopeChatRequest
+	if err := decodeStrict(r, &request); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	messages := request.Input.Messages
+	if len(messages) == 0 && request.Input.Prompt != "" {
+		messages = []llmconnector.ChatMessage{{Role: "user", Content: request.Input.Prompt}}
+	}
+	if request.Model == "" || len(messages) == 0 {
+		writeError(w, r, http.StatusBadRequest, "model and input.messages are required")
+		return
+	}
+
+	reply := s.chatReply(messages)
+	if !wait(r, time.Duration(reply.Latency)) {
+		return
+	}
+	if isFailure(reply.Status) {
+		writeError(w, r, reply.Status, reply.Message)
+		return
+	}
+
+	requestID := s.requestID()
+	inputTokens := messageTokens(messages)
+	stream := r.Header.Get("X-DashScope-SSE") == "enable" || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if !stream {
+		outputTokens := countTokens(reply.Content)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"output": map[string]string{"text": reply.Content, "finish_reason": reply.FinishReason},
+			"usage": map[string]int{
+				"input_tokens":  inputTokens,
+				"output_tokens": outputTokens,
+				"total_tokens":  inputTokens + outputTokens,
+			},
+			"request_id": requestID,
+		})
+		return
+	}
+
+	incremental, _ := request.Parameters["incremental_output"].(bool)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	var sent strings.Builder
+	for i, delta := range reply.Chunks {
+		if r.Context().Err() != nil {
+			return
+		}
+		sent.WriteString(delta)
+		text := sent.String()
+		if incremental {
+			text = delta
+		}
+		finishReason := "null"
+		if i == len(reply.Chunks)-1 {
+			finishReason = reply.FinishReason
+		}
+		outputTokens := countTokens(sent.String())
+		data, _ := json.Marshal(map[string]interface{}{
+			"output": map[string]string{"text": text, "finish_reason": finishReason},
+			"usage": map[string]int{
+				"input_tokens":  inputTokens,
+				"output_tokens": outputTokens,
+				"total_tokens":  inputTokens + outputTokens,
+			},
+			"request_id": requestID,
+		})
+		writeEvent(w, flusher, fmt.Sprintf("id:%d", i+1), "event:result", ":HTTP_STATUS/200", "data:"+string(data))
+	}
+}
+
+// dashScopeEmbedRequest is the body of a DashScope text embedding request.
+type dashScopeEmbedRequest struct {
+	Model string `json:"model"`
+	Input struct {
+		Texts []string `json:"texts"`
+	} `json:"input"`
+	Parameters struct {
+		TextType   string `json:"text_type"`
+		Dimension  int    `json:"dimension"`
+		OutputType string `json:"output_type"`
+	} `json:"parameters"`
+}
+
+func (s *Server) handleDashScopeEmbed(w http.ResponseWriter, r *http.Request) {
+	var request dashScopeEmbedRequest
+	if err := decodeStrict(r, &request); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if request.Model == "" || len(request.Input.Texts) == 0 {
+		writeError(w, r, http.StatusBadRequest, "model and input.texts are required")
+		return
+	}
+	dense, sparse := true, false
+	switch request.Parameters.OutputType {
+	case "", llmconnector.OutputTypeDense:
+	case llmconnector.OutputTypeSparse:
+		dense, sparse = false, true
+	case llmconnector.OutputTypeDenseAndSparse:
+		sparse = true
+	default:
+		writeError(w, r, http.StatusBadRequest, "parameters.output_type must be dense, sparse or dense&sparse")
+		return
+	}
+
+	reply := s.embedReply(request.Input.Texts, request.Parameters.Dimension)
+	if !wait(r, time.Duration(reply.Latency)) {
+		return
+	}
+	if isFailure(reply.Status) {
+		writeError(w, r, reply.Status, reply.Message)
+		return
+	}
+
+	embeddings := make([]map[string]interface{}, len(reply.Embeddings))
+	for i, embedding := range reply.Embeddings {
+		embeddings[i] = map[string]interface{}{"text_index": i}
+		if dense {
+			embeddings[i]["embedding"] = embedding
+		}
+		if sparse && i < len(request.Input.Texts) {
+			embeddings[i]["sparse_embedding"] = sparseEmbedding(request.Input.Texts[i])
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"output":     map[string]interface{}{"embeddings": s.arrange(embeddings)},
+		"usage":      map[string]int{"total_tokens": countTokens(request.Input.Texts...)},
+		"request_id": s.requestID(),
+	})
+}
+
+// sparseEmbedding returns a deterministic sparse vector of text: every distinct word, indexed by its hash,
+// weighted by its frequency.
+func sparseEmbedding(text string) llmconnector.SparseVector {
+	words := strings.Fields(text)
+	weights := make(map[string]int)
+	for _, word := range words {
+		weights[word]++
+	}
+	vector := make(llmconnector.SparseVector, 0, len(weights))
+	for _, word := range words {
+		count, ok := weights[word]
+		if !ok {
+			continue
+		}
+		delete(weights, word)
+		hash := fnv.New32a()
+		hash.Write([]byte(word))
+		vector = append(vector, llmconnector.SparseEntry{
+			Index: int(hash.Sum32() % 250000),
+			Value: float32(count) / float32(len(words)),
+			Token: word,
+		})
+	}
+	return vector
+}
+
+// decodeStrict decodes the body of r into v, rejecting fields v does not declare.
+func decodeStrict(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
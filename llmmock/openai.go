@@ -0,0 +1,180 @@
+package llmmock
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/simp-lee/llmconnector"
+	"math"
+	"net/http"
+	"time"
+)
+
+type openAIChatRequest struct {
+	Model         string                     `json:"model"`
+	Messages      []llmconnector.ChatMessage `json:"messages"`
+	Stream        bool                       `json:"stream"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+func (s *Server) handleOpenAIChat(w http.ResponseWriter, r *http.Request) {
+	var request openAIChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if request.Model == "" || len(request.Messages) == 0 {
+		writeError(w, r, http.StatusBadRequest, "model and messages are required")
+		return
+	}
+
+	reply := s.chatReply(request.Messages)
+	if !wait(r, time.Duration(reply.Latency)) {
+		return
+	}
+	if isFailure(reply.Status) {
+		writeError(w, r, reply.Status, reply.Message)
+		return
+	}
+
+	id := "chatcmpl-" + s.requestID()
+	created := time.Now().Unix()
+	usage := map[string]int{
+		"prompt_tokens":     messageTokens(request.Messages),
+		"completion_tokens": countTokens(reply.Content),
+	}
+	usage["total_tokens"] = usage["prompt_tokens"] + usage["completion_tokens"]
+
+	if !request.Stream {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   request.Model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": reply.Content},
+				"finish_reason": reply.FinishReason,
+			}},
+			"usage": usage,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	chunk := func(delta map[string]string, finishReason interface{}) string {
+		data, _ := json.Marshal(map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   request.Model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finishReason,
+			}},
+		})
+		return "data: " + string(data)
+	}
+
+	writeEvent(w, flusher, chunk(map[string]string{"role": "assistant"}, nil))
+	for _, delta := range reply.Chunks {
+		if r.Context().Err() != nil {
+			return
+		}
+		writeEvent(w, flusher, chunk(map[string]string{"content": delta}, nil))
+	}
+	writeEvent(w, flusher, chunk(map[string]string{}, reply.FinishReason))
+	if request.StreamOptions != nil && request.StreamOptions.IncludeUsage {
+		data, _ := json.Marshal(map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   request.Model,
+			"choices": []interface{}{},
+			"usage":   usage,
+		})
+		writeEvent(w, flusher, "data: "+string(data))
+	}
+	writeEvent(w, flusher, "data: [DONE]")
+}
+
+// openAIEmbedRequest is the body of /v1/embeddings, whose input is a string or a list of strings.
+type openAIEmbedRequest struct {
+	Model          string          `json:"model"`
+	Input          json.RawMessage `json:"input"`
+	Dimensions     int             `json:"dimensions"`
+	EncodingFormat string          `json:"encoding_format"`
+}
+
+func (s *Server) handleOpenAIEmbed(w http.ResponseWriter, r *http.Request) {
+	var request openAIEmbedRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	texts, ok := decodeTexts(request.Input)
+	if !ok || request.Model == "" {
+		writeError(w, r, http.StatusBadRequest, "model and input, a string or a list of strings, are required")
+		return
+	}
+	if request.EncodingFormat != "" && request.EncodingFormat != "float" && request.EncodingFormat != "base64" {
+		writeError(w, r, http.StatusBadRequest, "encoding_format must be float or base64")
+		return
+	}
+
+	reply := s.embedReply(texts, request.Dimensions)
+	if !wait(r, time.Duration(reply.Latency)) {
+		return
+	}
+	if isFailure(reply.Status) {
+		writeError(w, r, reply.Status, reply.Message)
+		return
+	}
+
+	data := make([]map[string]interface{}, len(reply.Embeddings))
+	for i, embedding := range reply.Embeddings {
+		data[i] = map[string]interface{}{
+			"object":    "embedding",
+			"index":     i,
+			"embedding": encodeEmbedding(embedding, request.EncodingFormat),
+		}
+	}
+	tokens := countTokens(texts...)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"model":  request.Model,
+		"data":   s.arrange(data),
+		"usage":  map[string]int{"prompt_tokens": tokens, "total_tokens": tokens},
+	})
+}
+
+// encodeEmbedding returns embedding as a JSON array, or as base64 little-endian float32 values for the base64 format.
+func encodeEmbedding(embedding []float32, format string) interface{} {
+	if format != "base64" {
+		return embedding
+	}
+	raw := make([]byte, len(embedding)*4)
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(value))
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func decodeTexts(input json.RawMessage) ([]string, bool) {
+	var single string
+	if err := json.Unmarshal(input, &single); err == nil {
+		return []string{single}, true
+	}
+	var list []string
+	if err := json.Unmarshal(input, &list); err == nil && len(list) > 0 {
+		return list, true
+	}
+	return nil, false
+}
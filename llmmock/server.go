@@ -0,0 +1,373 @@
+// Package llmmock implements a local mock of the OpenAI and DashScope HTTP APIs for integration tests.
+// It serves chat completions (plain and SSE streamed) and embeddings with scripted responses,
+// injected latency and error rates, so OpenAIStrategy and AlibabaStrategy can be exercised end to end.
+package llmmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"github.com/simp-lee/llmconnector/llmtest"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths served by the mock server.
+const (
+	OpenAIChatPath          = "/v1/chat/completions"
+	OpenAIEmbedPath         = "/v1/embeddings"
+	DashScopeGenerationPath = "/api/v1/services/aigc/text-generation/generation"
+	DashScopeChatPath       = "/api/v1/services/chat/completions"
+	DashScopeEmbedPath      = "/api/v1/services/embeddings/text-embedding/text-embedding"
+	DashScopeLegacyEmbed    = "/api/v1/services/embeddings/text-embedding"
+)
+
+// Duration is a time.Duration that is encoded in JSON as a string such as "150ms".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string like \"150ms\": %w", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ChatReply scripts the response to one chat request.
+type ChatReply struct {
+	Content string `json:"content,omitempty"`
+	// Chunks are the SSE deltas of a streamed reply. When empty, Content is split into words.
+	Chunks       []string `json:"chunks,omitempty"`
+	FinishReason string   `json:"finish_reason,omitempty"`
+	// Status, when not 2xx, makes the server answer with an error of that status.
+	Status  int      `json:"status,omitempty"`
+	Message string   `json:"message,omitempty"`
+	Latency Duration `json:"latency,omitempty"`
+}
+
+// EmbedReply scripts the response to one embedding request.
+type EmbedReply struct {
+	Embeddings [][]float32 `json:"embeddings,omitempty"`
+	Status     int         `json:"status,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	Latency    Duration    `json:"latency,omitempty"`
+}
+
+// Script is a set of queued replies, e.g. loaded from a JSON file.
+type Script struct {
+	Chat  []ChatReply  `json:"chat"`
+	Embed []EmbedReply `json:"embed"`
+}
+
+// Options configures a Server.
+type Options struct {
+	// APIKey, when set, is required as a bearer token on every request.
+	APIKey string
+	// Latency delays every response.
+	Latency time.Duration
+	// ErrorRate is the probability in [0, 1] of answering a request with ErrorStatus.
+	ErrorRate float64
+	// ErrorStatus is the status of injected errors. Defaults to 500.
+	ErrorStatus int
+	// Dimensions is the size of generated embeddings. Defaults to llmtest.DefaultDimensions.
+	Dimensions int
+	// Seed makes injected errors reproducible.
+	Seed int64
+	// ShuffleEmbeddings returns embedding entries in reverse order, relying on their index fields,
+	// to check that clients restore the input order.
+	ShuffleEmbeddings bool
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+// Server is an http.Handler mocking the OpenAI and DashScope APIs.
+// Without scripted replies it echoes the last user message and returns deterministic hash embeddings.
+type Server struct {
+	options  Options
+	embedder llmtest.HashEmbedder
+	mux      *http.ServeMux
+
+	mu         sync.Mutex
+	rand       *rand.Rand
+	chatQueue  []ChatReply
+	embedQueue []EmbedReply
+	requests   []Request
+	sequence   int
+}
+
+// New creates a Server.
+func New(options Options) *Server {
+	if options.ErrorStatus == 0 {
+		options.ErrorStatus = http.StatusInternalServerError
+	}
+	s := &Server{
+		options:  options,
+		embedder: llmtest.HashEmbedder{Dimensions: options.Dimensions},
+		mux:      http.NewServeMux(),
+		rand:     rand.New(rand.NewSource(options.Seed)),
+	}
+	s.mux.HandleFunc(OpenAIChatPath, s.handleOpenAIChat)
+	s.mux.HandleFunc(OpenAIEmbedPath, s.handleOpenAIEmbed)
+	s.mux.HandleFunc(DashScopeGenerationPath, s.handleDashScopeChat)
+	s.mux.HandleFunc(DashScopeChatPath, s.handleDashScopeChat)
+	s.mux.HandleFunc(DashScopeEmbedPath, s.handleDashScopeEmbed)
+	s.mux.HandleFunc(DashScopeLegacyEmbed, s.handleDashScopeEmbed)
+	return s
+}
+
+// EnqueueChat appends scripted chat replies.
+func (s *Server) EnqueueChat(replies ...ChatReply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chatQueue = append(s.chatQueue, replies...)
+}
+
+// EnqueueEmbed appends scripted embedding replies.
+func (s *Server) EnqueueEmbed(replies ...EmbedReply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embedQueue = append(s.embedQueue, replies...)
+}
+
+// LoadScript queues the replies of a JSON encoded Script.
+func (s *Server) LoadScript(r io.Reader) error {
+	var script Script
+	if err := json.NewDecoder(r).Decode(&script); err != nil {
+		return fmt.Errorf("llmmock: failed to decode script: %w", err)
+	}
+	s.EnqueueChat(script.Chat...)
+	s.EnqueueEmbed(script.Embed...)
+	return nil
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// OpenAIConfig returns a Config pointing an OpenAIStrategy at the server listening on baseURL.
+func (s *Server) OpenAIConfig(baseURL string) llmconnector.Config {
+	return llmconnector.Config{
+		APIKey:   s.apiKey(),
+		ChatURL:  baseURL + OpenAIChatPath,
+		EmbedURL: baseURL + OpenAIEmbedPath,
+	}
+}
+
+// AlibabaConfig returns a Config pointing an AlibabaStrategy at the server listening on baseURL.
+func (s *Server) AlibabaConfig(baseURL string) llmconnector.Config {
+	return llmconnector.Config{
+		APIKey:   s.apiKey(),
+		ChatURL:  baseURL + DashScopeGenerationPath,
+		EmbedURL: baseURL + DashScopeEmbedPath,
+	}
+}
+
+func (s *Server) apiKey() string {
+	if s.options.APIKey == "" {
+		return "mock-api-key"
+	}
+	return s.options.APIKey
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: string(body)})
+	injectError := s.options.ErrorRate > 0 && s.rand.Float64() < s.options.ErrorRate
+	s.mu.Unlock()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.options.APIKey != "" && r.Header.Get("Authorization") != "Bearer "+s.options.APIKey {
+		writeError(w, r, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+	if !wait(r, time.Duration(s.options.Latency)) {
+		return
+	}
+	if injectError {
+		writeError(w, r, s.options.ErrorStatus, "injected error")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) nextChat() (ChatReply, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sequence++
+	if len(s.chatQueue) == 0 {
+		return ChatReply{}, false
+	}
+	reply := s.chatQueue[0]
+	s.chatQueue = s.chatQueue[1:]
+	return reply, true
+}
+
+func (s *Server) nextEmbed() (EmbedReply, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sequence++
+	if len(s.embedQueue) == 0 {
+		return EmbedReply{}, false
+	}
+	reply := s.embedQueue[0]
+	s.embedQueue = s.embedQueue[1:]
+	return reply, true
+}
+
+func (s *Server) requestID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("mock-%d", s.sequence)
+}
+
+// chatReply resolves the reply to a chat request, echoing the last user message when nothing is scripted.
+func (s *Server) chatReply(messages []llmconnector.ChatMessage) ChatReply {
+	reply, ok := s.nextChat()
+	if !ok {
+		reply.Content = "mock response"
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].Role == "user" {
+				reply.Content = "mock response to: " + messages[i].Content
+				break
+			}
+		}
+	}
+	if reply.Content == "" {
+		reply.Content = strings.Join(reply.Chunks, "")
+	}
+	if len(reply.Chunks) == 0 {
+		reply.Chunks = strings.SplitAfter(reply.Content, " ")
+	}
+	if reply.FinishReason == "" {
+		reply.FinishReason = "stop"
+	}
+	return reply
+}
+
+// embedReply resolves the reply to an embedding request, hashing the texts when nothing is scripted.
+// Requested dimensions, when positive, override Options.Dimensions.
+func (s *Server) embedReply(texts []string, dimensions int) EmbedReply {
+	reply, ok := s.nextEmbed()
+	if !ok || (reply.Embeddings == nil && reply.Status == 0) {
+		embedder := s.embedder
+		if dimensions > 0 {
+			embedder.Dimensions = dimensions
+		}
+		reply.Embeddings = make([][]float32, len(texts))
+		for i, text := range texts {
+			reply.Embeddings[i] = embedder.Vector(text)
+		}
+	}
+	return reply
+}
+
+// wait sleeps for d and reports whether the client is still waiting.
+func wait(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-r.Context().Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// arrange applies Options.ShuffleEmbeddings to the entries of an embedding response.
+func (s *Server) arrange(entries []map[string]interface{}) []map[string]interface{} {
+	if !s.options.ShuffleEmbeddings {
+		return entries
+	}
+	reversed := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		reversed[len(entries)-1-i] = entry
+	}
+	return reversed
+}
+
+func isFailure(status int) bool {
+	return status != 0 && (status < 200 || status >= 300)
+}
+
+func countTokens(texts ...string) int {
+	count := 0
+	for _, text := range texts {
+		count += len(strings.Fields(text))
+	}
+	return count
+}
+
+func messageTokens(messages []llmconnector.ChatMessage) int {
+	count := 0
+	for _, message := range messages {
+		count += countTokens(message.Content)
+	}
+	return count
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeError answers with an error body in the dialect of the requested API.
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if strings.HasPrefix(r.URL.Path, "/api/v1/") {
+		writeJSON(w, status, map[string]interface{}{
+			"code":       http.StatusText(status),
+			"message":    message,
+			"request_id": "mock-error",
+		})
+		return
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    http.StatusText(status),
+			"code":    status,
+		},
+	})
+}
+
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, lines ...string) {
+	for _, line := range lines {
+		io.WriteString(w, line+"\n")
+	}
+	io.WriteString(w, "\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
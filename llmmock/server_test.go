@@ -0,0 +1,226 @@
+package llmmock
+
+import (
+	"context"
+	"github.com/simp-lee/llmconnector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, options Options) (*Server, *httptest.Server) {
+	mock := New(options)
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	return mock, server
+}
+
+func withRetries(config llmconnector.Config, retries int) llmconnector.Config {
+	config.CommonConfig = llmconnector.DefaultCommonConfig()
+	config.Retries = retries
+	return config
+}
+
+func TestServer_OpenAI(t *testing.T) {
+	mock, server := startServer(t, Options{APIKey: "test-key", Dimensions: 4})
+
+	strategy, err := llmconnector.NewOpenAIStrategy(mock.OpenAIConfig(server.URL))
+	require.NoError(t, err)
+
+	messages := []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}}
+	resp, err := strategy.Chat(context.Background(), messages, &llmconnector.ChatOptions{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, "mock response to: Hello", resp.GetContent())
+	assert.Equal(t, []string{"stop"}, llmconnector.FinishReasonsOf(resp))
+
+	embedResp, err := strategy.Embed(context.Background(), []string{"text1", "text2"}, &llmconnector.EmbedOptions{Model: "text-embedding-3-small"})
+	require.NoError(t, err)
+	require.Len(t, embedResp.GetEmbeddings(), 2)
+	assert.Len(t, embedResp.GetEmbeddings()[0], 4)
+
+	requests := mock.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, OpenAIChatPath, requests[0].Path)
+	assert.Equal(t, "Bearer test-key", requests[0].Header.Get("Authorization"))
+}
+
+func TestServer_OpenAIBase64Embeddings(t *testing.T) {
+	mock, server := startServer(t, Options{APIKey: "test-key", Dimensions: 4})
+
+	strategy, err := llmconnector.NewOpenAIStrategy(mock.OpenAIConfig(server.URL))
+	require.NoError(t, err)
+
+	options := &llmconnector.EmbedOptions{Model: "text-embedding-3-small", EncodingFormat: llmconnector.EncodingFormatBase64}
+	resp, err := strategy.Embed(context.Background(), []string{"text1"}, options)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{mock.embedder.Vector("text1")}, resp.GetEmbeddings())
+	assert.Contains(t, mock.Requests()[0].Body, `"encoding_format":"base64"`)
+}
+
+func TestServer_OpenAIStream(t *testing.T) {
+	mock, server := startServer(t, Options{})
+	mock.EnqueueChat(ChatReply{Chunks: []string{"Hi", " there"}, FinishReason: "length"})
+
+	strategy, err := llmconnector.NewOpenAIStrategy(mock.OpenAIConfig(server.URL))
+	require.NoError(t, err)
+
+	var deltas []string
+	resp, err := strategy.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}},
+		&llmconnector.ChatOptions{Model: "gpt-4o", StreamHandler: func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hi", " there"}, deltas)
+	assert.Equal(t, "Hi there", resp.GetContent())
+	assert.Equal(t, []string{"length"}, llmconnector.FinishReasonsOf(resp))
+	usage, ok := llmconnector.UsageOf(resp)
+	require.True(t, ok)
+	assert.Equal(t, 1, usage.PromptTokens)
+}
+
+func TestServer_Alibaba(t *testing.T) {
+	mock, server := startServer(t, Options{})
+	mock.EnqueueChat(ChatReply{Content: "scripted"})
+	mock.EnqueueEmbed(EmbedReply{Embeddings: [][]float32{{0.1, 0.2}}})
+
+	strategy, err := llmconnector.NewAlibabaStrategy(mock.AlibabaConfig(server.URL))
+	require.NoError(t, err)
+
+	resp, err := strategy.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}}, &llmconnector.ChatOptions{Model: "qwen-max"})
+	require.NoError(t, err)
+	assert.Equal(t, "scripted", resp.GetContent())
+
+	embedResp, err := strategy.Embed(context.Background(), []string{"text1"}, &llmconnector.EmbedOptions{Model: "text-embedding-v3"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1, 0.2}}, embedResp.GetEmbeddings())
+}
+
+func TestServer_AlibabaEmbedParameters(t *testing.T) {
+	mock, server := startServer(t, Options{Dimensions: 4})
+
+	strategy, err := llmconnector.NewAlibabaStrategy(mock.AlibabaConfig(server.URL))
+	require.NoError(t, err)
+
+	options := &llmconnector.EmbedOptions{Model: "text-embedding-v3", Dimensions: 64, OutputType: llmconnector.OutputTypeDenseAndSparse}
+	resp, err := strategy.Embed(context.Background(), []string{"to be or not to be"}, options)
+	require.NoError(t, err)
+	require.Len(t, resp.GetEmbeddings(), 1)
+	assert.Len(t, resp.GetEmbeddings()[0], 64)
+
+	sparse, ok := llmconnector.SparseEmbeddingsOf(resp)
+	require.True(t, ok)
+	require.Len(t, sparse, 1)
+	require.Len(t, sparse[0], 4)
+	assert.Equal(t, "to", sparse[0][0].Token)
+	assert.Equal(t, float32(2)/6, sparse[0][0].Value)
+}
+
+func TestServer_RejectsNonStandardBodies(t *testing.T) {
+	mock, server := startServer(t, Options{})
+
+	for _, tc := range []struct {
+		path string
+		body string
+	}{
+		{OpenAIChatPath, `{"model":"gpt-4o"}`},
+		{OpenAIEmbedPath, `{"model":"text-embedding-3-small","input":{"texts":["text1"]}}`},
+		{OpenAIEmbedPath, `{"input":["text1"]}`},
+		{DashScopeGenerationPath, `{"model":"qwen-max","messages":[{"role":"user","content":"Hello"}]}`},
+		{DashScopeGenerationPath, `{"model":"qwen-max","input":{"messages":[]}}`},
+		{DashScopeEmbedPath, `{"model":"text-embedding-v3","input":{"texts":["text1"]},"params":{"text_type":"query"}}`},
+		{DashScopeEmbedPath, `{"model":"text-embedding-v3","input":{"texts":["text1"]},"parameters":{"output_type":"dense+sparse"}}`},
+	} {
+		resp, err := http.Post(server.URL+tc.path, "application/json", strings.NewReader(tc.body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "%s %s", tc.path, tc.body)
+	}
+	assert.Len(t, mock.Requests(), 7)
+}
+
+func TestServer_AlibabaStream(t *testing.T) {
+	mock, server := startServer(t, Options{})
+
+	strategy, err := llmconnector.NewAlibabaStrategy(mock.AlibabaConfig(server.URL))
+	require.NoError(t, err)
+
+	var deltas []string
+	resp, err := strategy.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello world"}},
+		&llmconnector.ChatOptions{Model: "qwen-max", StreamHandler: func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		}})
+	require.NoError(t, err)
+	assert.Equal(t, "mock response to: Hello world", strings.Join(deltas, ""))
+	assert.Equal(t, "mock response to: Hello world", resp.GetContent())
+	assert.Equal(t, []string{"stop"}, llmconnector.FinishReasonsOf(resp))
+}
+
+func TestServer_ScriptedError(t *testing.T) {
+	mock, server := startServer(t, Options{})
+	mock.EnqueueChat(ChatReply{Status: http.StatusTooManyRequests, Message: "slow down"})
+
+	strategy, err := llmconnector.NewOpenAIStrategy(mock.OpenAIConfig(server.URL))
+	require.NoError(t, err)
+
+	// Streamed requests are not retried, so the scripted error reaches the caller.
+	_, err = strategy.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}},
+		&llmconnector.ChatOptions{Model: "gpt-4o", StreamHandler: func(string) error { return nil }})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 429")
+	assert.Contains(t, err.Error(), "slow down")
+	assert.Len(t, mock.Requests(), 1)
+}
+
+func TestServer_Unauthorized(t *testing.T) {
+	mock, server := startServer(t, Options{APIKey: "test-key"})
+
+	config := withRetries(mock.OpenAIConfig(server.URL), 1)
+	config.APIKey = "wrong-key"
+	strategy, err := llmconnector.NewOpenAIStrategy(config)
+	require.NoError(t, err)
+
+	_, err = strategy.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}}, &llmconnector.ChatOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 401")
+}
+
+func TestServer_ErrorRateAndLatency(t *testing.T) {
+	mock, server := startServer(t, Options{ErrorRate: 1, ErrorStatus: http.StatusBadGateway, Latency: 20 * time.Millisecond})
+
+	strategy, err := llmconnector.NewAlibabaStrategy(withRetries(mock.AlibabaConfig(server.URL), 1))
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = strategy.Embed(context.Background(), []string{"text1"}, &llmconnector.EmbedOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 502")
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestServer_LoadScript(t *testing.T) {
+	mock, server := startServer(t, Options{})
+	script := `{"chat":[{"content":"from script","latency":"1ms"}],"embed":[{"embeddings":[[1,2,3]]}]}`
+	require.NoError(t, mock.LoadScript(strings.NewReader(script)))
+
+	strategy, err := llmconnector.NewOpenAIStrategy(mock.OpenAIConfig(server.URL))
+	require.NoError(t, err)
+
+	resp, err := strategy.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}}, &llmconnector.ChatOptions{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, "from script", resp.GetContent())
+
+	embedResp, err := strategy.Embed(context.Background(), []string{"text1"}, &llmconnector.EmbedOptions{Model: "text-embedding-3-small"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 2, 3}}, embedResp.GetEmbeddings())
+}
+
+func TestServer_LoadScript_InvalidLatency(t *testing.T) {
+	mock := New(Options{})
+	assert.Error(t, mock.LoadScript(strings.NewReader(`{"chat":[{"latency":5}]}`)))
+}
@@ -0,0 +1,214 @@
+// Package llmotel instruments llmconnector.ModelContext with OpenTelemetry spans
+// following the GenAI semantic conventions.
+package llmotel
+
+import (
+	"context"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"strconv"
+)
+
+const instrumentationName = "github.com/simp-lee/llmconnector/llmotel"
+
+// Attribute keys from the OpenTelemetry GenAI semantic conventions.
+const (
+	AttrOperationName        = attribute.Key("gen_ai.operation.name")
+	AttrSystem               = attribute.Key("gen_ai.system")
+	AttrRequestModel         = attribute.Key("gen_ai.request.model")
+	AttrRequestTemperature   = attribute.Key("gen_ai.request.temperature")
+	AttrRequestMaxTokens     = attribute.Key("gen_ai.request.max_tokens")
+	AttrRequestTopP          = attribute.Key("gen_ai.request.top_p")
+	AttrRequestStopSequences = attribute.Key("gen_ai.request.stop_sequences")
+	AttrUsageInputTokens     = attribute.Key("gen_ai.usage.input_tokens")
+	AttrUsageOutputTokens    = attribute.Key("gen_ai.usage.output_tokens")
+	AttrResponseFinishReason = attribute.Key("gen_ai.response.finish_reasons")
+	AttrErrorType            = attribute.Key("error.type")
+)
+
+const (
+	operationChat       = "chat"
+	operationEmbeddings = "embeddings"
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	system         string
+	captureContent bool
+}
+
+// Option configures the tracing middleware.
+type Option func(c *config)
+
+// WithTracerProvider sets the tracer provider. The global provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithSystem sets gen_ai.system for strategies that do not report a provider name.
+func WithSystem(system string) Option {
+	return func(c *config) {
+		c.system = system
+	}
+}
+
+// WithCaptureContent records prompts and completions as span events.
+// Content may contain sensitive data, so this is off by default.
+func WithCaptureContent(capture bool) Option {
+	return func(c *config) {
+		c.captureContent = capture
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.tracerProvider == nil {
+		c.tracerProvider = otel.GetTracerProvider()
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return c.tracerProvider.Tracer(instrumentationName)
+}
+
+func (c *config) systemFor(ctx context.Context) string {
+	if provider := llmconnector.ProviderFromContext(ctx); provider != "" {
+		return provider
+	}
+	return c.system
+}
+
+// ChatMiddleware returns a middleware that traces every ModelContext.Chat call.
+func ChatMiddleware(opts ...Option) llmconnector.ChatMiddleware {
+	c := newConfig(opts)
+	tracer := c.tracer()
+	return func(next llmconnector.ChatHandler) llmconnector.ChatHandler {
+		return func(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (llmconnector.ChatResponse, error) {
+			attrs := []attribute.KeyValue{
+				AttrOperationName.String(operationChat),
+				AttrRequestModel.String(options.Model),
+			}
+			if system := c.systemFor(ctx); system != "" {
+				attrs = append(attrs, AttrSystem.String(system))
+			}
+			if options.Temperature != nil {
+				attrs = append(attrs, AttrRequestTemperature.Float64(*options.Temperature))
+			}
+			if options.MaxTokens != nil {
+				attrs = append(attrs, AttrRequestMaxTokens.Int(*options.MaxTokens))
+			}
+			if options.TopP != nil {
+				attrs = append(attrs, AttrRequestTopP.Float64(*options.TopP))
+			}
+			if len(options.Stop) > 0 {
+				attrs = append(attrs, AttrRequestStopSequences.StringSlice(options.Stop))
+			}
+
+			ctx, span := tracer.Start(ctx, spanName(operationChat, options.Model),
+				trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			if c.captureContent {
+				for _, message := range chatMessages {
+					span.AddEvent(fmt.Sprintf("gen_ai.%s.message", message.Role),
+						trace.WithAttributes(attribute.String("content", message.Content)))
+				}
+			}
+
+			resp, err := next(ctx, chatMessages, options)
+			if err != nil {
+				recordError(span, err)
+				return resp, err
+			}
+
+			recordUsage(span, resp)
+			if reasons := llmconnector.FinishReasonsOf(resp); len(reasons) > 0 {
+				span.SetAttributes(AttrResponseFinishReason.StringSlice(reasons))
+			}
+			if c.captureContent {
+				span.AddEvent("gen_ai.choice", trace.WithAttributes(attribute.String("content", resp.GetContent())))
+			}
+			return resp, nil
+		}
+	}
+}
+
+// EmbedMiddleware returns a middleware that traces every ModelContext.Embed call.
+func EmbedMiddleware(opts ...Option) llmconnector.EmbedMiddleware {
+	c := newConfig(opts)
+	tracer := c.tracer()
+	return func(next llmconnector.EmbedHandler) llmconnector.EmbedHandler {
+		return func(ctx context.Context, texts []string, options *llmconnector.EmbedOptions) (llmconnector.EmbedResponse, error) {
+			attrs := []attribute.KeyValue{
+				AttrOperationName.String(operationEmbeddings),
+				AttrRequestModel.String(options.Model),
+			}
+			if system := c.systemFor(ctx); system != "" {
+				attrs = append(attrs, AttrSystem.String(system))
+			}
+
+			ctx, span := tracer.Start(ctx, spanName(operationEmbeddings, options.Model),
+				trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			if c.captureContent {
+				span.AddEvent("gen_ai.embeddings.input", trace.WithAttributes(attribute.StringSlice("content", texts)))
+			}
+
+			resp, err := next(ctx, texts, options)
+			if err != nil {
+				recordError(span, err)
+				return resp, err
+			}
+
+			recordUsage(span, resp)
+			return resp, nil
+		}
+	}
+}
+
+func spanName(operation, model string) string {
+	if model == "" {
+		return operation
+	}
+	return operation + " " + model
+}
+
+func recordUsage(span trace.Span, resp interface{}) {
+	usage, ok := llmconnector.UsageOf(resp)
+	if !ok {
+		return
+	}
+	span.SetAttributes(AttrUsageInputTokens.Int(usage.PromptTokens))
+	if usage.CompletionTokens > 0 {
+		span.SetAttributes(AttrUsageOutputTokens.Int(usage.CompletionTokens))
+	}
+}
+
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.SetAttributes(AttrErrorType.String(errorType(err)))
+}
+
+// errorType maps err to a low-cardinality error.type value: the HTTP status code when known.
+func errorType(err error) string {
+	if code := llmconnector.StatusCode(err); code != 0 {
+		return strconv.Itoa(code)
+	}
+	switch class := llmconnector.ClassifyError(err); class {
+	case llmconnector.ErrorClassTimeout, llmconnector.ErrorClassCanceled, llmconnector.ErrorClassNetwork:
+		return string(class)
+	}
+	return "_OTHER"
+}
@@ -0,0 +1,151 @@
+package llmotel
+
+import (
+	"context"
+	"github.com/simp-lee/gohttpclient"
+	"github.com/simp-lee/llmconnector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+type stubChatStrategy struct {
+	err error
+}
+
+func (s *stubChatStrategy) ProviderName() string {
+	return "openai"
+}
+
+func (s *stubChatStrategy) Chat(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (llmconnector.ChatResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	resp := &llmconnector.OpenAIChatResponse{}
+	resp.Choices = make([]struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	}, 1)
+	resp.Choices[0].Message.Content = "Hi there"
+	resp.Choices[0].FinishReason = "stop"
+	resp.Usage.PromptTokens = 12
+	resp.Usage.CompletionTokens = 3
+	resp.Usage.TotalTokens = 15
+	return resp, nil
+}
+
+type stubEmbedStrategy struct{}
+
+func (s *stubEmbedStrategy) Embed(ctx context.Context, texts []string, options *llmconnector.EmbedOptions) (llmconnector.EmbedResponse, error) {
+	resp := &llmconnector.OpenAIEmbedResponse{}
+	resp.Usage.PromptTokens = 4
+	resp.Usage.TotalTokens = 4
+	return resp, nil
+}
+
+func newTestProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return provider, exporter
+}
+
+func attributeMap(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(attrs))
+	for _, attr := range attrs {
+		m[attr.Key] = attr.Value
+	}
+	return m
+}
+
+func TestChatMiddleware(t *testing.T) {
+	provider, exporter := newTestProvider()
+
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(&stubChatStrategy{})
+	modelContext.UseChat(ChatMiddleware(WithTracerProvider(provider)))
+
+	_, err := modelContext.Chat(context.Background(),
+		[]llmconnector.ChatMessage{{Role: "user", Content: "Hello"}},
+		llmconnector.WithChatModel("gpt-4o"),
+		llmconnector.WithTemperature(0.5),
+		llmconnector.WithMaxTokens(64),
+	)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "chat gpt-4o", span.Name)
+
+	attrs := attributeMap(span.Attributes)
+	assert.Equal(t, "chat", attrs[AttrOperationName].AsString())
+	assert.Equal(t, "openai", attrs[AttrSystem].AsString())
+	assert.Equal(t, "gpt-4o", attrs[AttrRequestModel].AsString())
+	assert.Equal(t, 0.5, attrs[AttrRequestTemperature].AsFloat64())
+	assert.Equal(t, int64(64), attrs[AttrRequestMaxTokens].AsInt64())
+	assert.Equal(t, int64(12), attrs[AttrUsageInputTokens].AsInt64())
+	assert.Equal(t, int64(3), attrs[AttrUsageOutputTokens].AsInt64())
+	assert.Equal(t, []string{"stop"}, attrs[AttrResponseFinishReason].AsStringSlice())
+	assert.Empty(t, span.Events)
+}
+
+func TestChatMiddleware_CaptureContent(t *testing.T) {
+	provider, exporter := newTestProvider()
+
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(&stubChatStrategy{})
+	modelContext.UseChat(ChatMiddleware(WithTracerProvider(provider), WithCaptureContent(true)))
+
+	_, err := modelContext.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}})
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0].Events, 2)
+	assert.Equal(t, "gen_ai.user.message", spans[0].Events[0].Name)
+	assert.Equal(t, "Hello", attributeMap(spans[0].Events[0].Attributes)["content"].AsString())
+	assert.Equal(t, "gen_ai.choice", spans[0].Events[1].Name)
+	assert.Equal(t, "Hi there", attributeMap(spans[0].Events[1].Attributes)["content"].AsString())
+}
+
+func TestChatMiddleware_Error(t *testing.T) {
+	provider, exporter := newTestProvider()
+
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(&stubChatStrategy{err: &gohttpclient.ClientError{Op: "non-2xx response", Code: 429}})
+	modelContext.UseChat(ChatMiddleware(WithTracerProvider(provider)))
+
+	_, err := modelContext.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}})
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "429", attributeMap(spans[0].Attributes)[AttrErrorType].AsString())
+	require.Len(t, spans[0].Events, 1)
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+}
+
+func TestEmbedMiddleware(t *testing.T) {
+	provider, exporter := newTestProvider()
+
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetEmbedStrategy(&stubEmbedStrategy{})
+	modelContext.UseEmbed(EmbedMiddleware(WithTracerProvider(provider), WithSystem("custom")))
+
+	_, err := modelContext.Embed(context.Background(), []string{"text1"}, llmconnector.WithEmbedModel("text-embedding-3-small"))
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "embeddings text-embedding-3-small", spans[0].Name)
+	attrs := attributeMap(spans[0].Attributes)
+	assert.Equal(t, "custom", attrs[AttrSystem].AsString())
+	assert.Equal(t, int64(4), attrs[AttrUsageInputTokens].AsInt64())
+}
@@ -0,0 +1,216 @@
+// Package llmprom collects Prometheus metrics for llmconnector.ModelContext calls.
+package llmprom
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/simp-lee/llmconnector"
+	"sync"
+	"time"
+)
+
+const (
+	operationChat  = "chat"
+	operationEmbed = "embed"
+)
+
+// Outcome label values.
+const (
+	OutcomeSuccess     = "success"
+	OutcomeClientError = "client_error"
+	OutcomeServerError = "server_error"
+	OutcomeTimeout     = "timeout"
+	OutcomeCanceled    = "canceled"
+	OutcomeError       = "error"
+)
+
+// Collector records request counts, latencies, time to first token, token usage and retries.
+// It implements prometheus.Collector, so it can be registered with any registry.
+type Collector struct {
+	requests         *prometheus.CounterVec
+	duration         *prometheus.HistogramVec
+	timeToFirstToken *prometheus.HistogramVec
+	tokens           *prometheus.CounterVec
+	retries          *prometheus.CounterVec
+}
+
+type config struct {
+	namespace     string
+	constLabels   prometheus.Labels
+	buckets       []float64
+	streamBuckets []float64
+}
+
+// Option configures a Collector.
+type Option func(c *config)
+
+// WithNamespace sets the metric namespace. Defaults to "llm".
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithConstLabels adds labels with fixed values to every metric.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(c *config) {
+		c.constLabels = labels
+	}
+}
+
+// WithDurationBuckets sets the histogram buckets for request latency, in seconds.
+func WithDurationBuckets(buckets []float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// WithTimeToFirstTokenBuckets sets the histogram buckets for time to first token, in seconds.
+func WithTimeToFirstTokenBuckets(buckets []float64) Option {
+	return func(c *config) {
+		c.streamBuckets = buckets
+	}
+}
+
+// NewCollector creates a Collector.
+func NewCollector(opts ...Option) *Collector {
+	c := &config{
+		namespace:     "llm",
+		buckets:       []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		streamBuckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Name:        "requests_total",
+			Help:        "Number of LLM requests by provider, model, operation and outcome.",
+			ConstLabels: c.constLabels,
+		}, []string{"provider", "model", "operation", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   c.namespace,
+			Name:        "request_duration_seconds",
+			Help:        "Latency of LLM requests in seconds.",
+			ConstLabels: c.constLabels,
+			Buckets:     c.buckets,
+		}, []string{"provider", "model", "operation"}),
+		timeToFirstToken: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   c.namespace,
+			Name:        "time_to_first_token_seconds",
+			Help:        "Time from the start of a streamed chat request to its first content delta in seconds.",
+			ConstLabels: c.constLabels,
+			Buckets:     c.streamBuckets,
+		}, []string{"provider", "model"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Name:        "tokens_total",
+			Help:        "Number of tokens consumed by type (prompt or completion).",
+			ConstLabels: c.constLabels,
+		}, []string{"provider", "model", "operation", "type"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Name:        "retries_total",
+			Help:        "Number of HTTP retries performed for LLM requests.",
+			ConstLabels: c.constLabels,
+		}, []string{"provider", "model", "operation"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.timeToFirstToken.Describe(ch)
+	c.tokens.Describe(ch)
+	c.retries.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.timeToFirstToken.Collect(ch)
+	c.tokens.Collect(ch)
+	c.retries.Collect(ch)
+}
+
+// ChatMiddleware returns a middleware recording metrics for every ModelContext.Chat call.
+func (c *Collector) ChatMiddleware() llmconnector.ChatMiddleware {
+	return func(next llmconnector.ChatHandler) llmconnector.ChatHandler {
+		return func(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (llmconnector.ChatResponse, error) {
+			provider := llmconnector.ProviderFromContext(ctx)
+			ctx, attempts := llmconnector.TrackAttempts(ctx)
+			start := time.Now()
+
+			if options.StreamHandler != nil {
+				streamed := *options
+				handler := options.StreamHandler
+				var once sync.Once
+				streamed.StreamHandler = func(delta string) error {
+					once.Do(func() {
+						c.timeToFirstToken.WithLabelValues(provider, options.Model).Observe(time.Since(start).Seconds())
+					})
+					return handler(delta)
+				}
+				options = &streamed
+			}
+
+			resp, err := next(ctx, chatMessages, options)
+			c.observe(provider, options.Model, operationChat, start, attempts(), resp, err)
+			return resp, err
+		}
+	}
+}
+
+// EmbedMiddleware returns a middleware recording metrics for every ModelContext.Embed call.
+func (c *Collector) EmbedMiddleware() llmconnector.EmbedMiddleware {
+	return func(next llmconnector.EmbedHandler) llmconnector.EmbedHandler {
+		return func(ctx context.Context, texts []string, options *llmconnector.EmbedOptions) (llmconnector.EmbedResponse, error) {
+			provider := llmconnector.ProviderFromContext(ctx)
+			ctx, attempts := llmconnector.TrackAttempts(ctx)
+			start := time.Now()
+
+			resp, err := next(ctx, texts, options)
+			c.observe(provider, options.Model, operationEmbed, start, attempts(), resp, err)
+			return resp, err
+		}
+	}
+}
+
+func (c *Collector) observe(provider, model, operation string, start time.Time, attempts int, resp interface{}, err error) {
+	c.requests.WithLabelValues(provider, model, operation, outcome(err)).Inc()
+	c.duration.WithLabelValues(provider, model, operation).Observe(time.Since(start).Seconds())
+	if attempts > 1 {
+		c.retries.WithLabelValues(provider, model, operation).Add(float64(attempts - 1))
+	}
+	if err != nil {
+		return
+	}
+	if usage, ok := llmconnector.UsageOf(resp); ok {
+		c.tokens.WithLabelValues(provider, model, operation, "prompt").Add(float64(usage.PromptTokens))
+		if operation == operationChat {
+			c.tokens.WithLabelValues(provider, model, operation, "completion").Add(float64(usage.CompletionTokens))
+		}
+	}
+}
+
+func outcome(err error) string {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	if code := llmconnector.StatusCode(err); code >= 500 {
+		return OutcomeServerError
+	} else if code >= 400 {
+		return OutcomeClientError
+	}
+	switch llmconnector.ClassifyError(err) {
+	case llmconnector.ErrorClassTimeout:
+		return OutcomeTimeout
+	case llmconnector.ErrorClassCanceled:
+		return OutcomeCanceled
+	}
+	return OutcomeError
+}
@@ -0,0 +1,114 @@
+package llmprom
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/simp-lee/gohttpclient"
+	"github.com/simp-lee/llmconnector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type stubChatStrategy struct {
+	err error
+}
+
+func (s *stubChatStrategy) ProviderName() string {
+	return "openai"
+}
+
+func (s *stubChatStrategy) Chat(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (llmconnector.ChatResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if options.StreamHandler != nil {
+		if err := options.StreamHandler("Hi"); err != nil {
+			return nil, err
+		}
+	}
+	resp := &llmconnector.AlibabaChatResponse{}
+	resp.Output.Text = "Hi"
+	resp.Usage.InputTokens = 10
+	resp.Usage.OutputTokens = 2
+	return resp, nil
+}
+
+type stubEmbedStrategy struct{}
+
+func (s *stubEmbedStrategy) Embed(ctx context.Context, texts []string, options *llmconnector.EmbedOptions) (llmconnector.EmbedResponse, error) {
+	resp := &llmconnector.OpenAIEmbedResponse{}
+	resp.Usage.PromptTokens = 4
+	return resp, nil
+}
+
+func TestCollector_Chat(t *testing.T) {
+	collector := NewCollector()
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(&stubChatStrategy{})
+	modelContext.UseChat(collector.ChatMiddleware())
+
+	_, err := modelContext.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}},
+		llmconnector.WithChatModel("gpt-4o"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues("openai", "gpt-4o", "chat", OutcomeSuccess)))
+	assert.Equal(t, 10.0, testutil.ToFloat64(collector.tokens.WithLabelValues("openai", "gpt-4o", "chat", "prompt")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(collector.tokens.WithLabelValues("openai", "gpt-4o", "chat", "completion")))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.duration))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.timeToFirstToken))
+}
+
+func TestCollector_ChatStream(t *testing.T) {
+	collector := NewCollector()
+
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(&stubChatStrategy{})
+	modelContext.UseChat(collector.ChatMiddleware())
+
+	var deltas []string
+	_, err := modelContext.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}},
+		llmconnector.WithChatModel("gpt-4o"),
+		llmconnector.WithStreamHandler(func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hi"}, deltas)
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.timeToFirstToken))
+}
+
+func TestCollector_ChatError(t *testing.T) {
+	collector := NewCollector()
+
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(&stubChatStrategy{err: &gohttpclient.ClientError{Op: "non-2xx response", Code: 503}})
+	modelContext.UseChat(collector.ChatMiddleware())
+
+	_, err := modelContext.Chat(context.Background(), []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}},
+		llmconnector.WithChatModel("gpt-4o"))
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues("openai", "gpt-4o", "chat", OutcomeServerError)))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.tokens))
+}
+
+func TestCollector_Embed(t *testing.T) {
+	collector := NewCollector(WithNamespace("test"))
+
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetEmbedStrategy(&stubEmbedStrategy{})
+	modelContext.UseEmbed(collector.EmbedMiddleware())
+
+	_, err := modelContext.Embed(context.Background(), []string{"text1"}, llmconnector.WithEmbedModel("text-embedding-3-small"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues("", "text-embedding-3-small", "embed", OutcomeSuccess)))
+	assert.Equal(t, 4.0, testutil.ToFloat64(collector.tokens.WithLabelValues("", "text-embedding-3-small", "embed", "prompt")))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.tokens))
+}
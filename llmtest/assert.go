@@ -0,0 +1,149 @@
+package llmtest
+
+import (
+	"github.com/simp-lee/llmconnector"
+	"reflect"
+	"testing"
+)
+
+// AssertChatCalls reports an error unless fake received exactly want calls.
+func AssertChatCalls(t testing.TB, fake *FakeChatStrategy, want int) bool {
+	t.Helper()
+	if got := len(fake.Calls()); got != want {
+		t.Errorf("llmtest: got %d chat calls, want %d", got, want)
+		return false
+	}
+	return true
+}
+
+// AssertEmbedCalls reports an error unless fake received exactly want calls.
+func AssertEmbedCalls(t testing.TB, fake *FakeEmbedStrategy, want int) bool {
+	t.Helper()
+	if got := len(fake.Calls()); got != want {
+		t.Errorf("llmtest: got %d embed calls, want %d", got, want)
+		return false
+	}
+	return true
+}
+
+// AssertMessages reports an error unless call received exactly the messages want.
+func AssertMessages(t testing.TB, call ChatCall, want ...llmconnector.ChatMessage) bool {
+	t.Helper()
+	if !reflect.DeepEqual(call.Messages, want) {
+		t.Errorf("llmtest: got messages %+v, want %+v", call.Messages, want)
+		return false
+	}
+	return true
+}
+
+// AssertLastMessage reports an error unless the last message of call has the given role and content.
+func AssertLastMessage(t testing.TB, call ChatCall, role, content string) bool {
+	t.Helper()
+	if len(call.Messages) == 0 {
+		t.Errorf("llmtest: call has no messages, want last message %s: %q", role, content)
+		return false
+	}
+	last := call.Messages[len(call.Messages)-1]
+	if last.Role != role || last.Content != content {
+		t.Errorf("llmtest: got last message %s: %q, want %s: %q", last.Role, last.Content, role, content)
+		return false
+	}
+	return true
+}
+
+// AssertChatOptions reports an error unless call received every option set by want.
+// Options left unset by want are not compared.
+func AssertChatOptions(t testing.TB, call ChatCall, want ...llmconnector.ChatOption) bool {
+	t.Helper()
+	expected := &llmconnector.ChatOptions{}
+	for _, opt := range want {
+		opt(expected)
+	}
+
+	ok := true
+	got := call.Options
+	if expected.Model != "" && got.Model != expected.Model {
+		t.Errorf("llmtest: got model %q, want %q", got.Model, expected.Model)
+		ok = false
+	}
+	if expected.Temperature != nil && !equalPtr(got.Temperature, expected.Temperature) {
+		t.Errorf("llmtest: got temperature %v, want %v", deref(got.Temperature), *expected.Temperature)
+		ok = false
+	}
+	if expected.MaxTokens != nil && !equalPtr(got.MaxTokens, expected.MaxTokens) {
+		t.Errorf("llmtest: got max tokens %v, want %v", deref(got.MaxTokens), *expected.MaxTokens)
+		ok = false
+	}
+	if expected.TopP != nil && !equalPtr(got.TopP, expected.TopP) {
+		t.Errorf("llmtest: got top p %v, want %v", deref(got.TopP), *expected.TopP)
+		ok = false
+	}
+	if expected.Stop != nil && !reflect.DeepEqual(got.Stop, expected.Stop) {
+		t.Errorf("llmtest: got stop %v, want %v", got.Stop, expected.Stop)
+		ok = false
+	}
+	if expected.StreamHandler != nil && got.StreamHandler == nil {
+		t.Errorf("llmtest: got a non-streamed call, want a streamed call")
+		ok = false
+	}
+	return ok
+}
+
+// AssertTexts reports an error unless call received exactly the texts want.
+func AssertTexts(t testing.TB, call EmbedCall, want ...string) bool {
+	t.Helper()
+	if !reflect.DeepEqual(call.Texts, want) {
+		t.Errorf("llmtest: got texts %q, want %q", call.Texts, want)
+		return false
+	}
+	return true
+}
+
+// AssertEmbedOptions reports an error unless call received every option set by want.
+// Options left unset by want are not compared.
+func AssertEmbedOptions(t testing.TB, call EmbedCall, want ...llmconnector.EmbedOption) bool {
+	t.Helper()
+	expected := &llmconnector.EmbedOptions{}
+	for _, opt := range want {
+		opt(expected)
+	}
+
+	ok := true
+	got := call.Options
+	if expected.Model != "" && got.Model != expected.Model {
+		t.Errorf("llmtest: got model %q, want %q", got.Model, expected.Model)
+		ok = false
+	}
+	if expected.EmbeddingType != "" && got.EmbeddingType != expected.EmbeddingType {
+		t.Errorf("llmtest: got embedding type %q, want %q", got.EmbeddingType, expected.EmbeddingType)
+		ok = false
+	}
+	if expected.Dimensions != 0 && got.Dimensions != expected.Dimensions {
+		t.Errorf("llmtest: got dimensions %d, want %d", got.Dimensions, expected.Dimensions)
+		ok = false
+	}
+	if expected.EncodingFormat != "" && got.EncodingFormat != expected.EncodingFormat {
+		t.Errorf("llmtest: got encoding format %q, want %q", got.EncodingFormat, expected.EncodingFormat)
+		ok = false
+	}
+	if expected.Normalize && !got.Normalize {
+		t.Errorf("llmtest: got no normalization, want normalization")
+		ok = false
+	}
+	if expected.OutputType != "" && got.OutputType != expected.OutputType {
+		t.Errorf("llmtest: got output type %q, want %q", got.OutputType, expected.OutputType)
+		ok = false
+	}
+	return ok
+}
+
+func equalPtr[T comparable](got, want *T) bool {
+	return got != nil && want != nil && *got == *want
+}
+
+func deref[T any](value *T) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
@@ -0,0 +1,55 @@
+package llmtest
+
+import (
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertChatOptions(t *testing.T) {
+	temperature := 0.2
+	call := ChatCall{Options: llmconnector.ChatOptions{Model: "test-model", Temperature: &temperature}}
+
+	tb := &recordingTB{}
+	assert.True(t, AssertChatOptions(tb, call, llmconnector.WithChatModel("test-model"), llmconnector.WithTemperature(0.2)))
+	assert.Empty(t, tb.errors)
+
+	assert.False(t, AssertChatOptions(tb, call, llmconnector.WithTemperature(0.7), llmconnector.WithMaxTokens(10)))
+	assert.Len(t, tb.errors, 2)
+}
+
+func TestAssertEmbedOptions(t *testing.T) {
+	call := EmbedCall{Options: llmconnector.EmbedOptions{Model: "test-model", OutputType: llmconnector.OutputTypeSparse}}
+
+	tb := &recordingTB{}
+	assert.True(t, AssertEmbedOptions(tb, call, llmconnector.WithEmbedModel("test-model"), llmconnector.WithOutputType(llmconnector.OutputTypeSparse)))
+	assert.Empty(t, tb.errors)
+
+	assert.False(t, AssertEmbedOptions(tb, call, llmconnector.WithOutputType(llmconnector.OutputTypeDense), llmconnector.WithDimensions(64)))
+	assert.Len(t, tb.errors, 2)
+}
+
+func TestAssertLastMessage(t *testing.T) {
+	call := ChatCall{Messages: []llmconnector.ChatMessage{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "Hello"},
+	}}
+
+	tb := &recordingTB{}
+	assert.True(t, AssertLastMessage(tb, call, "user", "Hello"))
+	assert.False(t, AssertLastMessage(tb, call, "user", "Goodbye"))
+	assert.False(t, AssertLastMessage(tb, ChatCall{}, "user", "Hello"))
+	assert.Len(t, tb.errors, 2)
+}
@@ -0,0 +1,243 @@
+// Package llmtest provides scriptable fake strategies and assertion helpers for testing code that depends
+// on llmconnector.ModelContext without network access.
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"github.com/simp-lee/llmconnector"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoReply is returned by FakeChatStrategy when no reply is queued and no default reply is set.
+var ErrNoReply = errors.New("llmtest: no reply queued")
+
+// ToolCall is a scripted tool invocation returned by a fake reply.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// Reply scripts the outcome of one chat call.
+type Reply struct {
+	// Content is the completion. When empty it is the concatenation of Chunks.
+	Content string
+	// Chunks are delivered to the stream handler of streamed calls. When empty, Content is sent as a single chunk.
+	Chunks       []string
+	ToolCalls    []ToolCall
+	Usage        llmconnector.Usage
+	FinishReason string
+	// Err is returned instead of a response.
+	Err error
+	// Latency delays the reply. The delay is interrupted when the context is done.
+	Latency time.Duration
+}
+
+// ChatResponse is the response returned by FakeChatStrategy.
+type ChatResponse struct {
+	Content      string
+	ToolCalls    []ToolCall
+	Usage        llmconnector.Usage
+	FinishReason string
+}
+
+func (r *ChatResponse) GetContent() string {
+	return r.Content
+}
+
+func (r *ChatResponse) GetUsage() llmconnector.Usage {
+	return r.Usage
+}
+
+func (r *ChatResponse) GetFinishReasons() []string {
+	if r.FinishReason == "" {
+		return nil
+	}
+	return []string{r.FinishReason}
+}
+
+// GetToolCalls returns the scripted tool calls of the reply.
+func (r *ChatResponse) GetToolCalls() []ToolCall {
+	return r.ToolCalls
+}
+
+// ChatCall is a chat call received by FakeChatStrategy.
+type ChatCall struct {
+	Messages []llmconnector.ChatMessage
+	Options  llmconnector.ChatOptions
+}
+
+// FakeChatStrategy is a llmconnector.ChatStrategy that serves queued replies in order and records every call.
+// It is safe for concurrent use.
+type FakeChatStrategy struct {
+	mu           sync.Mutex
+	replies      []Reply
+	defaultReply *Reply
+	latency      time.Duration
+	provider     string
+	calls        []ChatCall
+}
+
+// NewFakeChatStrategy creates a fake that serves replies in order.
+func NewFakeChatStrategy(replies ...Reply) *FakeChatStrategy {
+	return &FakeChatStrategy{replies: replies}
+}
+
+// Enqueue appends replies to the queue.
+func (f *FakeChatStrategy) Enqueue(replies ...Reply) *FakeChatStrategy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replies = append(f.replies, replies...)
+	return f
+}
+
+// EnqueueContent appends one reply per content.
+func (f *FakeChatStrategy) EnqueueContent(contents ...string) *FakeChatStrategy {
+	for _, content := range contents {
+		f.Enqueue(Reply{Content: content, FinishReason: "stop"})
+	}
+	return f
+}
+
+// EnqueueError appends a reply failing with err.
+func (f *FakeChatStrategy) EnqueueError(err error) *FakeChatStrategy {
+	return f.Enqueue(Reply{Err: err})
+}
+
+// SetDefault sets the reply served once the queue is empty.
+func (f *FakeChatStrategy) SetDefault(reply Reply) *FakeChatStrategy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.defaultReply = &reply
+	return f
+}
+
+// SetLatency delays every reply by latency in addition to the latency of the reply itself.
+func (f *FakeChatStrategy) SetLatency(latency time.Duration) *FakeChatStrategy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency = latency
+	return f
+}
+
+// SetProviderName sets the name reported through llmconnector.ProviderNamer.
+func (f *FakeChatStrategy) SetProviderName(provider string) *FakeChatStrategy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.provider = provider
+	return f
+}
+
+// ProviderName implements llmconnector.ProviderNamer. It defaults to "fake".
+func (f *FakeChatStrategy) ProviderName() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.provider == "" {
+		return "fake"
+	}
+	return f.provider
+}
+
+// Chat implements llmconnector.ChatStrategy.
+func (f *FakeChatStrategy) Chat(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions) (llmconnector.ChatResponse, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, ChatCall{
+		Messages: append([]llmconnector.ChatMessage(nil), chatMessages...),
+		Options:  *options,
+	})
+	var reply Reply
+	switch {
+	case len(f.replies) > 0:
+		reply = f.replies[0]
+		f.replies = f.replies[1:]
+	case f.defaultReply != nil:
+		reply = *f.defaultReply
+	default:
+		f.mu.Unlock()
+		return nil, ErrNoReply
+	}
+	latency := f.latency + reply.Latency
+	f.mu.Unlock()
+
+	if err := sleep(ctx, latency); err != nil {
+		return nil, err
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+
+	content := reply.Content
+	if content == "" {
+		content = strings.Join(reply.Chunks, "")
+	}
+	if options.StreamHandler != nil {
+		chunks := reply.Chunks
+		if len(chunks) == 0 && content != "" {
+			chunks = []string{content}
+		}
+		for _, chunk := range chunks {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err := options.StreamHandler(chunk); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &ChatResponse{
+		Content:      content,
+		ToolCalls:    reply.ToolCalls,
+		Usage:        reply.Usage,
+		FinishReason: reply.FinishReason,
+	}, nil
+}
+
+// Calls returns the calls received so far.
+func (f *FakeChatStrategy) Calls() []ChatCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ChatCall(nil), f.calls...)
+}
+
+// LastCall returns the most recent call. It returns false when no call was received.
+func (f *FakeChatStrategy) LastCall() (ChatCall, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.calls) == 0 {
+		return ChatCall{}, false
+	}
+	return f.calls[len(f.calls)-1], true
+}
+
+// Pending returns the number of queued replies not served yet.
+func (f *FakeChatStrategy) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.replies)
+}
+
+// Reset clears queued replies and recorded calls.
+func (f *FakeChatStrategy) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replies = nil
+	f.calls = nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
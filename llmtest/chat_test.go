@@ -0,0 +1,108 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"github.com/simp-lee/llmconnector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestFakeChatStrategy_Queue(t *testing.T) {
+	fake := NewFakeChatStrategy().EnqueueContent("first", "second").EnqueueError(errors.New("boom"))
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(fake)
+
+	messages := []llmconnector.ChatMessage{{Role: "user", Content: "Hello"}}
+	resp, err := modelContext.Chat(context.Background(), messages, llmconnector.WithChatModel("test-model"))
+	require.NoError(t, err)
+	assert.Equal(t, "first", resp.GetContent())
+
+	resp, err = modelContext.Chat(context.Background(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, "second", resp.GetContent())
+
+	_, err = modelContext.Chat(context.Background(), messages)
+	assert.EqualError(t, err, "boom")
+
+	_, err = modelContext.Chat(context.Background(), messages)
+	assert.ErrorIs(t, err, ErrNoReply)
+
+	AssertChatCalls(t, fake, 4)
+	calls := fake.Calls()
+	AssertChatOptions(t, calls[0], llmconnector.WithChatModel("test-model"))
+	AssertMessages(t, calls[0], messages...)
+}
+
+func TestFakeChatStrategy_Default(t *testing.T) {
+	fake := NewFakeChatStrategy().SetDefault(Reply{Content: "always"})
+
+	for i := 0; i < 3; i++ {
+		resp, err := fake.Chat(context.Background(), nil, &llmconnector.ChatOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "always", resp.GetContent())
+	}
+}
+
+func TestFakeChatStrategy_Stream(t *testing.T) {
+	fake := NewFakeChatStrategy(Reply{
+		Chunks:       []string{"Hi", " there"},
+		Usage:        llmconnector.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+		FinishReason: "stop",
+	})
+
+	var deltas []string
+	options := &llmconnector.ChatOptions{StreamHandler: func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	}}
+	resp, err := fake.Chat(context.Background(), nil, options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hi", " there"}, deltas)
+	assert.Equal(t, "Hi there", resp.GetContent())
+	assert.Equal(t, []string{"stop"}, llmconnector.FinishReasonsOf(resp))
+	usage, ok := llmconnector.UsageOf(resp)
+	require.True(t, ok)
+	assert.Equal(t, 5, usage.TotalTokens)
+}
+
+func TestFakeChatStrategy_ToolCalls(t *testing.T) {
+	fake := NewFakeChatStrategy(Reply{
+		ToolCalls:    []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Hangzhou"}`}},
+		FinishReason: "tool_calls",
+	})
+
+	resp, err := fake.Chat(context.Background(), nil, &llmconnector.ChatOptions{})
+	require.NoError(t, err)
+	fakeResp, ok := resp.(*ChatResponse)
+	require.True(t, ok)
+	require.Len(t, fakeResp.GetToolCalls(), 1)
+	assert.Equal(t, "get_weather", fakeResp.GetToolCalls()[0].Name)
+}
+
+func TestFakeChatStrategy_Latency(t *testing.T) {
+	fake := NewFakeChatStrategy(Reply{Content: "slow", Latency: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := fake.Chat(ctx, nil, &llmconnector.ChatOptions{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFakeChatStrategy_ProviderName(t *testing.T) {
+	fake := NewFakeChatStrategy().EnqueueContent("ok").SetProviderName("openai")
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(fake)
+
+	var provider string
+	modelContext.UseChat(llmconnector.ObserveChat(func(ctx context.Context, chatMessages []llmconnector.ChatMessage, options *llmconnector.ChatOptions, resp llmconnector.ChatResponse, err error, elapsed time.Duration) {
+		provider = llmconnector.ProviderFromContext(ctx)
+	}))
+
+	_, err := modelContext.Chat(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", provider)
+}
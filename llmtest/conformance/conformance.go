@@ -0,0 +1,510 @@
+// Package conformance is a reusable test suite holding ChatStrategy and EmbedStrategy implementations
+// to the same contract. It runs every check against a local llmmock server speaking the provider's dialect.
+//
+// A provider package adds a single test:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, conformance.Target{
+//			Dialect: conformance.DialectOpenAI,
+//			NewChatStrategy: func(config llmconnector.Config) (llmconnector.ChatStrategy, error) {
+//				return NewMyStrategy(config)
+//			},
+//		})
+//	}
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"github.com/simp-lee/llmconnector/llmmock"
+	"github.com/simp-lee/llmconnector/llmtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Dialect selects the wire format served by the stand-in server.
+type Dialect string
+
+const (
+	DialectOpenAI    Dialect = "openai"
+	DialectDashScope Dialect = "dashscope"
+)
+
+// Target describes the implementation under test. Either constructor may be nil to skip its checks.
+type Target struct {
+	Dialect          Dialect
+	NewChatStrategy  func(config llmconnector.Config) (llmconnector.ChatStrategy, error)
+	NewEmbedStrategy func(config llmconnector.Config) (llmconnector.EmbedStrategy, error)
+	// SkipStreaming skips the streaming check for strategies that do not support StreamHandler.
+	SkipStreaming bool
+	// Concurrency is the number of parallel calls of the concurrency check. Defaults to 16.
+	Concurrency int
+}
+
+// Run runs all chat and embed checks.
+func Run(t *testing.T, target Target) {
+	t.Run("Chat", func(t *testing.T) { RunChat(t, target) })
+	t.Run("Embed", func(t *testing.T) { RunEmbed(t, target) })
+}
+
+// RunChat runs the ChatStrategy checks.
+func RunChat(t *testing.T, target Target) {
+	if target.NewChatStrategy == nil {
+		t.Skip("no chat strategy")
+	}
+	t.Run("Basic", func(t *testing.T) { testChatBasic(t, target) })
+	t.Run("OptionMapping", func(t *testing.T) { testChatOptionMapping(t, target) })
+	t.Run("Streaming", func(t *testing.T) { testChatStreaming(t, target) })
+	t.Run("ErrorClassification", func(t *testing.T) { testChatErrors(t, target) })
+	t.Run("ContextCancellation", func(t *testing.T) { testChatCancellation(t, target) })
+	t.Run("Concurrency", func(t *testing.T) { testChatConcurrency(t, target) })
+}
+
+// RunEmbed runs the EmbedStrategy checks.
+func RunEmbed(t *testing.T, target Target) {
+	if target.NewEmbedStrategy == nil {
+		t.Skip("no embed strategy")
+	}
+	t.Run("Order", func(t *testing.T) { testEmbedOrder(t, target) })
+	t.Run("OptionMapping", func(t *testing.T) { testEmbedOptionMapping(t, target) })
+	t.Run("EmptyInput", func(t *testing.T) { testEmbedEmpty(t, target) })
+	t.Run("ErrorClassification", func(t *testing.T) { testEmbedErrors(t, target) })
+	t.Run("ContextCancellation", func(t *testing.T) { testEmbedCancellation(t, target) })
+	t.Run("Concurrency", func(t *testing.T) { testEmbedConcurrency(t, target) })
+}
+
+// retries and retryInterval are kept low so that checks of retried errors stay fast.
+const (
+	retries       = 1
+	retryInterval = time.Millisecond
+)
+
+func startServer(t *testing.T, target Target, options llmmock.Options) (*llmmock.Server, llmconnector.Config) {
+	t.Helper()
+	mock := llmmock.New(options)
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+
+	var config llmconnector.Config
+	switch target.Dialect {
+	case DialectOpenAI:
+		config = mock.OpenAIConfig(server.URL)
+	case DialectDashScope:
+		config = mock.AlibabaConfig(server.URL)
+	default:
+		t.Fatalf("conformance: unknown dialect %q", target.Dialect)
+	}
+	config.CommonConfig = llmconnector.DefaultCommonConfig()
+	config.Retries = retries
+	config.RetryInterval = retryInterval
+	return mock, config
+}
+
+func newChat(t *testing.T, target Target, options llmmock.Options) (*llmmock.Server, llmconnector.ChatStrategy) {
+	t.Helper()
+	mock, config := startServer(t, target, options)
+	strategy, err := target.NewChatStrategy(config)
+	if err != nil {
+		t.Fatalf("conformance: failed to create chat strategy: %v", err)
+	}
+	return mock, strategy
+}
+
+func newEmbed(t *testing.T, target Target, options llmmock.Options) (*llmmock.Server, llmconnector.EmbedStrategy) {
+	t.Helper()
+	mock, config := startServer(t, target, options)
+	strategy, err := target.NewEmbedStrategy(config)
+	if err != nil {
+		t.Fatalf("conformance: failed to create embed strategy: %v", err)
+	}
+	return mock, strategy
+}
+
+func userMessage(content string) []llmconnector.ChatMessage {
+	return []llmconnector.ChatMessage{{Role: "user", Content: content}}
+}
+
+func testChatBasic(t *testing.T, target Target) {
+	mock, strategy := newChat(t, target, llmmock.Options{})
+	mock.EnqueueChat(llmmock.ChatReply{Content: "Hi there", FinishReason: "stop"})
+
+	resp, err := strategy.Chat(context.Background(), userMessage("Hello"), &llmconnector.ChatOptions{Model: "test-model"})
+	if err != nil {
+		t.Fatalf("Chat returned error: %v", err)
+	}
+	if got := resp.GetContent(); got != "Hi there" {
+		t.Errorf("got content %q, want %q", got, "Hi there")
+	}
+	if reasons := llmconnector.FinishReasonsOf(resp); reasons != nil && (len(reasons) != 1 || reasons[0] != "stop") {
+		t.Errorf("got finish reasons %v, want [stop]", reasons)
+	}
+	if usage, ok := llmconnector.UsageOf(resp); ok && usage.PromptTokens == 0 {
+		t.Errorf("response reports usage but prompt tokens are 0")
+	}
+}
+
+func testChatOptionMapping(t *testing.T, target Target) {
+	mock, strategy := newChat(t, target, llmmock.Options{})
+
+	temperature, topP, maxTokens := 0.3, 0.8, 42
+	options := &llmconnector.ChatOptions{
+		Model:       "test-model",
+		Temperature: &temperature,
+		TopP:        &topP,
+		MaxTokens:   &maxTokens,
+		Stop:        []string{"###"},
+	}
+	messages := []llmconnector.ChatMessage{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "Hello"},
+	}
+	if _, err := strategy.Chat(context.Background(), messages, options); err != nil {
+		t.Fatalf("Chat returned error: %v", err)
+	}
+
+	body := lastBody(t, mock)
+	expectField(t, body, chatPath(target, "model"), "test-model")
+	expectField(t, body, chatPath(target, "temperature"), 0.3)
+	expectField(t, body, chatPath(target, "top_p"), 0.8)
+	expectField(t, body, chatPath(target, "max_tokens"), 42)
+	expectField(t, body, chatPath(target, "stop"), []string{"###"})
+	expectField(t, body, chatPath(target, "messages"), messages)
+}
+
+func testChatStreaming(t *testing.T, target Target) {
+	if target.SkipStreaming {
+		t.Skip("streaming not supported")
+	}
+	mock, strategy := newChat(t, target, llmmock.Options{})
+	mock.EnqueueChat(llmmock.ChatReply{Chunks: []string{"Hi", " there", "!"}})
+
+	var deltas []string
+	resp, err := strategy.Chat(context.Background(), userMessage("Hello"), &llmconnector.ChatOptions{
+		Model: "test-model",
+		StreamHandler: func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Chat returned error: %v", err)
+	}
+	if got := strings.Join(deltas, ""); got != "Hi there!" {
+		t.Errorf("got streamed content %q, want %q", got, "Hi there!")
+	}
+	if got := resp.GetContent(); got != "Hi there!" {
+		t.Errorf("got content %q, want %q", got, "Hi there!")
+	}
+}
+
+var errorCases = []struct {
+	status int
+	class  llmconnector.ErrorClass
+}{
+	{http.StatusBadRequest, llmconnector.ErrorClassInvalidRequest},
+	{http.StatusUnauthorized, llmconnector.ErrorClassAuth},
+	{http.StatusTooManyRequests, llmconnector.ErrorClassRateLimit},
+	{http.StatusServiceUnavailable, llmconnector.ErrorClassServer},
+}
+
+func testChatErrors(t *testing.T, target Target) {
+	for _, tc := range errorCases {
+		t.Run(fmt.Sprint(tc.status), func(t *testing.T) {
+			mock, strategy := newChat(t, target, llmmock.Options{})
+			for i := 0; i <= retries; i++ {
+				mock.EnqueueChat(llmmock.ChatReply{Status: tc.status, Message: "scripted failure"})
+			}
+
+			_, err := strategy.Chat(context.Background(), userMessage("Hello"), &llmconnector.ChatOptions{Model: "test-model"})
+			checkError(t, err, tc.status, tc.class)
+		})
+	}
+}
+
+func testEmbedErrors(t *testing.T, target Target) {
+	for _, tc := range errorCases {
+		t.Run(fmt.Sprint(tc.status), func(t *testing.T) {
+			mock, strategy := newEmbed(t, target, llmmock.Options{})
+			for i := 0; i <= retries; i++ {
+				mock.EnqueueEmbed(llmmock.EmbedReply{Status: tc.status, Message: "scripted failure"})
+			}
+
+			_, err := strategy.Embed(context.Background(), []string{"text1"}, &llmconnector.EmbedOptions{Model: "test-model"})
+			checkError(t, err, tc.status, tc.class)
+		})
+	}
+}
+
+func checkError(t *testing.T, err error, status int, class llmconnector.ErrorClass) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected an error for HTTP %d", status)
+	}
+	if got := llmconnector.StatusCode(err); got != status {
+		t.Errorf("got status code %d, want %d (error: %v)", got, status, err)
+	}
+	if got := llmconnector.ClassifyError(err); got != class {
+		t.Errorf("got error class %q, want %q (error: %v)", got, class, err)
+	}
+}
+
+// slowReply is longer than any check is willing to wait.
+const slowReply = 10 * time.Second
+
+func testChatCancellation(t *testing.T, target Target) {
+	mock, strategy := newChat(t, target, llmmock.Options{})
+	for i := 0; i <= retries; i++ {
+		mock.EnqueueChat(llmmock.ChatReply{Content: "too late", Latency: llmmock.Duration(slowReply)})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := strategy.Chat(ctx, userMessage("Hello"), &llmconnector.ChatOptions{Model: "test-model"})
+	checkCanceled(t, err, time.Since(start))
+}
+
+func testEmbedCancellation(t *testing.T, target Target) {
+	mock, strategy := newEmbed(t, target, llmmock.Options{})
+	for i := 0; i <= retries; i++ {
+		mock.EnqueueEmbed(llmmock.EmbedReply{Latency: llmmock.Duration(slowReply)})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := strategy.Embed(ctx, []string{"text1"}, &llmconnector.EmbedOptions{Model: "test-model"})
+	checkCanceled(t, err, time.Since(start))
+}
+
+func checkCanceled(t *testing.T, err error, elapsed time.Duration) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected an error after cancellation")
+	}
+	if got := llmconnector.ClassifyError(err); got != llmconnector.ErrorClassCanceled {
+		t.Errorf("got error class %q, want %q (error: %v)", got, llmconnector.ErrorClassCanceled, err)
+	}
+	if elapsed >= slowReply {
+		t.Errorf("call returned after %v, it did not stop on cancellation", elapsed)
+	}
+}
+
+func concurrency(target Target) int {
+	if target.Concurrency > 0 {
+		return target.Concurrency
+	}
+	return 16
+}
+
+func testChatConcurrency(t *testing.T, target Target) {
+	_, strategy := newChat(t, target, llmmock.Options{Latency: 5 * time.Millisecond})
+
+	n := concurrency(target)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prompt := fmt.Sprintf("question %d", i)
+			resp, err := strategy.Chat(context.Background(), userMessage(prompt), &llmconnector.ChatOptions{Model: "test-model"})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			// The stand-in server echoes the prompt, so mixed up responses are detected.
+			if want := "mock response to: " + prompt; resp.GetContent() != want {
+				errs[i] = fmt.Errorf("got content %q, want %q", resp.GetContent(), want)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("call %d: %v", i, err)
+		}
+	}
+}
+
+func testEmbedOrder(t *testing.T, target Target) {
+	_, strategy := newEmbed(t, target, llmmock.Options{ShuffleEmbeddings: true, Dimensions: 4})
+
+	texts := []string{"alpha", "beta", "gamma", "delta"}
+	resp, err := strategy.Embed(context.Background(), texts, &llmconnector.EmbedOptions{Model: "test-model"})
+	if err != nil {
+		t.Fatalf("Embed returned error: %v", err)
+	}
+	checkEmbeddings(t, texts, resp.GetEmbeddings(), 4)
+}
+
+func testEmbedOptionMapping(t *testing.T, target Target) {
+	mock, strategy := newEmbed(t, target, llmmock.Options{Dimensions: 4})
+
+	texts := []string{"text1", "text2"}
+	options := &llmconnector.EmbedOptions{Model: "test-model", EmbeddingType: "document", Dimensions: 16}
+	resp, err := strategy.Embed(context.Background(), texts, options)
+	if err != nil {
+		t.Fatalf("Embed returned error: %v", err)
+	}
+	checkEmbeddings(t, texts, resp.GetEmbeddings(), 16)
+
+	body := lastBody(t, mock)
+	expectField(t, body, embedPath(target, "model"), "test-model")
+	expectField(t, body, embedPath(target, "input"), texts)
+	expectField(t, body, embedPath(target, "dimensions"), 16)
+	// OpenAI has no text types.
+	if target.Dialect == DialectDashScope {
+		expectField(t, body, embedPath(target, "text_type"), "document")
+	}
+}
+
+func testEmbedEmpty(t *testing.T, target Target) {
+	mock, strategy := newEmbed(t, target, llmmock.Options{})
+
+	resp, err := strategy.Embed(context.Background(), nil, &llmconnector.EmbedOptions{Model: "test-model"})
+	if err != nil {
+		t.Fatalf("Embed of no texts returned error: %v", err)
+	}
+	if got := len(resp.GetEmbeddings()); got != 0 {
+		t.Errorf("got %d embeddings for no texts", got)
+	}
+	if got := len(mock.Requests()); got != 0 {
+		t.Errorf("Embed of no texts sent %d requests, want none", got)
+	}
+}
+
+func testEmbedConcurrency(t *testing.T, target Target) {
+	_, strategy := newEmbed(t, target, llmmock.Options{Latency: 5 * time.Millisecond, Dimensions: 4})
+
+	n := concurrency(target)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			texts := []string{fmt.Sprintf("text %d", i), fmt.Sprintf("other %d", i)}
+			resp, err := strategy.Embed(context.Background(), texts, &llmconnector.EmbedOptions{Model: "test-model"})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if !embeddingsMatch(texts, resp.GetEmbeddings(), 4) {
+				errs[i] = fmt.Errorf("embeddings do not match texts %q", texts)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("call %d: %v", i, err)
+		}
+	}
+}
+
+func checkEmbeddings(t *testing.T, texts []string, got [][]float32, dimensions int) {
+	t.Helper()
+	if !embeddingsMatch(texts, got, dimensions) {
+		t.Errorf("embeddings are not in input order for texts %q", texts)
+	}
+}
+
+// embeddingsMatch compares got with the vectors the stand-in server derives from each text.
+func embeddingsMatch(texts []string, got [][]float32, dimensions int) bool {
+	if len(got) != len(texts) {
+		return false
+	}
+	embedder := llmtest.HashEmbedder{Dimensions: dimensions}
+	for i, text := range texts {
+		want := embedder.Vector(text)
+		if len(got[i]) != len(want) {
+			return false
+		}
+		for j := range want {
+			if got[i][j] != want[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func lastBody(t *testing.T, mock *llmmock.Server) map[string]interface{} {
+	t.Helper()
+	requests := mock.Requests()
+	if len(requests) == 0 {
+		t.Fatalf("no request reached the server")
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(requests[len(requests)-1].Body), &body); err != nil {
+		t.Fatalf("request body is not a JSON object: %v", err)
+	}
+	return body
+}
+
+// chatPath returns the path of a chat request field in the layout of the target's dialect.
+func chatPath(target Target, key string) []string {
+	if target.Dialect != DialectDashScope || key == "model" {
+		return []string{key}
+	}
+	if key == "messages" {
+		return []string{"input", "messages"}
+	}
+	return []string{"parameters", key}
+}
+
+// embedPath returns the path of an embedding request field in the layout of the target's dialect.
+func embedPath(target Target, key string) []string {
+	if target.Dialect != DialectDashScope || key == "model" {
+		return []string{key}
+	}
+	switch key {
+	case "input":
+		return []string{"input", "texts"}
+	case "dimensions":
+		return []string{"parameters", "dimension"}
+	}
+	return []string{"parameters", key}
+}
+
+// lookup returns the value at path in body.
+func lookup(body map[string]interface{}, path []string) (interface{}, bool) {
+	var value interface{} = body
+	for _, key := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func expectField(t *testing.T, body map[string]interface{}, path []string, want interface{}) {
+	t.Helper()
+	name := strings.Join(path, ".")
+	got, ok := lookup(body, path)
+	if !ok {
+		t.Errorf("request does not set %s: %v", name, body)
+		return
+	}
+	// want goes through the same decoding as got, so that objects compare with sorted keys.
+	wantJSON, _ := json.Marshal(want)
+	json.Unmarshal(wantJSON, &want)
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ = json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("got %s = %s, want %s", name, gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,30 @@
+package conformance
+
+import (
+	"github.com/simp-lee/llmconnector"
+	"testing"
+)
+
+func TestOpenAIStrategy(t *testing.T) {
+	Run(t, Target{
+		Dialect: DialectOpenAI,
+		NewChatStrategy: func(config llmconnector.Config) (llmconnector.ChatStrategy, error) {
+			return llmconnector.NewOpenAIStrategy(config)
+		},
+		NewEmbedStrategy: func(config llmconnector.Config) (llmconnector.EmbedStrategy, error) {
+			return llmconnector.NewOpenAIStrategy(config)
+		},
+	})
+}
+
+func TestAlibabaStrategy(t *testing.T) {
+	Run(t, Target{
+		Dialect: DialectDashScope,
+		NewChatStrategy: func(config llmconnector.Config) (llmconnector.ChatStrategy, error) {
+			return llmconnector.NewAlibabaStrategy(config)
+		},
+		NewEmbedStrategy: func(config llmconnector.Config) (llmconnector.EmbedStrategy, error) {
+			return llmconnector.NewAlibabaStrategy(config)
+		},
+	})
+}
@@ -0,0 +1,211 @@
+package llmtest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"github.com/simp-lee/llmconnector"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDimensions is the vector size of a zero HashEmbedder.
+const DefaultDimensions = 8
+
+// HashEmbedder is a deterministic llmconnector.EmbedStrategy deriving a unit vector from the SHA-256 of each text.
+// The same text always yields the same vector, different texts yield unrelated vectors.
+type HashEmbedder struct {
+	Dimensions int
+}
+
+// Vector returns the embedding of text.
+func (h HashEmbedder) Vector(text string) []float32 {
+	dimensions := h.Dimensions
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+
+	vector := make([]float32, dimensions)
+	var norm float64
+	block := sha256.Sum256([]byte(text))
+	for i := range vector {
+		offset := (i * 4) % len(block)
+		if i > 0 && offset == 0 {
+			block = sha256.Sum256(block[:])
+		}
+		value := float64(binary.BigEndian.Uint32(block[offset:offset+4]))/math.MaxUint32*2 - 1
+		vector[i] = float32(value)
+		norm += value * value
+	}
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for i := range vector {
+			vector[i] = float32(float64(vector[i]) / norm)
+		}
+	}
+	return vector
+}
+
+// Embed implements llmconnector.EmbedStrategy.
+func (h HashEmbedder) Embed(ctx context.Context, texts []string, options *llmconnector.EmbedOptions) (llmconnector.EmbedResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	resp := &EmbedResponse{Embeddings: make([][]float32, len(texts))}
+	for i, text := range texts {
+		resp.Embeddings[i] = h.Vector(text)
+		resp.Usage.PromptTokens += len(strings.Fields(text))
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+	return resp, nil
+}
+
+// EmbedResponse is the response returned by the fake embed strategies.
+type EmbedResponse struct {
+	Embeddings [][]float32
+	Usage      llmconnector.Usage
+}
+
+func (r *EmbedResponse) GetEmbeddings() [][]float32 {
+	return r.Embeddings
+}
+
+func (r *EmbedResponse) GetUsage() llmconnector.Usage {
+	return r.Usage
+}
+
+// EmbedReply scripts the outcome of one embed call.
+type EmbedReply struct {
+	Embeddings [][]float32
+	Usage      llmconnector.Usage
+	Err        error
+	Latency    time.Duration
+}
+
+// EmbedCall is an embed call received by FakeEmbedStrategy.
+type EmbedCall struct {
+	Texts   []string
+	Options llmconnector.EmbedOptions
+}
+
+// FakeEmbedStrategy is a llmconnector.EmbedStrategy that serves queued replies in order and records every call.
+// Once the queue is empty it falls back to a HashEmbedder. It is safe for concurrent use.
+type FakeEmbedStrategy struct {
+	mu       sync.Mutex
+	replies  []EmbedReply
+	fallback HashEmbedder
+	latency  time.Duration
+	provider string
+	calls    []EmbedCall
+}
+
+// NewFakeEmbedStrategy creates a fake that serves replies in order.
+func NewFakeEmbedStrategy(replies ...EmbedReply) *FakeEmbedStrategy {
+	return &FakeEmbedStrategy{replies: replies}
+}
+
+// Enqueue appends replies to the queue.
+func (f *FakeEmbedStrategy) Enqueue(replies ...EmbedReply) *FakeEmbedStrategy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replies = append(f.replies, replies...)
+	return f
+}
+
+// EnqueueError appends a reply failing with err.
+func (f *FakeEmbedStrategy) EnqueueError(err error) *FakeEmbedStrategy {
+	return f.Enqueue(EmbedReply{Err: err})
+}
+
+// SetDimensions sets the vector size of the hash embedder fallback.
+func (f *FakeEmbedStrategy) SetDimensions(dimensions int) *FakeEmbedStrategy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fallback.Dimensions = dimensions
+	return f
+}
+
+// SetLatency delays every reply by latency in addition to the latency of the reply itself.
+func (f *FakeEmbedStrategy) SetLatency(latency time.Duration) *FakeEmbedStrategy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency = latency
+	return f
+}
+
+// SetProviderName sets the name reported through llmconnector.ProviderNamer.
+func (f *FakeEmbedStrategy) SetProviderName(provider string) *FakeEmbedStrategy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.provider = provider
+	return f
+}
+
+// ProviderName implements llmconnector.ProviderNamer. It defaults to "fake".
+func (f *FakeEmbedStrategy) ProviderName() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.provider == "" {
+		return "fake"
+	}
+	return f.provider
+}
+
+// Embed implements llmconnector.EmbedStrategy.
+func (f *FakeEmbedStrategy) Embed(ctx context.Context, texts []string, options *llmconnector.EmbedOptions) (llmconnector.EmbedResponse, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, EmbedCall{
+		Texts:   append([]string(nil), texts...),
+		Options: *options,
+	})
+	var reply *EmbedReply
+	if len(f.replies) > 0 {
+		reply = &f.replies[0]
+		f.replies = f.replies[1:]
+	}
+	fallback := f.fallback
+	latency := f.latency
+	f.mu.Unlock()
+
+	if reply == nil {
+		if err := sleep(ctx, latency); err != nil {
+			return nil, err
+		}
+		return fallback.Embed(ctx, texts, options)
+	}
+
+	if err := sleep(ctx, latency+reply.Latency); err != nil {
+		return nil, err
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+	return &EmbedResponse{Embeddings: reply.Embeddings, Usage: reply.Usage}, nil
+}
+
+// Calls returns the calls received so far.
+func (f *FakeEmbedStrategy) Calls() []EmbedCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]EmbedCall(nil), f.calls...)
+}
+
+// LastCall returns the most recent call. It returns false when no call was received.
+func (f *FakeEmbedStrategy) LastCall() (EmbedCall, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.calls) == 0 {
+		return EmbedCall{}, false
+	}
+	return f.calls[len(f.calls)-1], true
+}
+
+// Reset clears queued replies and recorded calls.
+func (f *FakeEmbedStrategy) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replies = nil
+	f.calls = nil
+}
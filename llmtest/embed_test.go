@@ -0,0 +1,54 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"github.com/simp-lee/llmconnector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	embedder := HashEmbedder{Dimensions: 16}
+
+	a := embedder.Vector("hello")
+	b := embedder.Vector("hello")
+	c := embedder.Vector("world")
+	assert.Len(t, a, 16)
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+
+	var norm float64
+	for _, value := range a {
+		norm += float64(value) * float64(value)
+	}
+	assert.InDelta(t, 1.0, math.Sqrt(norm), 1e-5)
+}
+
+func TestHashEmbedder_DefaultDimensions(t *testing.T) {
+	assert.Len(t, HashEmbedder{}.Vector("hello"), DefaultDimensions)
+}
+
+func TestFakeEmbedStrategy(t *testing.T) {
+	fake := NewFakeEmbedStrategy(EmbedReply{Embeddings: [][]float32{{1, 0}}}).EnqueueError(errors.New("boom"))
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetEmbedStrategy(fake)
+
+	resp, err := modelContext.Embed(context.Background(), []string{"text1"}, llmconnector.WithEmbedModel("test-model"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}}, resp.GetEmbeddings())
+
+	_, err = modelContext.Embed(context.Background(), []string{"text1"})
+	assert.EqualError(t, err, "boom")
+
+	resp, err = modelContext.Embed(context.Background(), []string{"text1", "text2"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{HashEmbedder{}.Vector("text1"), HashEmbedder{}.Vector("text2")}, resp.GetEmbeddings())
+
+	AssertEmbedCalls(t, fake, 3)
+	calls := fake.Calls()
+	AssertEmbedOptions(t, calls[0], llmconnector.WithEmbedModel("test-model"))
+	AssertTexts(t, calls[2], "text1", "text2")
+}
@@ -0,0 +1,198 @@
+package llmconnector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// requestLogger logs requests made by a strategy. A nil *requestLogger logs nothing.
+type requestLogger struct {
+	logger    *slog.Logger
+	level     slog.Level
+	logBodies bool
+	provider  string
+
+	mu       sync.RWMutex
+	secrets  []string
+	replacer func(string) string
+}
+
+func newRequestLogger(config CommonConfig, provider string, secrets ...string) *requestLogger {
+	if config.Logger == nil {
+		return nil
+	}
+	return &requestLogger{
+		logger:    config.Logger.With(slog.String("provider", provider)),
+		level:     config.LogLevel,
+		logBodies: config.LogBodies,
+		provider:  provider,
+		secrets:   secrets,
+		replacer:  Redactor(secrets...),
+	}
+}
+
+// redact replaces the secrets given to newRequestLogger and every credential sent so far in s.
+func (l *requestLogger) redact(s string) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.replacer(s)
+}
+
+// addSecret makes redact replace secret from now on.
+func (l *requestLogger) addSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, known := range l.secrets {
+		if known == secret {
+			return
+		}
+	}
+	l.secrets = append(l.secrets, secret)
+	l.replacer = Redactor(l.secrets...)
+}
+
+// start logs the beginning of a request and returns its start time.
+func (l *requestLogger) start(ctx context.Context, operation, model string, request interface{}) time.Time {
+	start := time.Now()
+	if l == nil {
+		return start
+	}
+	l.logger.Log(ctx, l.level, "llm request started",
+		slog.String("operation", operation),
+		slog.String("model", model),
+	)
+	if l.logBodies {
+		if body, err := json.Marshal(request); err == nil {
+			l.logger.DebugContext(ctx, "llm request body",
+				slog.String("operation", operation),
+				slog.String("body", l.redact(string(body))),
+			)
+		}
+	}
+	return start
+}
+
+// end logs the outcome of a request. result is the decoded response and may carry token usage.
+func (l *requestLogger) end(ctx context.Context, operation, model string, start time.Time, body []byte, result interface{}, err error) {
+	if l == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("operation", operation),
+		slog.String("model", model),
+		slog.Duration("latency", time.Since(start)),
+		slog.Int("status", statusOf(err)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", l.redact(err.Error())))
+		l.logger.LogAttrs(ctx, slog.LevelError, "llm request failed", attrs...)
+		return
+	}
+	if usage, ok := UsageOf(result); ok {
+		attrs = append(attrs,
+			slog.Int("prompt_tokens", usage.PromptTokens),
+			slog.Int("completion_tokens", usage.CompletionTokens),
+			slog.Int("total_tokens", usage.TotalTokens),
+		)
+	}
+	l.logger.LogAttrs(ctx, l.level, "llm request finished", attrs...)
+	if l.logBodies && body != nil {
+		l.logger.DebugContext(ctx, "llm response body",
+			slog.String("operation", operation),
+			slog.String("body", l.redact(string(body))),
+		)
+	}
+}
+
+// logHTTPRequest is a request interceptor logging every attempt with redacted headers at debug level.
+// It runs after authorize, so it also learns the credentials a CredentialProvider resolved for the attempt
+// and redacts them wherever they are echoed, e.g. in error messages or response bodies.
+func (l *requestLogger) logHTTPRequest(req *http.Request) error {
+	for key, values := range req.Header {
+		if !IsSensitiveHeader(key) {
+			continue
+		}
+		for _, value := range values {
+			l.addSecret(strings.TrimPrefix(value, "Bearer "))
+		}
+	}
+	l.logger.DebugContext(req.Context(), "llm http request",
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+		slog.Any("headers", redactHeaders(req.Header)),
+	)
+	return nil
+}
+
+// logHTTPResponse is a response interceptor logging the status of every attempt at debug level.
+func (l *requestLogger) logHTTPResponse(resp *http.Response) error {
+	l.logger.DebugContext(resp.Request.Context(), "llm http response",
+		slog.String("url", resp.Request.URL.String()),
+		slog.Int("status", resp.StatusCode),
+	)
+	return nil
+}
+
+// redactHeaders returns a copy of header with credentials replaced.
+func redactHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for key, values := range header {
+		value := strings.Join(values, ",")
+		if IsSensitiveHeader(key) {
+			value = redacted
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// IsSensitiveHeader reports whether the header named key may carry credentials, so logs and recordings
+// must not show its value.
+func IsSensitiveHeader(key string) bool {
+	key = strings.ToLower(key)
+	return key == "authorization" || key == "proxy-authorization" || key == "cookie" || key == "set-cookie" ||
+		strings.Contains(key, "key") || strings.Contains(key, "token") || strings.Contains(key, "secret")
+}
+
+// statusOf returns the HTTP status of a finished request, 0 when no response was received.
+func statusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return StatusCode(err)
+}
+
+// slogAdapter routes the gohttpclient logs to slog at debug level.
+type slogAdapter struct {
+	logger *slog.Logger
+	redact func(string) string
+}
+
+func (a *slogAdapter) Info(msg string, keyVals ...interface{}) {
+	a.logger.Debug(msg, a.args(keyVals)...)
+}
+
+func (a *slogAdapter) Error(msg string, keyVals ...interface{}) {
+	a.logger.Debug(msg, a.args(keyVals)...)
+}
+
+func (a *slogAdapter) args(keyVals []interface{}) []any {
+	args := make([]any, len(keyVals))
+	for i, value := range keyVals {
+		if err, ok := value.(error); ok {
+			value = a.redact(err.Error())
+		}
+		args[i] = value
+	}
+	return args
+}
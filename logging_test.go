@@ -0,0 +1,119 @@
+package llmconnector
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStrategyLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi there"}}],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	commonConfig := DefaultCommonConfig()
+	commonConfig.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	commonConfig.LogLevel = slog.LevelInfo
+	commonConfig.LogBodies = true
+
+	strategy, err := NewOpenAIStrategy(Config{
+		APIKey:       "sk-secret-key",
+		ChatURL:      server.URL,
+		CommonConfig: commonConfig,
+	})
+	require.NoError(t, err)
+
+	messages := []ChatMessage{{Role: "user", Content: "my key is sk-secret-key"}}
+	_, err = strategy.Chat(context.Background(), messages, &ChatOptions{Model: "test-model"})
+	require.NoError(t, err)
+
+	logs := buf.String()
+	assert.Contains(t, logs, "llm request started")
+	assert.Contains(t, logs, "llm request finished")
+	assert.Contains(t, logs, "provider=openai")
+	assert.Contains(t, logs, "model=test-model")
+	assert.Contains(t, logs, "status=200")
+	assert.Contains(t, logs, "total_tokens=7")
+	assert.Contains(t, logs, "llm request body")
+	assert.Contains(t, logs, "Authorization:[REDACTED]")
+	assert.NotContains(t, logs, "sk-secret-key")
+}
+
+func TestStrategyLogging_Failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid key sk-secret-key"}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	commonConfig := DefaultCommonConfig()
+	commonConfig.Retries = 0
+	commonConfig.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+	strategy, err := NewAlibabaStrategy(Config{
+		APIKey:       "sk-secret-key",
+		EmbedURL:     server.URL,
+		CommonConfig: commonConfig,
+	})
+	require.NoError(t, err)
+
+	_, err = strategy.Embed(context.Background(), []string{"text1"}, &EmbedOptions{Model: "test-model"})
+	require.Error(t, err)
+
+	logs := buf.String()
+	assert.Contains(t, logs, "level=ERROR")
+	assert.Contains(t, logs, "llm request failed")
+	assert.Contains(t, logs, "status=401")
+	assert.NotContains(t, logs, "sk-secret-key")
+}
+
+func TestStrategyLogging_RedactsResolvedCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"invalid key ` + strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") + `"}}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	commonConfig := DefaultCommonConfig()
+	commonConfig.Retries = 0
+	commonConfig.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	commonConfig.LogBodies = true
+
+	strategy, err := NewOpenAIStrategy(Config{
+		Credentials:  StaticCredential("sk-resolved-key"),
+		ChatURL:      server.URL,
+		CommonConfig: commonConfig,
+	})
+	require.NoError(t, err)
+
+	_, err = strategy.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}}, &ChatOptions{Model: "test-model"})
+	require.Error(t, err)
+
+	logs := buf.String()
+	assert.Contains(t, logs, "llm request failed")
+	assert.Contains(t, logs, "invalid key [REDACTED]")
+	assert.NotContains(t, logs, "sk-resolved-key")
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer sk-secret-key")
+	header.Set("X-Api-Key", "sk-secret-key")
+	header.Set("Content-Type", "application/json")
+
+	assert.Equal(t, map[string]string{
+		"Authorization": redacted,
+		"X-Api-Key":     redacted,
+		"Content-Type":  "application/json",
+	}, redactHeaders(header))
+}
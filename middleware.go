@@ -0,0 +1,175 @@
+package llmconnector
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// ChatHandler performs a chat call with fully resolved options.
+type ChatHandler func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error)
+
+// EmbedHandler performs an embed call with fully resolved options.
+type EmbedHandler func(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error)
+
+// ChatMiddleware wraps a ChatHandler with cross-cutting behavior.
+type ChatMiddleware func(next ChatHandler) ChatHandler
+
+// EmbedMiddleware wraps an EmbedHandler with cross-cutting behavior.
+type EmbedMiddleware func(next EmbedHandler) EmbedHandler
+
+// chainChat wraps handler with middlewares so that the first middleware is the outermost.
+func chainChat(handler ChatHandler, middlewares []ChatMiddleware) ChatHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// chainEmbed wraps handler with middlewares so that the first middleware is the outermost.
+func chainEmbed(handler EmbedHandler, middlewares []EmbedMiddleware) EmbedHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// ChatDefaults applies opts before the call for every field the caller left unset.
+func ChatDefaults(opts ...ChatOption) ChatMiddleware {
+	defaults := &ChatOptions{}
+	for _, opt := range opts {
+		opt(defaults)
+	}
+	return func(next ChatHandler) ChatHandler {
+		return func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+			merged := *options
+			if merged.Model == "" {
+				merged.Model = defaults.Model
+			}
+			if merged.Temperature == nil {
+				merged.Temperature = defaults.Temperature
+			}
+			if merged.MaxTokens == nil {
+				merged.MaxTokens = defaults.MaxTokens
+			}
+			if merged.TopP == nil {
+				merged.TopP = defaults.TopP
+			}
+			if merged.Stop == nil {
+				merged.Stop = defaults.Stop
+			}
+			return next(ctx, chatMessages, &merged)
+		}
+	}
+}
+
+// EmbedDefaults applies opts before the call for every field the caller left unset.
+func EmbedDefaults(opts ...EmbedOption) EmbedMiddleware {
+	defaults := &EmbedOptions{}
+	for _, opt := range opts {
+		opt(defaults)
+	}
+	return func(next EmbedHandler) EmbedHandler {
+		return func(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+			merged := *options
+			if merged.Model == "" {
+				merged.Model = defaults.Model
+			}
+			if merged.EmbeddingType == "" {
+				merged.EmbeddingType = defaults.EmbeddingType
+			}
+			if merged.Dimensions == 0 {
+				merged.Dimensions = defaults.Dimensions
+			}
+			if merged.EncodingFormat == "" {
+				merged.EncodingFormat = defaults.EncodingFormat
+			}
+			if !merged.Normalize {
+				merged.Normalize = defaults.Normalize
+			}
+			if merged.OutputType == "" {
+				merged.OutputType = defaults.OutputType
+			}
+			return next(ctx, texts, &merged)
+		}
+	}
+}
+
+// ChatTimeout bounds every chat call by timeout.
+func ChatTimeout(timeout time.Duration) ChatMiddleware {
+	return func(next ChatHandler) ChatHandler {
+		return func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, chatMessages, options)
+		}
+	}
+}
+
+// EmbedTimeout bounds every embed call by timeout.
+func EmbedTimeout(timeout time.Duration) EmbedMiddleware {
+	return func(next EmbedHandler) EmbedHandler {
+		return func(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, texts, options)
+		}
+	}
+}
+
+// RedactChatMessages rewrites the content of every message with redact before it leaves the process.
+// The caller's slice is left untouched.
+func RedactChatMessages(redact func(content string) string) ChatMiddleware {
+	return func(next ChatHandler) ChatHandler {
+		return func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+			redacted := make([]ChatMessage, len(chatMessages))
+			for i, message := range chatMessages {
+				message.Content = redact(message.Content)
+				redacted[i] = message
+			}
+			return next(ctx, redacted, options)
+		}
+	}
+}
+
+// Redactor returns a redact function for RedactChatMessages that replaces every occurrence of secrets with "[REDACTED]".
+func Redactor(secrets ...string) func(string) string {
+	pairs := make([]string, 0, len(secrets)*2)
+	for _, secret := range secrets {
+		if secret != "" {
+			pairs = append(pairs, secret, "[REDACTED]")
+		}
+	}
+	replacer := strings.NewReplacer(pairs...)
+	return replacer.Replace
+}
+
+// ChatObserver receives the outcome of a chat call.
+type ChatObserver func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions, resp ChatResponse, err error, elapsed time.Duration)
+
+// EmbedObserver receives the outcome of an embed call.
+type EmbedObserver func(ctx context.Context, texts []string, options *EmbedOptions, resp EmbedResponse, err error, elapsed time.Duration)
+
+// ObserveChat calls observer after every chat call, e.g. for logging or metrics.
+func ObserveChat(observer ChatObserver) ChatMiddleware {
+	return func(next ChatHandler) ChatHandler {
+		return func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+			start := time.Now()
+			resp, err := next(ctx, chatMessages, options)
+			observer(ctx, chatMessages, options, resp, err, time.Since(start))
+			return resp, err
+		}
+	}
+}
+
+// ObserveEmbed calls observer after every embed call, e.g. for logging or metrics.
+func ObserveEmbed(observer EmbedObserver) EmbedMiddleware {
+	return func(next EmbedHandler) EmbedHandler {
+		return func(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+			start := time.Now()
+			resp, err := next(ctx, texts, options)
+			observer(ctx, texts, options, resp, err, time.Since(start))
+			return resp, err
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package llmconnector
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type recordingChatStrategy struct {
+	messages []ChatMessage
+	options  *ChatOptions
+	ctx      context.Context
+	err      error
+}
+
+func (s *recordingChatStrategy) Chat(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+	s.ctx = ctx
+	s.messages = chatMessages
+	s.options = options
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &MockChatResponse{Content: "Mock response"}, nil
+}
+
+type recordingEmbedStrategy struct {
+	options *EmbedOptions
+}
+
+func (s *recordingEmbedStrategy) Embed(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+	s.options = options
+	return &MockEmbedResponse{Embeddings: [][]float32{{0.1, 0.2, 0.3}}}, nil
+}
+
+func TestModelContext_UseChat_Order(t *testing.T) {
+	var calls []string
+	trace := func(name string) ChatMiddleware {
+		return func(next ChatHandler) ChatHandler {
+			return func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+				calls = append(calls, name+" before")
+				resp, err := next(ctx, chatMessages, options)
+				calls = append(calls, name+" after")
+				return resp, err
+			}
+		}
+	}
+
+	ctx := NewModelContext()
+	ctx.SetChatStrategy(&recordingChatStrategy{})
+	ctx.UseChat(trace("first"), trace("second"))
+
+	_, err := ctx.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first before", "second before", "second after", "first after"}, calls)
+}
+
+func TestChatDefaults(t *testing.T) {
+	strategy := &recordingChatStrategy{}
+	ctx := NewModelContext()
+	ctx.SetChatStrategy(strategy)
+	ctx.UseChat(ChatDefaults(WithChatModel("default-model"), WithTemperature(0.2), WithMaxTokens(100)))
+
+	_, err := ctx.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}}, WithTemperature(0.9))
+	require.NoError(t, err)
+	assert.Equal(t, "default-model", strategy.options.Model)
+	assert.Equal(t, 0.9, *strategy.options.Temperature)
+	assert.Equal(t, 100, *strategy.options.MaxTokens)
+}
+
+func TestEmbedDefaults(t *testing.T) {
+	strategy := &recordingEmbedStrategy{}
+	ctx := NewModelContext()
+	ctx.SetEmbedStrategy(strategy)
+	ctx.UseEmbed(EmbedDefaults(WithEmbedModel("default-model"), WithEmbeddingType("document")))
+
+	_, err := ctx.Embed(context.Background(), []string{"text1"}, WithEmbedModel("test-model"))
+	require.NoError(t, err)
+	assert.Equal(t, "test-model", strategy.options.Model)
+	assert.Equal(t, "document", strategy.options.EmbeddingType)
+}
+
+func TestChatTimeout(t *testing.T) {
+	strategy := &recordingChatStrategy{}
+	ctx := NewModelContext()
+	ctx.SetChatStrategy(strategy)
+	ctx.UseChat(ChatTimeout(time.Minute))
+
+	_, err := ctx.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}})
+	require.NoError(t, err)
+	_, ok := strategy.ctx.Deadline()
+	assert.True(t, ok)
+}
+
+func TestRedactChatMessages(t *testing.T) {
+	strategy := &recordingChatStrategy{}
+	ctx := NewModelContext()
+	ctx.SetChatStrategy(strategy)
+	ctx.UseChat(RedactChatMessages(Redactor("4111-1111")))
+
+	messages := []ChatMessage{{Role: "user", Content: "my card is 4111-1111"}}
+	_, err := ctx.Chat(context.Background(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, "my card is [REDACTED]", strategy.messages[0].Content)
+	assert.Equal(t, "my card is 4111-1111", messages[0].Content)
+}
+
+func TestObserveChat(t *testing.T) {
+	strategy := &recordingChatStrategy{err: errors.New("boom")}
+	ctx := NewModelContext()
+	ctx.SetChatStrategy(strategy)
+
+	var observedErr error
+	var observedModel string
+	ctx.UseChat(ObserveChat(func(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions, resp ChatResponse, err error, elapsed time.Duration) {
+		observedErr = err
+		observedModel = options.Model
+	}))
+
+	_, err := ctx.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}}, WithChatModel("test-model"))
+	require.Error(t, err)
+	assert.Equal(t, err, observedErr)
+	assert.Equal(t, "test-model", observedModel)
+}
+
+func TestObserveEmbed(t *testing.T) {
+	ctx := NewModelContext()
+	ctx.SetEmbedStrategy(&recordingEmbedStrategy{})
+
+	var observed EmbedResponse
+	ctx.UseEmbed(ObserveEmbed(func(ctx context.Context, texts []string, options *EmbedOptions, resp EmbedResponse, err error, elapsed time.Duration) {
+		observed = resp
+	}))
+
+	resp, err := ctx.Embed(context.Background(), []string{"text1"})
+	require.NoError(t, err)
+	assert.Equal(t, resp, observed)
+}
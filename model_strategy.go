@@ -3,6 +3,7 @@ package llmconnector
 import (
 	"context"
 	"fmt"
+	"sync"
 )
 
 type ChatStrategy interface {
@@ -13,10 +14,63 @@ type EmbedStrategy interface {
 	Embed(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error)
 }
 
-// ModelContext supports separate strategies for chat and embed
+// ProviderNamer is implemented by strategies that can report which provider they talk to.
+type ProviderNamer interface {
+	ProviderName() string
+}
+
+// DefaultChatModeler is implemented by chat strategies that use a default model for calls that do not set one.
+type DefaultChatModeler interface {
+	DefaultChatModel() string
+}
+
+// DefaultEmbedModeler is implemented by embed strategies that use a default model for calls that do not set one.
+type DefaultEmbedModeler interface {
+	DefaultEmbedModel() string
+}
+
+type providerContextKey struct{}
+
+// ProviderFromContext returns the provider name of the strategy serving the current ModelContext call.
+// It is empty outside of a call or when the strategy does not implement ProviderNamer.
+func ProviderFromContext(ctx context.Context) string {
+	provider, _ := ctx.Value(providerContextKey{}).(string)
+	return provider
+}
+
+func withProvider(ctx context.Context, strategy interface{}) context.Context {
+	if namer, ok := strategy.(ProviderNamer); ok {
+		return context.WithValue(ctx, providerContextKey{}, namer.ProviderName())
+	}
+	return ctx
+}
+
+type chatStrategyContextKey struct{}
+
+type embedStrategyContextKey struct{}
+
+// ContextWithChatStrategy returns a context whose ModelContext.Chat calls use strategy,
+// overriding the strategies configured on the ModelContext.
+func ContextWithChatStrategy(ctx context.Context, strategy ChatStrategy) context.Context {
+	return context.WithValue(ctx, chatStrategyContextKey{}, strategy)
+}
+
+// ContextWithEmbedStrategy returns a context whose ModelContext.Embed calls use strategy,
+// overriding the strategies configured on the ModelContext.
+func ContextWithEmbedStrategy(ctx context.Context, strategy EmbedStrategy) context.Context {
+	return context.WithValue(ctx, embedStrategyContextKey{}, strategy)
+}
+
+// ModelContext supports separate strategies for chat and embed.
+// It is safe for concurrent use, including reconfiguration while calls are in flight;
+// a call uses the configuration present when it starts.
 type ModelContext struct {
-	chatStrategy  ChatStrategy
-	embedStrategy EmbedStrategy
+	mu               sync.RWMutex
+	chatStrategy     ChatStrategy
+	embedStrategy    EmbedStrategy
+	chatMiddlewares  []ChatMiddleware
+	embedMiddlewares []EmbedMiddleware
+	registry         *Registry
 }
 
 func NewModelContext() *ModelContext {
@@ -24,31 +78,133 @@ func NewModelContext() *ModelContext {
 }
 
 func (c *ModelContext) SetChatStrategy(strategy ChatStrategy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.chatStrategy = strategy
 }
 
 func (c *ModelContext) SetEmbedStrategy(strategy EmbedStrategy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.embedStrategy = strategy
 }
 
+// SetRegistry routes calls by model address: WithChatModel("alibaba:qwen-max") uses the "alibaba" strategy
+// of registry with model "qwen-max". Models without a registered provider prefix use the strategy set with
+// SetChatStrategy or SetEmbedStrategy, or else the default provider of registry.
+func (c *ModelContext) SetRegistry(registry *Registry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.registry = registry
+}
+
+// UseChat appends middlewares to the chat chain. The first registered middleware is the outermost.
+func (c *ModelContext) UseChat(middlewares ...ChatMiddleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Copy so that calls in flight keep iterating over their own chain.
+	c.chatMiddlewares = append(append([]ChatMiddleware(nil), c.chatMiddlewares...), middlewares...)
+}
+
+// UseEmbed appends middlewares to the embed chain. The first registered middleware is the outermost.
+func (c *ModelContext) UseEmbed(middlewares ...EmbedMiddleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.embedMiddlewares = append(append([]EmbedMiddleware(nil), c.embedMiddlewares...), middlewares...)
+}
+
+// Chat runs a chat call. The strategy is, in order of precedence: the one given with WithChatStrategy,
+// the one in ctx (see ContextWithChatStrategy), the registry provider of a "provider:model" address,
+// the one set with SetChatStrategy, and the default provider of the registry.
 func (c *ModelContext) Chat(ctx context.Context, chatMessages []ChatMessage, opts ...ChatOption) (ChatResponse, error) {
-	if c.chatStrategy == nil {
-		return nil, fmt.Errorf("chat strategy not set")
-	}
 	options := &ChatOptions{}
 	for _, opt := range opts {
 		opt(options)
 	}
-	return c.chatStrategy.Chat(ctx, chatMessages, options)
+
+	c.mu.RLock()
+	strategy, registry, middlewares := c.chatStrategy, c.registry, c.chatMiddlewares
+	c.mu.RUnlock()
+
+	strategy, err := resolveChat(ctx, options, strategy, registry)
+	if err != nil {
+		return nil, err
+	}
+	options.strategy = nil
+	ctx = withProvider(ctx, strategy)
+	return chainChat(strategy.Chat, middlewares)(ctx, chatMessages, options)
 }
 
-func (c *ModelContext) Embed(ctx context.Context, texts []string, opts ...EmbedOption) (EmbedResponse, error) {
-	if c.embedStrategy == nil {
-		return nil, fmt.Errorf("embedding strategy not set")
+// resolveChat picks the strategy of a call and strips a provider prefix from options.Model.
+// Calls that set no model get the default model of the strategy, so middlewares see the model that is sent.
+func resolveChat(ctx context.Context, options *ChatOptions, strategy ChatStrategy, registry *Registry) (ChatStrategy, error) {
+	resolved := options.strategy
+	if override, ok := ctx.Value(chatStrategyContextKey{}).(ChatStrategy); resolved == nil && ok && override != nil {
+		resolved = override
+	}
+	if resolved == nil && registry != nil && (strategy == nil || registry.routes(options.Model)) {
+		var err error
+		resolved, options.Model, err = registry.ResolveChat(options.Model)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resolved == nil {
+		if strategy == nil {
+			return nil, fmt.Errorf("chat strategy not set")
+		}
+		resolved = strategy
 	}
+	if defaulter, ok := resolved.(DefaultChatModeler); ok && options.Model == "" {
+		options.Model = defaulter.DefaultChatModel()
+	}
+	return resolved, nil
+}
+
+// Embed runs an embed call. The strategy is, in order of precedence: the one given with WithEmbedStrategy,
+// the one in ctx (see ContextWithEmbedStrategy), the registry provider of a "provider:model" address,
+// the one set with SetEmbedStrategy, and the default provider of the registry.
+func (c *ModelContext) Embed(ctx context.Context, texts []string, opts ...EmbedOption) (EmbedResponse, error) {
 	options := &EmbedOptions{}
 	for _, opt := range opts {
 		opt(options)
 	}
-	return c.embedStrategy.Embed(ctx, texts, options)
+
+	c.mu.RLock()
+	strategy, registry, middlewares := c.embedStrategy, c.registry, c.embedMiddlewares
+	c.mu.RUnlock()
+
+	strategy, err := resolveEmbed(ctx, options, strategy, registry)
+	if err != nil {
+		return nil, err
+	}
+	options.strategy = nil
+	ctx = withProvider(ctx, strategy)
+	return chainEmbed(strategy.Embed, middlewares)(ctx, texts, options)
+}
+
+// resolveEmbed picks the strategy of a call and strips a provider prefix from options.Model.
+// Calls that set no model get the default model of the strategy, so middlewares see the model that is sent.
+func resolveEmbed(ctx context.Context, options *EmbedOptions, strategy EmbedStrategy, registry *Registry) (EmbedStrategy, error) {
+	resolved := options.strategy
+	if override, ok := ctx.Value(embedStrategyContextKey{}).(EmbedStrategy); resolved == nil && ok && override != nil {
+		resolved = override
+	}
+	if resolved == nil && registry != nil && (strategy == nil || registry.routes(options.Model)) {
+		var err error
+		resolved, options.Model, err = registry.ResolveEmbed(options.Model)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resolved == nil {
+		if strategy == nil {
+			return nil, fmt.Errorf("embedding strategy not set")
+		}
+		resolved = strategy
+	}
+	if defaulter, ok := resolved.(DefaultEmbedModeler); ok && options.Model == "" {
+		options.Model = defaulter.DefaultEmbedModel()
+	}
+	return resolved, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"sync"
 	"testing"
 )
 
@@ -68,3 +69,62 @@ type MockEmbedResponse struct {
 func (r *MockEmbedResponse) GetEmbeddings() [][]float32 {
 	return r.Embeddings
 }
+
+func TestModelContext_StrategyOverride(t *testing.T) {
+	ctx := NewModelContext()
+	ctx.SetChatStrategy(&MockChatStrategy{})
+	ctx.SetEmbedStrategy(&MockEmbedStrategy{})
+	messages := []ChatMessage{{Role: "user", Content: "Hello"}}
+
+	option := &namedChatStrategy{name: "option"}
+	resp, err := ctx.Chat(context.Background(), messages, WithChatStrategy(option))
+	require.NoError(t, err)
+	assert.Equal(t, "option", resp.GetContent())
+
+	fromContext := &namedChatStrategy{name: "context"}
+	callCtx := ContextWithChatStrategy(context.Background(), fromContext)
+	resp, err = ctx.Chat(callCtx, messages)
+	require.NoError(t, err)
+	assert.Equal(t, "context", resp.GetContent())
+
+	resp, err = ctx.Chat(callCtx, messages, WithChatStrategy(option))
+	require.NoError(t, err)
+	assert.Equal(t, "option", resp.GetContent(), "the option takes precedence over the context")
+
+	resp, err = ctx.Chat(context.Background(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, "Mock response", resp.GetContent())
+
+	embed := &MockEmbedStrategy{}
+	_, err = NewModelContext().Embed(ContextWithEmbedStrategy(context.Background(), embed), []string{"text1"})
+	require.NoError(t, err)
+	_, err = NewModelContext().Embed(context.Background(), []string{"text1"}, WithEmbedStrategy(embed))
+	require.NoError(t, err)
+}
+
+func TestModelContext_ConcurrentReconfiguration(t *testing.T) {
+	ctx := NewModelContext()
+	ctx.SetChatStrategy(&MockChatStrategy{})
+	messages := []ChatMessage{{Role: "user", Content: "Hello"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_, err := ctx.Chat(context.Background(), messages)
+				assert.NoError(t, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				ctx.SetChatStrategy(&MockChatStrategy{})
+				ctx.UseChat(ChatDefaults(WithChatModel("test-model")))
+				ctx.SetRegistry(nil)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -11,15 +11,56 @@ type ChatOptions struct {
 	MaxTokens   *int     `json:"max_tokens,omitempty"`
 	TopP        *float64 `json:"top_p,omitempty"`
 	Stop        []string `json:"stop,omitempty"`
+
+	// StreamHandler, when set, makes the strategy stream the completion and call it with every content delta.
+	// The returned ChatResponse still carries the full content.
+	StreamHandler StreamHandler `json:"-"`
+
+	// strategy overrides the strategy of a ModelContext call, see WithChatStrategy.
+	strategy ChatStrategy
 	// TODO: add more options
 }
 
+// withDefaultModel returns options with Model set to model when it is empty. options itself is not changed.
+func (o *ChatOptions) withDefaultModel(model string) *ChatOptions {
+	if o.Model != "" || model == "" {
+		return o
+	}
+	withModel := *o
+	withModel.Model = model
+	return &withModel
+}
+
+// StreamHandler receives content deltas of a streamed chat completion. Returning an error aborts the stream.
+type StreamHandler func(delta string) error
+
 type EmbedOptions struct {
 	Model         string `json:"model"`
 	EmbeddingType string `json:"embedding_type,omitempty"`
+	// Dimensions is the size of the returned vectors, 0 for the model's default.
+	Dimensions int `json:"dimensions,omitempty"`
+	// EncodingFormat is the wire format of the vectors, EncodingFormatFloat when empty.
+	EncodingFormat string `json:"encoding_format,omitempty"`
+	// Normalize scales every vector to unit length.
+	Normalize bool `json:"normalize,omitempty"`
+	// OutputType selects dense vectors, sparse vectors or both, OutputTypeDense when empty.
+	OutputType string `json:"output_type,omitempty"`
+
+	// strategy overrides the strategy of a ModelContext call, see WithEmbedStrategy.
+	strategy EmbedStrategy
 	// TODO: add more options
 }
 
+// withDefaultModel returns options with Model set to model when it is empty. options itself is not changed.
+func (o *EmbedOptions) withDefaultModel(model string) *EmbedOptions {
+	if o.Model != "" || model == "" {
+		return o
+	}
+	withModel := *o
+	withModel.Model = model
+	return &withModel
+}
+
 type ChatResponse interface {
 	GetContent() string
 }
@@ -27,3 +68,51 @@ type ChatResponse interface {
 type EmbedResponse interface {
 	GetEmbeddings() [][]float32
 }
+
+// Usage is the token consumption reported by a provider for a single call.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// UsageReporter is implemented by chat and embed responses that carry token usage.
+type UsageReporter interface {
+	GetUsage() Usage
+}
+
+// FinishReasonReporter is implemented by chat responses that carry the reasons generation stopped.
+type FinishReasonReporter interface {
+	GetFinishReasons() []string
+}
+
+// UsageOf returns the token usage of a chat or embed response, if the response reports it.
+func UsageOf(resp interface{}) (Usage, bool) {
+	if reporter, ok := resp.(UsageReporter); ok {
+		return reporter.GetUsage(), true
+	}
+	return Usage{}, false
+}
+
+// FinishReasonsOf returns the finish reasons of a chat response, if the response reports them.
+func FinishReasonsOf(resp ChatResponse) []string {
+	if reporter, ok := resp.(FinishReasonReporter); ok {
+		return reporter.GetFinishReasons()
+	}
+	return nil
+}
+
+// orderByIndex places embeddings[i] at position indexes[i]. The original order is kept
+// when the indexes are not a permutation of the positions, e.g. when the provider omits them.
+func orderByIndex[V any](indexes []int, embeddings []V) []V {
+	ordered := make([]V, len(embeddings))
+	placed := make([]bool, len(embeddings))
+	for i, index := range indexes {
+		if index < 0 || index >= len(ordered) || placed[index] {
+			return embeddings
+		}
+		ordered[index] = embeddings[i]
+		placed[index] = true
+	}
+	return ordered
+}
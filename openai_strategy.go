@@ -5,16 +5,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/simp-lee/gohttpclient"
+	"strings"
 )
 
 type OpenAIStrategy struct {
 	chatClient  *gohttpclient.Client
 	embedClient *gohttpclient.Client
 	config      *Config
+	logger      *requestLogger
+}
+
+func init() {
+	RegisterProvider("openai", func(config Config) (interface{}, error) {
+		return NewOpenAIStrategy(config)
+	})
 }
 
 func NewOpenAIStrategy(config Config) (*OpenAIStrategy, error) {
-	if config.APIKey == "" {
+	if !config.hasCredentials() {
 		return nil, fmt.Errorf("OpenAI API key is required")
 	}
 
@@ -23,7 +31,7 @@ func NewOpenAIStrategy(config Config) (*OpenAIStrategy, error) {
 		config.ChatURL = "https://api.openai.com/v1/chat/completions"
 	}
 	if config.EmbedURL == "" {
-		config.EmbedURL = "https://api.openai.com/v1/engines/text-similarity/embeddings"
+		config.EmbedURL = "https://api.openai.com/v1/embeddings"
 	}
 
 	// Use default common config if not set
@@ -31,14 +39,16 @@ func NewOpenAIStrategy(config Config) (*OpenAIStrategy, error) {
 		config.CommonConfig = DefaultCommonConfig()
 	}
 
+	logger := newRequestLogger(config.CommonConfig, "openai", config.APIKey)
+
 	// Prepare the chat client
-	chatClient, err := createClient(config.CommonConfig, config.APIKey)
+	chatClient, err := createClient(config, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OpenAI chat client: %w", err)
 	}
 
 	// Prepare the embedding client
-	embedClient, err := createClient(config.CommonConfig, config.APIKey)
+	embedClient, err := createClient(config, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OpenAI embedding client: %w", err)
 	}
@@ -47,10 +57,27 @@ func NewOpenAIStrategy(config Config) (*OpenAIStrategy, error) {
 		chatClient:  chatClient,
 		embedClient: embedClient,
 		config:      &config,
+		logger:      logger,
 	}, nil
 }
 
+// ProviderName returns the provider identifier used in telemetry.
+func (s *OpenAIStrategy) ProviderName() string {
+	return "openai"
+}
+
+// DefaultChatModel returns Config.ChatModel, the model of chat calls that do not set one.
+func (s *OpenAIStrategy) DefaultChatModel() string {
+	return s.config.ChatModel
+}
+
+// DefaultEmbedModel returns Config.EmbedModel, the model of embed calls that do not set one.
+func (s *OpenAIStrategy) DefaultEmbedModel() string {
+	return s.config.EmbedModel
+}
+
 func (s *OpenAIStrategy) Chat(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+	options = options.withDefaultModel(s.config.ChatModel)
 	request := map[string]interface{}{
 		"model":    options.Model,
 		"messages": chatMessages,
@@ -67,26 +94,100 @@ func (s *OpenAIStrategy) Chat(ctx context.Context, chatMessages []ChatMessage, o
 	if options.Stop != nil {
 		request["stop"] = options.Stop
 	}
+	if options.StreamHandler != nil {
+		return s.chatStream(ctx, request, options.Model, options.StreamHandler)
+	}
 
-	resp, err := s.chatClient.Post(ctx, s.config.ChatURL, request)
+	start := s.logger.start(ctx, "chat", options.Model, request)
+	resp, err := post(ctx, s.chatClient, s.config, s.config.ChatURL, request)
 	if err != nil {
+		s.logger.end(ctx, "chat", options.Model, start, nil, nil, err)
 		return nil, fmt.Errorf("OpenAI chat request failed: %w", err)
 	}
 
 	var openAIResp OpenAIChatResponse
 	if err := json.Unmarshal(resp, &openAIResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal OpenAI chat response: %w", err)
+		err = fmt.Errorf("failed to unmarshal OpenAI chat response: %w", err)
+		s.logger.end(ctx, "chat", options.Model, start, resp, nil, err)
+		return nil, err
 	}
+	s.logger.end(ctx, "chat", options.Model, start, resp, &openAIResp, nil)
 
 	return &openAIResp, nil
 }
 
+func (s *OpenAIStrategy) chatStream(ctx context.Context, request map[string]interface{}, model string, handler StreamHandler) (ChatResponse, error) {
+	request["stream"] = true
+	request["stream_options"] = map[string]interface{}{"include_usage": true}
+
+	var content strings.Builder
+	var finishReason string
+	result := &OpenAIChatResponse{}
+	start := s.logger.start(ctx, "chat", model, request)
+	err := postStream(ctx, s.chatClient, s.config, s.config.ChatURL, s.config.requestHeaders(), request, func(data []byte) error {
+		var chunk openAIChatChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal OpenAI chat chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			result.Usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		if chunk.Choices[0].FinishReason != "" {
+			finishReason = chunk.Choices[0].FinishReason
+		}
+		delta := chunk.Choices[0].Delta.Content
+		if delta == "" {
+			return nil
+		}
+		content.WriteString(delta)
+		return handler(delta)
+	})
+	if err != nil {
+		s.logger.end(ctx, "chat", model, start, nil, nil, err)
+		return nil, fmt.Errorf("OpenAI chat stream failed: %w", err)
+	}
+
+	result.Choices = make([]struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	}, 1)
+	result.Choices[0].Message.Content = content.String()
+	result.Choices[0].FinishReason = finishReason
+	s.logger.end(ctx, "chat", model, start, nil, result, nil)
+	return result, nil
+}
+
+type openAIChatChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
 type OpenAIChatResponse struct {
 	Choices []struct {
 		Message struct {
 			Content string `json:"content"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
 }
 
 func (r *OpenAIChatResponse) GetContent() string {
@@ -96,42 +197,116 @@ func (r *OpenAIChatResponse) GetContent() string {
 	return ""
 }
 
+func (r *OpenAIChatResponse) GetUsage() Usage {
+	return Usage{
+		PromptTokens:     r.Usage.PromptTokens,
+		CompletionTokens: r.Usage.CompletionTokens,
+		TotalTokens:      r.Usage.TotalTokens,
+	}
+}
+
+func (r *OpenAIChatResponse) GetFinishReasons() []string {
+	reasons := make([]string, 0, len(r.Choices))
+	for _, choice := range r.Choices {
+		if choice.FinishReason != "" {
+			reasons = append(reasons, choice.FinishReason)
+		}
+	}
+	return reasons
+}
+
 func (s *OpenAIStrategy) Embed(ctx context.Context, texts []string, options *EmbedOptions) (EmbedResponse, error) {
+	if len(texts) == 0 {
+		return &OpenAIEmbedResponse{}, nil
+	}
+	options = options.withDefaultModel(s.config.EmbedModel)
+
+	// OpenAI has no text types, so options.EmbeddingType is not sent.
 	request := map[string]interface{}{
 		"model": options.Model,
-		"input": map[string]interface{}{
-			"texts": texts,
-		},
+		"input": texts,
 	}
-	if options.EmbeddingType != "" {
-		request["params"] = map[string]string{
-			"text_type": options.EmbeddingType,
-		}
+	if nativeDimensions("openai", options) {
+		request["dimensions"] = options.Dimensions
+	}
+	if options.EncodingFormat != "" {
+		request["encoding_format"] = options.EncodingFormat
 	}
 
-	resp, err := s.embedClient.Post(ctx, s.config.EmbedURL, request)
+	start := s.logger.start(ctx, "embed", options.Model, request)
+	resp, err := post(ctx, s.embedClient, s.config, s.config.EmbedURL, request)
 	if err != nil {
+		s.logger.end(ctx, "embed", options.Model, start, nil, nil, err)
 		return nil, fmt.Errorf("OpenAI embed request failed: %w", err)
 	}
 
 	var openAIResp OpenAIEmbedResponse
-	if err := json.Unmarshal(resp, &openAIResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal OpenAI embed response: %w", err)
+	if err := openAIResp.unmarshal(resp); err != nil {
+		err = fmt.Errorf("failed to unmarshal OpenAI embed response: %w", err)
+		s.logger.end(ctx, "embed", options.Model, start, resp, nil, err)
+		return nil, err
+	}
+	for i := range openAIResp.Data {
+		openAIResp.Data[i].Embedding = shapeEmbedding(openAIResp.Data[i].Embedding, options)
 	}
+	s.logger.end(ctx, "embed", options.Model, start, resp, &openAIResp, nil)
 
 	return &openAIResp, nil
 }
 
 type OpenAIEmbedResponse struct {
 	Data []struct {
+		Index     int       `json:"index"`
 		Embedding []float32 `json:"embedding"`
 	} `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
 }
 
+// unmarshal decodes data, whose vectors are arrays of numbers or base64 strings depending on the encoding format.
+func (r *OpenAIEmbedResponse) unmarshal(data []byte) error {
+	var wire struct {
+		Data []struct {
+			Index     int             `json:"index"`
+			Embedding EmbeddingVector `json:"embedding"`
+		} `json:"data"`
+		Usage json.RawMessage `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	if len(wire.Usage) > 0 {
+		if err := json.Unmarshal(wire.Usage, &r.Usage); err != nil {
+			return err
+		}
+	}
+	r.Data = make([]struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	}, len(wire.Data))
+	for i, data := range wire.Data {
+		r.Data[i].Index = data.Index
+		r.Data[i].Embedding = data.Embedding
+	}
+	return nil
+}
+
+// GetEmbeddings returns the embeddings in input order.
 func (r *OpenAIEmbedResponse) GetEmbeddings() [][]float32 {
+	indexes := make([]int, len(r.Data))
 	embeddings := make([][]float32, len(r.Data))
 	for i, data := range r.Data {
+		indexes[i] = data.Index
 		embeddings[i] = data.Embedding
 	}
-	return embeddings
+	return orderByIndex(indexes, embeddings)
+}
+
+func (r *OpenAIEmbedResponse) GetUsage() Usage {
+	return Usage{
+		PromptTokens: r.Usage.PromptTokens,
+		TotalTokens:  r.Usage.TotalTokens,
+	}
 }
@@ -3,10 +3,15 @@ package llmconnector
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 )
 
@@ -72,7 +77,7 @@ func TestOpenAIStrategy_Embed(t *testing.T) {
 		require.NoError(t, err)
 
 		assert.Equal(t, "test-model", request["model"])
-		assert.Equal(t, []interface{}{"text1", "text2"}, request["input"].(map[string]interface{})["texts"])
+		assert.Equal(t, []interface{}{"text1", "text2"}, request["input"])
 
 		response := `{"data":[{"embedding":[0.1,0.2,0.3]}]}`
 		w.Write([]byte(response))
@@ -102,12 +107,39 @@ func TestOpenAIStrategy_Embed(t *testing.T) {
 	assert.Equal(t, [][]float32{{0.1, 0.2, 0.3}}, embedResp.GetEmbeddings())
 }
 
+func TestOpenAIStrategy_DefaultURLs(t *testing.T) {
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.openai.com/v1/chat/completions", strategy.config.ChatURL)
+	assert.Equal(t, "https://api.openai.com/v1/embeddings", strategy.config.EmbedURL)
+}
+
+func TestOpenAIStrategy_EmbedRequestSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		// OpenAI has no text types, so the embedding type is not sent.
+		assert.JSONEq(t, `{"model":"test-model","input":["text1","text2"]}`, string(body))
+
+		w.Write([]byte(`{"data":[{"index":0,"embedding":[0.1]},{"index":1,"embedding":[0.2]}]}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key", EmbedURL: server.URL})
+	require.NoError(t, err)
+
+	resp, err := strategy.Embed(context.Background(), []string{"text1", "text2"}, &EmbedOptions{Model: "test-model", EmbeddingType: "document"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1}, {0.2}}, resp.GetEmbeddings())
+}
+
 func TestOpenAIChatResponse_GetContent(t *testing.T) {
 	resp := &OpenAIChatResponse{
 		Choices: []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		}{
 			{Message: struct {
 				Content string `json:"content"`
@@ -127,6 +159,7 @@ func TestOpenAIChatResponse_GetContent_Empty(t *testing.T) {
 func TestOpenAIEmbedResponse_GetEmbeddings(t *testing.T) {
 	resp := &OpenAIEmbedResponse{
 		Data: []struct {
+			Index     int       `json:"index"`
 			Embedding []float32 `json:"embedding"`
 		}{
 			{Embedding: []float32{0.1, 0.2, 0.3}},
@@ -142,3 +175,257 @@ func TestOpenAIEmbedResponse_GetEmbeddings_Empty(t *testing.T) {
 
 	assert.Equal(t, [][]float32{}, resp.GetEmbeddings())
 }
+
+func TestOpenAIChatResponse_Usage(t *testing.T) {
+	var resp OpenAIChatResponse
+	err := json.Unmarshal([]byte(`{"choices":[{"message":{"content":"Hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":2,"total_tokens":11}}`), &resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, Usage{PromptTokens: 9, CompletionTokens: 2, TotalTokens: 11}, resp.GetUsage())
+	assert.Equal(t, []string{"stop"}, resp.GetFinishReasons())
+}
+
+func TestOpenAIStrategy_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+
+		var request map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		require.NoError(t, err)
+		assert.Equal(t, true, request["stream"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\" there\"},\"finish_reason\":\"stop\"}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2,\"total_tokens\":7}}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key", ChatURL: server.URL})
+	require.NoError(t, err)
+
+	var deltas []string
+	options := &ChatOptions{
+		Model: "test-model",
+		StreamHandler: func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		},
+	}
+
+	resp, err := strategy.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}}, options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hi", " there"}, deltas)
+	assert.Equal(t, "Hi there", resp.GetContent())
+	assert.Equal(t, []string{"stop"}, FinishReasonsOf(resp))
+	usage, ok := UsageOf(resp)
+	require.True(t, ok)
+	assert.Equal(t, 7, usage.TotalTokens)
+}
+
+func TestOpenAIStrategy_TrackAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi there"}}]}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key", ChatURL: server.URL})
+	require.NoError(t, err)
+
+	ctx, attempts := TrackAttempts(context.Background())
+	_, err = strategy.Chat(ctx, []ChatMessage{{Role: "user", Content: "Hello"}}, &ChatOptions{Model: "test-model"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts())
+}
+
+func TestOpenAIEmbedResponse_GetEmbeddings_Order(t *testing.T) {
+	var resp OpenAIEmbedResponse
+	err := json.Unmarshal([]byte(`{"data":[{"index":1,"embedding":[0.4]},{"index":0,"embedding":[0.1]}]}`), &resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]float32{{0.1}, {0.4}}, resp.GetEmbeddings())
+}
+
+func TestOpenAIStrategy_RetryResendsBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi there"}}]}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key", ChatURL: server.URL})
+	require.NoError(t, err)
+
+	resp, err := strategy.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}}, &ChatOptions{Model: "test-model"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", resp.GetContent())
+	require.Len(t, bodies, 2)
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.Contains(t, bodies[1], "Hello")
+}
+
+func TestOpenAIStrategy_ConcurrentRetries(t *testing.T) {
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		first := !failed[string(body)]
+		failed[string(body)] = true
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data":[{"index":0,"embedding":[0.1]}]}`))
+	}))
+	defer server.Close()
+
+	commonConfig := DefaultCommonConfig()
+	commonConfig.Retries = 1
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key", EmbedURL: server.URL, CommonConfig: commonConfig})
+	require.NoError(t, err)
+
+	// Every request fails once and gets its own retry, whatever the others do.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, attempts := TrackAttempts(context.Background())
+			_, err := strategy.Embed(ctx, []string{fmt.Sprint("text", i)}, &EmbedOptions{Model: "test-model"})
+			assert.NoError(t, err)
+			assert.Equal(t, 2, attempts())
+		}(i)
+	}
+	wg.Wait()
+}
+
+type permanentTransport struct {
+	requests int
+}
+
+func (t *permanentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return nil, backoff.Permanent(errors.New("no recorded response"))
+}
+
+func TestOpenAIStrategy_PermanentTransportError(t *testing.T) {
+	transport := &permanentTransport{}
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key", Transport: transport})
+	require.NoError(t, err)
+
+	_, err = strategy.Embed(context.Background(), []string{"text1"}, &EmbedOptions{Model: "test-model"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no recorded response")
+	assert.Equal(t, 1, transport.requests)
+}
+
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestOpenAIStrategy_HTTPClientAndExtraHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "org-123", r.Header.Get("OpenAI-Organization"))
+		assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\ndata: [DONE]\n\n"))
+			return
+		}
+		if r.URL.Path == "/embed" {
+			w.Write([]byte(`{"data":[{"index":0,"embedding":[0.1]}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi there"}}]}`))
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	strategy, err := NewOpenAIStrategy(Config{
+		APIKey:       "test-api-key",
+		ChatURL:      server.URL + "/chat",
+		EmbedURL:     server.URL + "/embed",
+		HTTPClient:   &http.Client{Transport: transport},
+		ExtraHeaders: map[string]string{"openai-organization": "org-123"},
+	})
+	require.NoError(t, err)
+
+	messages := []ChatMessage{{Role: "user", Content: "Hello"}}
+	_, err = strategy.Chat(context.Background(), messages, &ChatOptions{Model: "test-model"})
+	require.NoError(t, err)
+	_, err = strategy.Chat(context.Background(), messages, &ChatOptions{Model: "test-model", StreamHandler: func(string) error { return nil }})
+	require.NoError(t, err)
+	_, err = strategy.Embed(context.Background(), []string{"text1"}, &EmbedOptions{Model: "test-model"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, transport.requests)
+}
+
+func TestOpenAIStrategy_EmbedDimensionsAndBase64(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"model":"text-embedding-3-small","input":["text"],"dimensions":256,"encoding_format":"base64"}`, string(body))
+
+		// [3, 4] as little-endian float32 values.
+		w.Write([]byte(`{"data":[{"index":0,"embedding":"AABAQAAAgEA="}],"usage":{"prompt_tokens":2,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key", EmbedURL: server.URL})
+	require.NoError(t, err)
+
+	options := &EmbedOptions{Model: "text-embedding-3-small", Dimensions: 256, EncodingFormat: EncodingFormatBase64, Normalize: true}
+	resp, err := strategy.Embed(context.Background(), []string{"text"}, options)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.6, 0.8}}, resp.GetEmbeddings())
+	assert.Equal(t, Usage{PromptTokens: 2, TotalTokens: 2}, resp.(*OpenAIEmbedResponse).GetUsage())
+}
+
+func TestOpenAIStrategy_EmbedTruncatesUnsupportedDimensions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.NotContains(t, request, "dimensions", "text-embedding-ada-002 cannot shorten vectors")
+
+		w.Write([]byte(`{"data":[{"index":0,"embedding":[3,4,12]}]}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key", EmbedURL: server.URL})
+	require.NoError(t, err)
+
+	resp, err := strategy.Embed(context.Background(), []string{"text"}, &EmbedOptions{Model: "text-embedding-ada-002", Dimensions: 2})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.6, 0.8}}, resp.GetEmbeddings())
+}
+
+func TestOpenAIStrategy_EmbedOmitsNativeDimensions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.NotContains(t, request, "dimensions", "1536 is the native size of text-embedding-ada-002")
+
+		w.Write([]byte(`{"data":[{"index":0,"embedding":[0.1,0.2,0.3]}]}`))
+	}))
+	defer server.Close()
+
+	strategy, err := NewOpenAIStrategy(Config{APIKey: "test-api-key", EmbedURL: server.URL})
+	require.NoError(t, err)
+
+	resp, err := strategy.Embed(context.Background(), []string{"text"}, &EmbedOptions{Model: "text-embedding-ada-002", Dimensions: 1536})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1, 0.2, 0.3}}, resp.GetEmbeddings())
+}
@@ -33,6 +33,20 @@ func WithStop(stop []string) ChatOption {
 	}
 }
 
+// WithStreamHandler streams the completion and calls handler with every content delta as it arrives.
+func WithStreamHandler(handler StreamHandler) ChatOption {
+	return func(c *ChatOptions) {
+		c.StreamHandler = handler
+	}
+}
+
+// WithChatStrategy makes a single ModelContext.Chat call use strategy instead of the configured one.
+func WithChatStrategy(strategy ChatStrategy) ChatOption {
+	return func(c *ChatOptions) {
+		c.strategy = strategy
+	}
+}
+
 func WithEmbedModel(model string) EmbedOption {
 	return func(e *EmbedOptions) {
 		e.Model = model
@@ -45,4 +59,42 @@ func WithEmbeddingType(textType string) EmbedOption {
 	}
 }
 
+// WithDimensions asks for vectors of the given size. Models that cannot shorten vectors natively get them
+// truncated client-side.
+func WithDimensions(dimensions int) EmbedOption {
+	return func(e *EmbedOptions) {
+		e.Dimensions = dimensions
+	}
+}
+
+// WithEncodingFormat sets how vectors are transferred, EncodingFormatFloat or EncodingFormatBase64.
+// Base64 vectors are decoded transparently.
+func WithEncodingFormat(format string) EmbedOption {
+	return func(e *EmbedOptions) {
+		e.EncodingFormat = format
+	}
+}
+
+// WithOutputType asks for dense vectors, sparse vectors or both: OutputTypeDense, OutputTypeSparse or
+// OutputTypeDenseAndSparse. Sparse vectors are read with SparseEmbeddingsOf.
+func WithOutputType(outputType string) EmbedOption {
+	return func(e *EmbedOptions) {
+		e.OutputType = outputType
+	}
+}
+
+// WithNormalize scales every vector to unit length client-side.
+func WithNormalize() EmbedOption {
+	return func(e *EmbedOptions) {
+		e.Normalize = true
+	}
+}
+
+// WithEmbedStrategy makes a single ModelContext.Embed call use strategy instead of the configured one.
+func WithEmbedStrategy(strategy EmbedStrategy) EmbedOption {
+	return func(e *EmbedOptions) {
+		e.strategy = strategy
+	}
+}
+
 // TODO: add more options
@@ -0,0 +1,213 @@
+package llmconnector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProviderFactory creates a strategy from a Config. The result implements ChatStrategy, EmbedStrategy or both.
+type ProviderFactory func(config Config) (interface{}, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]ProviderFactory)
+)
+
+// RegisterProvider makes a provider type available to configuration files and NewProvider.
+// Provider packages call it from init. It panics if factory is nil or providerType is already registered.
+func RegisterProvider(providerType string, factory ProviderFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("llmconnector: RegisterProvider factory is nil")
+	}
+	if _, dup := factories[providerType]; dup {
+		panic("llmconnector: RegisterProvider called twice for provider type " + providerType)
+	}
+	factories[providerType] = factory
+}
+
+// ProviderTypes returns the sorted names of the registered provider types.
+func ProviderTypes() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	types := make([]string, 0, len(factories))
+	for providerType := range factories {
+		types = append(types, providerType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func lookupProvider(providerType string) (ProviderFactory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	factory, ok := factories[providerType]
+	return factory, ok
+}
+
+// NewProvider creates a strategy of a registered provider type.
+func NewProvider(providerType string, config Config) (interface{}, error) {
+	factory, ok := lookupProvider(providerType)
+	if !ok {
+		return nil, fmt.Errorf("unknown provider type %q", providerType)
+	}
+	return factory(config)
+}
+
+// Registry holds chat and embed strategies by provider name and routes "provider:model" addresses to them.
+type Registry struct {
+	mu              sync.RWMutex
+	chat            map[string]ChatStrategy
+	embed           map[string]EmbedStrategy
+	defaultProvider string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		chat:  make(map[string]ChatStrategy),
+		embed: make(map[string]EmbedStrategy),
+	}
+}
+
+// Register adds strategy under name as a chat strategy, an embed strategy or both,
+// depending on the interfaces it implements. It replaces strategies registered earlier under name.
+// Names must not contain a colon, which separates the provider from the model in an address.
+func (r *Registry) Register(name string, strategy interface{}) error {
+	if name == "" || strings.Contains(name, ":") {
+		return fmt.Errorf("invalid provider name %q", name)
+	}
+	chat, isChat := strategy.(ChatStrategy)
+	embed, isEmbed := strategy.(EmbedStrategy)
+	if !isChat && !isEmbed {
+		return fmt.Errorf("strategy %q implements neither ChatStrategy nor EmbedStrategy", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if isChat {
+		r.chat[name] = chat
+	}
+	if isEmbed {
+		r.embed[name] = embed
+	}
+	return nil
+}
+
+// SetDefault sets the provider used for models without a provider prefix.
+func (r *Registry) SetDefault(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, isChat := r.chat[name]
+	_, isEmbed := r.embed[name]
+	if !isChat && !isEmbed {
+		return fmt.Errorf("provider %q is not registered", name)
+	}
+	r.defaultProvider = name
+	return nil
+}
+
+// Default returns the default provider name, empty if none is set.
+func (r *Registry) Default() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.defaultProvider
+}
+
+// ChatStrategy returns the chat strategy registered under name.
+func (r *Registry) ChatStrategy(name string) (ChatStrategy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	strategy, ok := r.chat[name]
+	return strategy, ok
+}
+
+// EmbedStrategy returns the embed strategy registered under name.
+func (r *Registry) EmbedStrategy(name string) (EmbedStrategy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	strategy, ok := r.embed[name]
+	return strategy, ok
+}
+
+// Names returns the sorted names of all registered strategies.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := make(map[string]bool, len(r.chat)+len(r.embed))
+	for name := range r.chat {
+		seen[name] = true
+	}
+	for name := range r.embed {
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitAddress splits "provider:model" when provider is registered. Other models, including model names
+// that contain colons such as OpenAI fine-tunes ("ft:gpt-4o-mini:org::id"), are returned unchanged.
+func (r *Registry) splitAddress(address string) (provider, model string, ok bool) {
+	provider, model, found := strings.Cut(address, ":")
+	if !found {
+		return "", address, false
+	}
+	_, isChat := r.chat[provider]
+	_, isEmbed := r.embed[provider]
+	if !isChat && !isEmbed {
+		return "", address, false
+	}
+	return provider, model, true
+}
+
+// routes reports whether address names a registered provider.
+func (r *Registry) routes(address string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, _, ok := r.splitAddress(address)
+	return ok
+}
+
+// ResolveChat returns the chat strategy serving address and the model name to send to it.
+// An address is "provider:model", or a plain model served by the default provider.
+func (r *Registry) ResolveChat(address string) (ChatStrategy, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, model, ok := r.splitAddress(address)
+	if !ok {
+		provider = r.defaultProvider
+	}
+	if provider == "" {
+		return nil, "", fmt.Errorf("model %q has no provider prefix and no default provider is set", address)
+	}
+	strategy, found := r.chat[provider]
+	if !found {
+		return nil, "", fmt.Errorf("provider %q has no chat strategy", provider)
+	}
+	return strategy, model, nil
+}
+
+// ResolveEmbed returns the embed strategy serving address and the model name to send to it.
+// An address is "provider:model", or a plain model served by the default provider.
+func (r *Registry) ResolveEmbed(address string) (EmbedStrategy, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, model, ok := r.splitAddress(address)
+	if !ok {
+		provider = r.defaultProvider
+	}
+	if provider == "" {
+		return nil, "", fmt.Errorf("model %q has no provider prefix and no default provider is set", address)
+	}
+	strategy, found := r.embed[provider]
+	if !found {
+		return nil, "", fmt.Errorf("provider %q has no embed strategy", provider)
+	}
+	return strategy, model, nil
+}
@@ -0,0 +1,94 @@
+package llmconnector
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type namedChatStrategy struct {
+	name   string
+	models []string
+}
+
+func (s *namedChatStrategy) Chat(ctx context.Context, chatMessages []ChatMessage, options *ChatOptions) (ChatResponse, error) {
+	s.models = append(s.models, options.Model)
+	return &MockChatResponse{Content: s.name}, nil
+}
+
+func TestRegisterProvider(t *testing.T) {
+	assert.Subset(t, ProviderTypes(), []string{"alibaba", "openai"})
+
+	strategy, err := NewProvider("openai", Config{APIKey: "test-api-key"})
+	require.NoError(t, err)
+	assert.IsType(t, &OpenAIStrategy{}, strategy)
+
+	_, err = NewProvider("unknown", Config{})
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		RegisterProvider("openai", func(config Config) (interface{}, error) { return nil, nil })
+	})
+}
+
+func TestRegistry_Resolve(t *testing.T) {
+	registry := NewRegistry()
+	openai := &namedChatStrategy{name: "openai"}
+	alibaba := &namedChatStrategy{name: "alibaba"}
+	require.NoError(t, registry.Register("openai", openai))
+	require.NoError(t, registry.Register("alibaba", alibaba))
+	assert.Error(t, registry.Register("bad:name", openai))
+	assert.Error(t, registry.Register("none", struct{}{}))
+
+	_, _, err := registry.ResolveChat("gpt-4o")
+	assert.Error(t, err, "no default provider")
+
+	strategy, model, err := registry.ResolveChat("alibaba:qwen-max")
+	require.NoError(t, err)
+	assert.Same(t, alibaba, strategy)
+	assert.Equal(t, "qwen-max", model)
+
+	assert.Error(t, registry.SetDefault("missing"))
+	require.NoError(t, registry.SetDefault("openai"))
+	strategy, model, err = registry.ResolveChat("ft:gpt-4o-mini:org::id")
+	require.NoError(t, err)
+	assert.Same(t, openai, strategy)
+	assert.Equal(t, "ft:gpt-4o-mini:org::id", model)
+
+	_, _, err = registry.ResolveEmbed("alibaba:text-embedding-v2")
+	assert.Error(t, err, "no embed strategy")
+}
+
+func TestModelContext_Registry(t *testing.T) {
+	registry := NewRegistry()
+	openai := &namedChatStrategy{name: "openai"}
+	alibaba := &namedChatStrategy{name: "alibaba"}
+	require.NoError(t, registry.Register("openai", openai))
+	require.NoError(t, registry.Register("alibaba", alibaba))
+	require.NoError(t, registry.SetDefault("openai"))
+
+	ctx := NewModelContext()
+	ctx.SetRegistry(registry)
+	messages := []ChatMessage{{Role: "user", Content: "Hello"}}
+
+	resp, err := ctx.Chat(context.Background(), messages, WithChatModel("alibaba:qwen-max"))
+	require.NoError(t, err)
+	assert.Equal(t, "alibaba", resp.GetContent())
+	assert.Equal(t, []string{"qwen-max"}, alibaba.models)
+
+	resp, err = ctx.Chat(context.Background(), messages, WithChatModel("gpt-4o"))
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.GetContent())
+
+	// An explicitly set strategy serves models without a provider prefix.
+	ctx.SetChatStrategy(&MockChatStrategy{})
+	resp, err = ctx.Chat(context.Background(), messages, WithChatModel("gpt-4o"))
+	require.NoError(t, err)
+	assert.Equal(t, "Mock response", resp.GetContent())
+
+	resp, err = ctx.Chat(context.Background(), messages, WithChatModel("openai:gpt-4o"))
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.GetContent())
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o"}, openai.models)
+}
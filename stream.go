@@ -0,0 +1,105 @@
+package llmconnector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/simp-lee/gohttpclient"
+	"io"
+	"net/http"
+	"time"
+)
+
+// postStream sends request and calls onData with the payload of every server-sent event until the stream ends.
+// Streams bypass the retry and rate limiting logic of the client since a partially consumed stream cannot be replayed.
+// The overall timeout of the client does not apply either: a stream lasts as long as the server keeps sending,
+// see Config.StreamIdleTimeout.
+func postStream(ctx context.Context, client *gohttpclient.Client, config *Config, url string, headers map[string]string, request interface{}, onData func(data []byte) error) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream request: %w", err)
+	}
+
+	idle := config.StreamIdleTimeout
+	if idle == 0 {
+		idle = client.Timeout
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	timeout := fmt.Errorf("stream received no data for %v: %w", idle, context.DeadlineExceeded)
+	// reset restarts the wait for the response headers or the next line of the stream.
+	reset := func() {}
+	if idle > 0 {
+		timer := time.AfterFunc(idle, func() { cancel(timeout) })
+		defer timer.Stop()
+		reset = func() { timer.Reset(idle) }
+	}
+	// cause reports the idle timeout instead of the cancellation it caused.
+	cause := func(err error) error {
+		if context.Cause(ctx) == timeout {
+			return timeout
+		}
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create stream request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if err := config.authorize(req); err != nil {
+		return &gohttpclient.ClientError{Op: "request interceptor", Err: err}
+	}
+	if err := countAttempt(req); err != nil {
+		return err
+	}
+
+	streamClient := client.Client
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return &gohttpclient.ClientError{Op: "do request", Err: cause(err)}
+	}
+	defer resp.Body.Close()
+	reset()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		config.invalidateOnUnauthorized(resp)
+		respBody, _ := io.ReadAll(resp.Body)
+		return &gohttpclient.ClientError{
+			Op:   "non-2xx response",
+			Err:  fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody)),
+			Code: resp.StatusCode,
+		}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		reset()
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		data := bytes.TrimSpace(line[len("data:"):])
+		if len(data) == 0 {
+			continue
+		}
+		if bytes.Equal(data, []byte("[DONE]")) {
+			return nil
+		}
+		if err := onData(data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &gohttpclient.ClientError{Op: "read stream", Err: cause(err)}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package llmconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sseServer answers with the given events, waiting delay before each of them.
+func sseServer(t *testing.T, delay time.Duration, events ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, event := range events {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(delay):
+			}
+			fmt.Fprintf(w, "%s\n\n", event)
+			flusher.Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func streamConfig(timeout, idle time.Duration) *Config {
+	config := &Config{APIKey: "test-api-key", CommonConfig: DefaultCommonConfig()}
+	config.Timeout = timeout
+	config.StreamIdleTimeout = idle
+	return config
+}
+
+func collectStream(t *testing.T, config *Config, url string) ([]string, error) {
+	client, err := createClient(*config, nil)
+	require.NoError(t, err)
+	var payloads []string
+	err = postStream(context.Background(), client, config, url, config.requestHeaders(), map[string]string{}, func(data []byte) error {
+		payloads = append(payloads, string(data))
+		return nil
+	})
+	return payloads, err
+}
+
+func TestPostStream(t *testing.T) {
+	server := sseServer(t, 0, ": comment", "event: result\ndata: one", "data:two", "data: [DONE]", "data: ignored")
+
+	payloads, err := collectStream(t, streamConfig(time.Second, 0), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, payloads)
+}
+
+func TestPostStream_OutlivesTimeout(t *testing.T) {
+	server := sseServer(t, 40*time.Millisecond, "data: 1", "data: 2", "data: 3", "data: 4", "data: 5")
+
+	// The stream takes 200ms in total, twice the timeout of whole requests, but is never idle for long.
+	payloads, err := collectStream(t, streamConfig(100*time.Millisecond, 0), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, payloads)
+}
+
+func TestPostStream_IdleTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		server func(t *testing.T) *httptest.Server
+		want   []string
+	}{
+		{"FirstByte", func(t *testing.T) *httptest.Server { return sseServer(t, time.Second, "data: late") }, nil},
+		{"BetweenEvents", func(t *testing.T) *httptest.Server {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "data: first\n\n")
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			}))
+			t.Cleanup(server.Close)
+			return server
+		}, []string{"first"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			payloads, err := collectStream(t, streamConfig(time.Minute, 50*time.Millisecond), tc.server(t).URL)
+			require.Error(t, err)
+			assert.Less(t, time.Since(start), time.Second)
+			assert.Equal(t, tc.want, payloads)
+			assert.Equal(t, ErrorClassTimeout, ClassifyError(err))
+			assert.Contains(t, err.Error(), "stream received no data")
+		})
+	}
+}
+
+func TestPostStream_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"slow down"}`))
+	}))
+	defer server.Close()
+
+	_, err := collectStream(t, streamConfig(time.Second, 0), server.URL)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, StatusCode(err))
+	assert.Contains(t, err.Error(), "slow down")
+
+	// An error of the handler aborts the stream.
+	stop := errors.New("stop")
+	config := streamConfig(time.Second, 0)
+	client, err := createClient(*config, nil)
+	require.NoError(t, err)
+	calls := 0
+	err = postStream(context.Background(), client, config, sseServer(t, 0, "data: 1", "data: 2").URL, nil, nil, func([]byte) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithStreamHandler(t *testing.T) {
+	strategy := &recordingChatStrategy{}
+	modelContext := NewModelContext()
+	modelContext.SetChatStrategy(strategy)
+
+	_, err := modelContext.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "Hello"}},
+		WithStreamHandler(func(string) error { return nil }))
+	require.NoError(t, err)
+	assert.NotNil(t, strategy.options.StreamHandler)
+}
@@ -0,0 +1,95 @@
+package textsplit
+
+import (
+	"strings"
+)
+
+// MarkdownSplitter splits a markdown document into sections at its headings, so that no chunk spans two sections.
+// Chunks carry the headings of their section. Sections longer than the chunk size are split recursively with the
+// separators of WithSeparators. Lines in fenced code blocks are never taken as headings.
+type MarkdownSplitter struct {
+	config *config
+}
+
+// NewMarkdownSplitter creates a splitter for markdown documents.
+func NewMarkdownSplitter(opts ...Option) *MarkdownSplitter {
+	return &MarkdownSplitter{config: newConfig(1000, opts)}
+}
+
+func (s *MarkdownSplitter) Split(text string) []Chunk {
+	var chunks []Chunk
+	var headings []string
+	// levels holds the level of each heading in headings.
+	var levels []int
+	sectionStart := 0
+	var fence string
+
+	flush := func(end int) {
+		chunks = s.config.split(text, span{sectionStart, end}, s.config.separators, chunks, append([]string(nil), headings...))
+	}
+	for lineStart := 0; lineStart < len(text); {
+		lineEnd := len(text)
+		if i := strings.IndexByte(text[lineStart:], '\n'); i >= 0 {
+			lineEnd = lineStart + i + 1
+		}
+		line := strings.TrimRight(text[lineStart:lineEnd], "\r\n")
+		trimmed := strings.TrimLeft(line, " ")
+
+		if marker := fenceMarker(trimmed); marker != "" && len(line)-len(trimmed) < 4 {
+			if fence == "" {
+				fence = marker
+			} else if strings.HasPrefix(marker, fence[:1]) && len(marker) >= len(fence) {
+				fence = ""
+			}
+		} else if level, title := heading(line); fence == "" && level > 0 && level <= s.config.maxHeadingLevel {
+			flush(lineStart)
+			sectionStart = lineStart
+			for len(levels) > 0 && levels[len(levels)-1] >= level {
+				headings, levels = headings[:len(headings)-1], levels[:len(levels)-1]
+			}
+			headings, levels = append(headings, title), append(levels, level)
+		}
+		lineStart = lineEnd
+	}
+	flush(len(text))
+	return chunks
+}
+
+// fenceMarker returns the ``` or ~~~ run opening line, or "" when line is not a code fence.
+func fenceMarker(line string) string {
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(line) && line[n] == c {
+			n++
+		}
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// heading returns the level and title of an ATX heading line, or level 0 when line is not a heading.
+func heading(line string) (int, string) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) >= 4 {
+		return 0, ""
+	}
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+	rest := trimmed[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, ""
+	}
+	title := strings.TrimSpace(rest)
+	// A closing sequence of #s is not part of the title.
+	if stripped := strings.TrimRight(title, "#"); stripped == "" || strings.HasSuffix(stripped, " ") {
+		title = strings.TrimSpace(stripped)
+	}
+	return level, title
+}
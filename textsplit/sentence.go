@@ -0,0 +1,75 @@
+package textsplit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// terminators end a sentence when followed by whitespace, so that "3.14" and "example.com" stay whole.
+	terminators = ".!?"
+	// cjkTerminators end a sentence wherever they appear, as CJK text has no spaces between sentences.
+	cjkTerminators = "。！？．…"
+	// closers are quotes and brackets that belong to the sentence they follow.
+	closers = "\"')]”’」』）》"
+)
+
+// SentenceSplitter splits a text into sentences and merges adjacent sentences into chunks up to the chunk size,
+// so that no chunk ends mid-sentence unless a single sentence exceeds the chunk size. Sentences end at line
+// breaks, at Latin terminators followed by whitespace and at CJK terminators.
+type SentenceSplitter struct {
+	config *config
+}
+
+// NewSentenceSplitter creates a sentence-aware splitter. Sentences longer than the chunk size are split with the
+// separators of WithSeparators.
+func NewSentenceSplitter(opts ...Option) *SentenceSplitter {
+	return &SentenceSplitter{config: newConfig(1000, opts)}
+}
+
+func (s *SentenceSplitter) Split(text string) []Chunk {
+	return s.config.mergeParts(text, sentences(text), s.config.separators, nil, nil)
+}
+
+// sentences returns the sentences of text, each with the whitespace following it, covering the text without gaps.
+func sentences(text string) []span {
+	var spans []span
+	start := 0
+	for pos := 0; pos < len(text); {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		pos += size
+		switch {
+		case r == '\n':
+		case strings.ContainsRune(terminators, r) || strings.ContainsRune(cjkTerminators, r):
+			for pos < len(text) {
+				next, size := utf8.DecodeRuneInString(text[pos:])
+				if !strings.ContainsRune(terminators+cjkTerminators+closers, next) {
+					break
+				}
+				pos += size
+			}
+			if !strings.ContainsRune(cjkTerminators, r) && pos < len(text) {
+				next, _ := utf8.DecodeRuneInString(text[pos:])
+				if !unicode.IsSpace(next) {
+					continue
+				}
+			}
+		default:
+			continue
+		}
+		for pos < len(text) {
+			next, size := utf8.DecodeRuneInString(text[pos:])
+			if !unicode.IsSpace(next) {
+				break
+			}
+			pos += size
+		}
+		spans = append(spans, span{start, pos})
+		start = pos
+	}
+	if start < len(text) {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
@@ -0,0 +1,218 @@
+// Package textsplit splits documents into chunks small enough to embed: recursively by separators, by sentences
+// (including CJK punctuation), by markdown sections or by a token budget. Every chunk records its byte offsets in the
+// source text, so embeddings map back to the text they were computed from.
+package textsplit
+
+import (
+	"github.com/simp-lee/llmconnector"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk is a part of a split text.
+type Chunk struct {
+	// Text is source[Start:End], trimmed of surrounding whitespace.
+	Text  string
+	Start int
+	End   int
+	// Headings are the markdown headings the chunk is under, outermost first. Only set by MarkdownSplitter.
+	Headings []string
+}
+
+// Splitter splits a text into chunks in source order.
+type Splitter interface {
+	Split(text string) []Chunk
+}
+
+// Texts returns the texts of chunks, in order, for passing to ModelContext.Embed.
+func Texts(chunks []Chunk) []string {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	return texts
+}
+
+// DefaultSeparators are tried in order by RecursiveSplitter: paragraphs, lines, words, then characters.
+var DefaultSeparators = []string{"\n\n", "\n", " ", ""}
+
+type config struct {
+	chunkSize       int
+	chunkOverlap    int
+	length          func(text string) int
+	separators      []string
+	maxHeadingLevel int
+}
+
+// Option configures a splitter.
+type Option func(*config)
+
+// WithChunkSize sets the maximum length of a chunk. Defaults to 1000 characters, or 512 tokens for NewTokenSplitter.
+func WithChunkSize(size int) Option {
+	return func(c *config) {
+		c.chunkSize = size
+	}
+}
+
+// WithChunkOverlap sets how much of the end of a chunk is repeated at the start of the next one, so that text cut at
+// a boundary keeps some context. It should be well below the chunk size. Defaults to 0.
+func WithChunkOverlap(overlap int) Option {
+	return func(c *config) {
+		c.chunkOverlap = overlap
+	}
+}
+
+// WithLengthFunc sets how chunk sizes and overlaps are measured. Defaults to counting characters.
+func WithLengthFunc(length func(text string) int) Option {
+	return func(c *config) {
+		c.length = length
+	}
+}
+
+// WithTokenCounter measures chunk sizes and overlaps in tokens counted by countTokens.
+func WithTokenCounter(countTokens func(text string) int) Option {
+	return WithLengthFunc(countTokens)
+}
+
+// WithSeparators sets the separators RecursiveSplitter tries, coarsest first. An empty separator splits between
+// characters; without it, a text without any of the separators may exceed the chunk size. Defaults to DefaultSeparators.
+func WithSeparators(separators ...string) Option {
+	return func(c *config) {
+		c.separators = separators
+	}
+}
+
+// WithMaxHeadingLevel sets the deepest heading level MarkdownSplitter starts a section at. Defaults to 6.
+func WithMaxHeadingLevel(level int) Option {
+	return func(c *config) {
+		c.maxHeadingLevel = level
+	}
+}
+
+func newConfig(defaultSize int, opts []Option) *config {
+	c := &config{
+		chunkSize:       defaultSize,
+		length:          utf8.RuneCountInString,
+		separators:      DefaultSeparators,
+		maxHeadingLevel: 6,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.chunkSize < 1 {
+		c.chunkSize = 1
+	}
+	return c
+}
+
+// RecursiveSplitter splits a text at the coarsest separator that yields pieces within the chunk size, splitting
+// oversized pieces further with the next separators, then merges adjacent pieces into chunks up to the chunk size.
+type RecursiveSplitter struct {
+	config *config
+}
+
+// NewRecursiveSplitter creates a splitter keeping paragraphs, then lines, then words together where possible.
+func NewRecursiveSplitter(opts ...Option) *RecursiveSplitter {
+	return &RecursiveSplitter{config: newConfig(1000, opts)}
+}
+
+// NewTokenSplitter creates a RecursiveSplitter measuring chunks in tokens, so that every chunk fits an embedding
+// model's input limit. Tokens are estimated with llmconnector.EstimateTokens unless WithTokenCounter is given.
+func NewTokenSplitter(opts ...Option) *RecursiveSplitter {
+	return &RecursiveSplitter{config: newConfig(512, append([]Option{WithTokenCounter(llmconnector.EstimateTokens)}, opts...))}
+}
+
+func (s *RecursiveSplitter) Split(text string) []Chunk {
+	return s.config.split(text, span{0, len(text)}, s.config.separators, nil, nil)
+}
+
+// span is a part of the source text, as byte offsets.
+type span struct {
+	start, end int
+}
+
+// measure returns the length of text[start:end] without surrounding whitespace, as it would be chunked.
+func (c *config) measure(text string, start, end int) int {
+	return c.length(strings.TrimSpace(text[start:end]))
+}
+
+// split appends to chunks the chunks of text[whole.start:whole.end], split at the first of separators found in it.
+func (c *config) split(text string, whole span, separators []string, chunks []Chunk, headings []string) []Chunk {
+	if c.measure(text, whole.start, whole.end) <= c.chunkSize {
+		return appendChunk(chunks, text, whole.start, whole.end, headings)
+	}
+	for i, separator := range separators {
+		if separator == "" {
+			var parts []span
+			for pos := whole.start; pos < whole.end; {
+				_, size := utf8.DecodeRuneInString(text[pos:whole.end])
+				parts = append(parts, span{pos, pos + size})
+				pos += size
+			}
+			return c.mergeParts(text, parts, nil, chunks, headings)
+		}
+		if !strings.Contains(text[whole.start:whole.end], separator) {
+			continue
+		}
+		// Separators stay at the end of the part they terminate, so that the parts cover the text without gaps.
+		var parts []span
+		for pos := whole.start; pos < whole.end; {
+			next := whole.end
+			if j := strings.Index(text[pos:whole.end], separator); j >= 0 {
+				next = pos + j + len(separator)
+			}
+			parts = append(parts, span{pos, next})
+			pos = next
+		}
+		return c.mergeParts(text, parts, separators[i+1:], chunks, headings)
+	}
+	return appendChunk(chunks, text, whole.start, whole.end, headings)
+}
+
+// mergeParts appends to chunks the consecutive parts within the chunk size merged together, and the parts
+// exceeding it split further with separators.
+func (c *config) mergeParts(text string, parts []span, separators []string, chunks []Chunk, headings []string) []Chunk {
+	var fitting []span
+	for _, part := range parts {
+		if c.measure(text, part.start, part.end) <= c.chunkSize {
+			fitting = append(fitting, part)
+			continue
+		}
+		chunks = c.merge(text, fitting, chunks, headings)
+		fitting = nil
+		chunks = c.split(text, part, separators, chunks, headings)
+	}
+	return c.merge(text, fitting, chunks, headings)
+}
+
+// merge appends to chunks the parts combined into chunks of up to the chunk size, each starting with up to the
+// chunk overlap of the end of the previous one.
+func (c *config) merge(text string, parts []span, chunks []Chunk, headings []string) []Chunk {
+	var current []span
+	for _, part := range parts {
+		if len(current) > 0 && c.measure(text, current[0].start, part.end) > c.chunkSize {
+			chunks = appendChunk(chunks, text, current[0].start, current[len(current)-1].end, headings)
+			for len(current) > 0 && (c.measure(text, current[0].start, current[len(current)-1].end) > c.chunkOverlap ||
+				c.measure(text, current[0].start, part.end) > c.chunkSize) {
+				current = current[1:]
+			}
+		}
+		current = append(current, part)
+	}
+	if len(current) > 0 {
+		chunks = appendChunk(chunks, text, current[0].start, current[len(current)-1].end, headings)
+	}
+	return chunks
+}
+
+// appendChunk appends text[start:end] trimmed of whitespace, unless nothing remains.
+func appendChunk(chunks []Chunk, text string, start, end int, headings []string) []Chunk {
+	trimmed := strings.TrimLeftFunc(text[start:end], unicode.IsSpace)
+	start = end - len(trimmed)
+	end = start + len(strings.TrimRightFunc(trimmed, unicode.IsSpace))
+	if start == end {
+		return chunks
+	}
+	return append(chunks, Chunk{Text: text[start:end], Start: start, End: end, Headings: headings})
+}
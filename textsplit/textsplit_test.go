@@ -0,0 +1,117 @@
+package textsplit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// assertChunks checks that every chunk is the source text at its offsets, in order and within size.
+func assertChunks(t *testing.T, text string, chunks []Chunk, size int, length func(string) int) {
+	t.Helper()
+	require.NotEmpty(t, chunks)
+	for i, chunk := range chunks {
+		assert.Equal(t, text[chunk.Start:chunk.End], chunk.Text)
+		assert.LessOrEqual(t, length(chunk.Text), size, "chunk %d: %q", i, chunk.Text)
+		if i > 0 {
+			assert.Greater(t, chunk.Start, chunks[i-1].Start)
+		}
+	}
+}
+
+func TestRecursiveSplitter(t *testing.T) {
+	text := "First paragraph is short.\n\nSecond paragraph is a little longer than the first one.\n\nThird."
+	chunks := NewRecursiveSplitter(WithChunkSize(40)).Split(text)
+	assertChunks(t, text, chunks, 40, utf8.RuneCountInString)
+	assert.Equal(t, []string{
+		"First paragraph is short.",
+		"Second paragraph is a little longer than",
+		"the first one.",
+		"Third.",
+	}, Texts(chunks))
+
+	chunks = NewRecursiveSplitter(WithChunkSize(100)).Split(text)
+	assert.Equal(t, []string{strings.TrimSpace(text)}, Texts(chunks))
+
+	assert.Empty(t, NewRecursiveSplitter().Split(" \n\n "))
+}
+
+func TestRecursiveSplitter_Overlap(t *testing.T) {
+	text := "one two three four five six seven eight nine ten"
+	chunks := NewRecursiveSplitter(WithChunkSize(20), WithChunkOverlap(10)).Split(text)
+	assertChunks(t, text, chunks, 20, utf8.RuneCountInString)
+	assert.Equal(t, []string{
+		"one two three four",
+		"three four five six",
+		"five six seven eight",
+		"eight nine ten",
+	}, Texts(chunks))
+}
+
+func TestRecursiveSplitter_Characters(t *testing.T) {
+	text := "没有空格的中文文本需要按字符切分"
+	chunks := NewRecursiveSplitter(WithChunkSize(5)).Split(text)
+	assertChunks(t, text, chunks, 5, utf8.RuneCountInString)
+	assert.Equal(t, []string{"没有空格的", "中文文本需", "要按字符切", "分"}, Texts(chunks))
+}
+
+func TestSentenceSplitter(t *testing.T) {
+	text := "Pi is 3.14 roughly. Is it? \"Yes!\" Next line\nends here."
+	chunks := NewSentenceSplitter(WithChunkSize(1)).Split(text)
+	// Sentences longer than the chunk size are split further, so compare sentences directly.
+	var got []string
+	for _, s := range sentences(text) {
+		got = append(got, text[s.start:s.end])
+	}
+	assert.Equal(t, []string{"Pi is 3.14 roughly. ", "Is it? ", "\"Yes!\" ", "Next line\n", "ends here."}, got)
+	assertChunks(t, text, chunks, 1, utf8.RuneCountInString)
+
+	chunks = NewSentenceSplitter(WithChunkSize(30)).Split(text)
+	assertChunks(t, text, chunks, 30, utf8.RuneCountInString)
+	assert.Equal(t, []string{"Pi is 3.14 roughly. Is it?", "\"Yes!\" Next line\nends here."}, Texts(chunks))
+}
+
+func TestSentenceSplitter_CJK(t *testing.T) {
+	text := "今天天气很好。我们去公园吧！你觉得呢？“好的。”他说……然后走了"
+	chunks := NewSentenceSplitter(WithChunkSize(12)).Split(text)
+	assertChunks(t, text, chunks, 12, utf8.RuneCountInString)
+	assert.Equal(t, []string{"今天天气很好。", "我们去公园吧！你觉得呢？", "“好的。”他说……", "然后走了"}, Texts(chunks))
+}
+
+func TestMarkdownSplitter(t *testing.T) {
+	text := "Intro text.\n\n# Guide\n\nWelcome.\n\n## Install\n\nRun go get.\n\n```sh\n# not a heading\n```\n\n" +
+		"## Usage ##\n\nCall it.\n\n# FAQ\n\nAsk away."
+	chunks := NewMarkdownSplitter().Split(text)
+	assertChunks(t, text, chunks, 1000, utf8.RuneCountInString)
+	require.Len(t, chunks, 5)
+	assert.Equal(t, "Intro text.", chunks[0].Text)
+	assert.Empty(t, chunks[0].Headings)
+	assert.Equal(t, "# Guide\n\nWelcome.", chunks[1].Text)
+	assert.Equal(t, []string{"Guide"}, chunks[1].Headings)
+	assert.Equal(t, "## Install\n\nRun go get.\n\n```sh\n# not a heading\n```", chunks[2].Text)
+	assert.Equal(t, []string{"Guide", "Install"}, chunks[2].Headings)
+	assert.Equal(t, []string{"Guide", "Usage"}, chunks[3].Headings)
+	assert.Equal(t, []string{"FAQ"}, chunks[4].Headings)
+
+	chunks = NewMarkdownSplitter(WithMaxHeadingLevel(1), WithChunkSize(20)).Split(text)
+	assertChunks(t, text, chunks, 20, utf8.RuneCountInString)
+	for _, chunk := range chunks[1:] {
+		assert.Len(t, chunk.Headings, 1)
+	}
+}
+
+func TestTokenSplitter(t *testing.T) {
+	countWords := func(text string) int { return len(strings.Fields(text)) }
+	text := strings.Repeat("word ", 25)
+	chunks := NewTokenSplitter(WithTokenCounter(countWords), WithChunkSize(10), WithChunkOverlap(2)).Split(text)
+	assertChunks(t, text, chunks, 10, countWords)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, 10, countWords(chunks[0].Text))
+	// The second chunk starts with the last two words of the first.
+	assert.Equal(t, chunks[0].End-len("word word"), chunks[1].Start)
+
+	chunks = NewTokenSplitter().Split(strings.Repeat("abc ", 1000))
+	assert.Greater(t, len(chunks), 1)
+}
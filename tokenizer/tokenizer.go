@@ -0,0 +1,205 @@
+// Package tokenizer counts tokens with the BPE vocabularies of OpenAI (cl100k_base, o200k_base) and Qwen models,
+// which are embedded in the binary. Importing it registers the encodings with llmconnector, so that
+// llmconnector.TokenizerFor, ModelContext.CountTokens and BatchEmbed count exact tokens instead of estimating:
+//
+//	import _ "github.com/simp-lee/llmconnector/tokenizer"
+//
+// Vocabularies are parsed on first use, which takes a few hundred milliseconds per encoding.
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+	"github.com/simp-lee/llmconnector"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// qwenVocabulary is qwen.tiktoken of the Qwen models, shared by the Qwen chat and text-embedding-v* models.
+// It comes from github.com/CharLemAznable/qwen-tokenizer under the MIT License, see NOTICE.
+//
+//go:embed qwen.tiktoken
+var qwenVocabulary []byte
+
+const (
+	cl100kPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+	o200kPattern  = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+	// qwenPattern differs from cl100kPattern in splitting numbers into single digits.
+	qwenPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+)
+
+var encodings = map[string]*Tokenizer{
+	llmconnector.EncodingCL100K: {
+		name: llmconnector.EncodingCL100K,
+		load: func() (*tiktoken.Encoding, error) {
+			return openAIEncoding(llmconnector.EncodingCL100K, cl100kPattern, map[string]int{
+				"<|endoftext|>":   100257,
+				"<|fim_prefix|>":  100258,
+				"<|fim_middle|>":  100259,
+				"<|fim_suffix|>":  100260,
+				"<|endofprompt|>": 100276,
+			})
+		},
+	},
+	llmconnector.EncodingO200K: {
+		name: llmconnector.EncodingO200K,
+		load: func() (*tiktoken.Encoding, error) {
+			return openAIEncoding(llmconnector.EncodingO200K, o200kPattern, map[string]int{
+				"<|endoftext|>":   199999,
+				"<|endofprompt|>": 200018,
+			})
+		},
+	},
+	llmconnector.EncodingQwen: {
+		name: llmconnector.EncodingQwen,
+		load: func() (*tiktoken.Encoding, error) {
+			ranks, err := parseRanks(qwenVocabulary)
+			if err != nil {
+				return nil, err
+			}
+			return &tiktoken.Encoding{
+				Name:           llmconnector.EncodingQwen,
+				PatStr:         qwenPattern,
+				MergeableRanks: ranks,
+				SpecialTokens: map[string]int{
+					"<|endoftext|>": 151643,
+					"<|im_start|>":  151644,
+					"<|im_end|>":    151645,
+				},
+			}, nil
+		},
+	},
+}
+
+func init() {
+	for name, tokenizer := range encodings {
+		llmconnector.RegisterTokenizer(name, tokenizer)
+	}
+}
+
+// Tokenizer encodes text with a BPE encoding. It is safe for concurrent use.
+type Tokenizer struct {
+	name string
+	load func() (*tiktoken.Encoding, error)
+
+	once sync.Once
+	bpe  *tiktoken.Tiktoken
+	err  error
+}
+
+// Get returns the tokenizer of an encoding, one of llmconnector.EncodingCL100K, EncodingO200K and EncodingQwen.
+func Get(encoding string) (*Tokenizer, error) {
+	tokenizer, ok := encodings[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+	if err := tokenizer.init(); err != nil {
+		return nil, err
+	}
+	return tokenizer, nil
+}
+
+// Encodings returns the sorted names of the available encodings.
+func Encodings() []string {
+	names := make([]string, 0, len(encodings))
+	for name := range encodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *Tokenizer) init() error {
+	t.once.Do(func() {
+		encoding, err := t.load()
+		if err != nil {
+			t.err = fmt.Errorf("failed to load encoding %s: %w", t.name, err)
+			return
+		}
+		bpe, err := tiktoken.NewCoreBPE(encoding.MergeableRanks, encoding.SpecialTokens, encoding.PatStr)
+		if err != nil {
+			t.err = fmt.Errorf("failed to load encoding %s: %w", t.name, err)
+			return
+		}
+		specialTokens := make(map[string]any, len(encoding.SpecialTokens))
+		for token := range encoding.SpecialTokens {
+			specialTokens[token] = nil
+		}
+		t.bpe = tiktoken.NewTiktoken(bpe, encoding, specialTokens)
+	})
+	return t.err
+}
+
+// encoder returns the loaded encoding. The vocabularies are embedded, so failing to load them is a build defect.
+func (t *Tokenizer) encoder() *tiktoken.Tiktoken {
+	if err := t.init(); err != nil {
+		panic("tokenizer: " + err.Error())
+	}
+	return t.bpe
+}
+
+// Name returns the name of the encoding.
+func (t *Tokenizer) Name() string {
+	return t.name
+}
+
+// Encode returns the tokens of text. Special tokens such as <|endoftext|> in text are encoded as plain text.
+func (t *Tokenizer) Encode(text string) []int {
+	return t.encoder().EncodeOrdinary(text)
+}
+
+// Decode returns the text of tokens.
+func (t *Tokenizer) Decode(tokens []int) string {
+	return t.encoder().Decode(tokens)
+}
+
+// CountTokens returns the number of tokens of text.
+func (t *Tokenizer) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(t.Encode(text))
+}
+
+func openAIEncoding(name, pattern string, specialTokens map[string]int) (*tiktoken.Encoding, error) {
+	ranks, err := tiktokenloader.NewOfflineLoader().LoadTiktokenBpe(name + ".tiktoken")
+	if err != nil {
+		return nil, err
+	}
+	return &tiktoken.Encoding{Name: name, PatStr: pattern, MergeableRanks: ranks, SpecialTokens: specialTokens}, nil
+}
+
+// parseRanks parses a vocabulary in the tiktoken format: a base64 token and its rank per line.
+func parseRanks(data []byte) (map[string]int, error) {
+	ranks := make(map[string]int, 160000)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		encoded, rankText, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid vocabulary line %q", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		rank, err := strconv.Atoi(rankText)
+		if err != nil {
+			return nil, err
+		}
+		ranks[string(token)] = rank
+	}
+	return ranks, scanner.Err()
+}
@@ -0,0 +1,56 @@
+package tokenizer
+
+import (
+	"context"
+	"github.com/simp-lee/llmconnector"
+	"github.com/simp-lee/llmconnector/llmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTokenizer(t *testing.T) {
+	cl100k, err := Get(llmconnector.EncodingCL100K)
+	require.NoError(t, err)
+	assert.Equal(t, []int{83, 1609, 5963, 374, 2294, 0}, cl100k.Encode("tiktoken is great!"))
+	assert.Equal(t, 2, cl100k.CountTokens("12345"))
+	assert.Equal(t, 0, cl100k.CountTokens(""))
+
+	qwen, err := Get(llmconnector.EncodingQwen)
+	require.NoError(t, err)
+	assert.Equal(t, 5, qwen.CountTokens("12345"))
+
+	_, err = Get("gpt2")
+	assert.Error(t, err)
+
+	for _, name := range Encodings() {
+		tokenizer, err := Get(name)
+		require.NoError(t, err)
+		for _, text := range []string{"Hello, world!", "你好，世界。今天天气很好。", "<|endoftext|> is plain text"} {
+			assert.Equal(t, text, tokenizer.Decode(tokenizer.Encode(text)), name)
+		}
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	cl100k, _ := Get(llmconnector.EncodingCL100K)
+	o200k, _ := Get(llmconnector.EncodingO200K)
+	qwen, _ := Get(llmconnector.EncodingQwen)
+	assert.Same(t, cl100k, llmconnector.TokenizerFor("openai", "gpt-4"))
+	assert.Same(t, o200k, llmconnector.TokenizerFor("openai", "gpt-4o-2024-08-06"))
+	assert.Same(t, o200k, llmconnector.TokenizerFor("openai", "gpt-unknown"))
+	assert.Same(t, qwen, llmconnector.TokenizerFor("alibaba", "qwen-max"))
+}
+
+func TestModelContext_CountTokens(t *testing.T) {
+	modelContext := llmconnector.NewModelContext()
+	modelContext.SetChatStrategy(llmtest.NewFakeChatStrategy().SetProviderName("openai"))
+	messages := []llmconnector.ChatMessage{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: "tiktoken is great!"},
+	}
+	count, err := modelContext.CountTokens(context.Background(), messages, llmconnector.WithChatModel("gpt-4"))
+	require.NoError(t, err)
+	// 3 per message, 1 per role, 6 + 6 content tokens and 3 priming the reply, as counted by OpenAI.
+	assert.Equal(t, 3+1+6+3+1+6+3, count)
+}
@@ -0,0 +1,127 @@
+package llmconnector
+
+import (
+	"context"
+	"sync"
+)
+
+// Tokenizer counts the tokens a model splits a text into.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts a function to a Tokenizer.
+type TokenizerFunc func(text string) int
+
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// Encoding names of the tokenizers used by the built-in providers.
+const (
+	EncodingCL100K = "cl100k_base"
+	EncodingO200K  = "o200k_base"
+	EncodingQwen   = "qwen"
+)
+
+// defaultEncodings are the encodings of provider models missing from the catalog.
+var defaultEncodings = map[string]string{
+	"openai":  EncodingO200K,
+	"alibaba": EncodingQwen,
+}
+
+var (
+	tokenizersMu sync.RWMutex
+	tokenizers   = make(map[string]Tokenizer)
+)
+
+// RegisterTokenizer makes the tokenizer of an encoding available to TokenizerFor. The tokenizer package
+// registers the encodings of the built-in providers from init, so importing it is enough:
+//
+//	import _ "github.com/simp-lee/llmconnector/tokenizer"
+//
+// It panics if tokenizer is nil or encoding is already registered.
+func RegisterTokenizer(encoding string, tokenizer Tokenizer) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	if tokenizer == nil {
+		panic("llmconnector: RegisterTokenizer tokenizer is nil")
+	}
+	if _, dup := tokenizers[encoding]; dup {
+		panic("llmconnector: RegisterTokenizer called twice for encoding " + encoding)
+	}
+	tokenizers[encoding] = tokenizer
+}
+
+// TokenizerFor returns the tokenizer of a model. The encoding is looked up in the default catalog, falling back
+// to the provider's latest encoding. Without a registered tokenizer for the encoding, tokens are estimated with
+// EstimateTokens.
+func TokenizerFor(provider, model string) Tokenizer {
+	encoding := defaultEncodings[provider]
+	if info, ok := DefaultCatalog().Lookup(provider, model); ok && info.Tokenizer != "" {
+		encoding = info.Tokenizer
+	}
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+	if tokenizer, ok := tokenizers[encoding]; ok {
+		return tokenizer
+	}
+	return TokenizerFunc(EstimateTokens)
+}
+
+// chatFormat is the token overhead of the chat template of a provider.
+type chatFormat struct {
+	// perMessage is the tokens around every message besides its role and content,
+	// e.g. <|im_start|>, "\n", <|im_end|> and "\n" of ChatML.
+	perMessage int
+	// reply is the tokens priming the assistant reply.
+	reply int
+}
+
+var chatFormats = map[string]chatFormat{
+	"openai":  {perMessage: 3, reply: 3},
+	"alibaba": {perMessage: 4, reply: 3},
+}
+
+// CountMessageTokens returns the prompt tokens of messages sent to provider: their roles and contents counted
+// with tokenizer, plus the overhead of the provider's chat format. Unknown providers are assumed to use ChatML.
+func CountMessageTokens(tokenizer Tokenizer, provider string, messages []ChatMessage) int {
+	format := chatFormatOf(provider)
+	tokens := format.reply
+	for _, message := range messages {
+		tokens += format.count(tokenizer, message)
+	}
+	return tokens
+}
+
+func chatFormatOf(provider string) chatFormat {
+	if format, ok := chatFormats[provider]; ok {
+		return format
+	}
+	return chatFormats["alibaba"]
+}
+
+// count returns the tokens of message in the format, without the reply priming.
+func (f chatFormat) count(tokenizer Tokenizer, message ChatMessage) int {
+	return f.perMessage + tokenizer.CountTokens(message.Role) + tokenizer.CountTokens(message.Content)
+}
+
+// CountTokens returns the prompt tokens of a Chat call with the same arguments, without sending it.
+// The strategy and model are resolved as for Chat.
+func (c *ModelContext) CountTokens(ctx context.Context, chatMessages []ChatMessage, opts ...ChatOption) (int, error) {
+	options := &ChatOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	c.mu.RLock()
+	strategy, registry := c.chatStrategy, c.registry
+	c.mu.RUnlock()
+
+	strategy, err := resolveChat(ctx, options, strategy, registry)
+	if err != nil {
+		return 0, err
+	}
+	provider := ProviderFromContext(withProvider(ctx, strategy))
+	return CountMessageTokens(TokenizerFor(provider, options.Model), provider, chatMessages), nil
+}
@@ -0,0 +1,36 @@
+package llmconnector
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestCountMessageTokens(t *testing.T) {
+	countWords := TokenizerFunc(func(text string) int { return len(strings.Fields(text)) })
+	messages := []ChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "How are you today?"},
+	}
+	assert.Equal(t, 3+1+2+3+1+4+3, CountMessageTokens(countWords, "openai", messages))
+	assert.Equal(t, 4+1+2+4+1+4+3, CountMessageTokens(countWords, "alibaba", messages))
+	assert.Equal(t, 3, CountMessageTokens(countWords, "openai", nil))
+}
+
+func TestTokenizerFor_Estimates(t *testing.T) {
+	// No tokenizer is registered in this package's tests.
+	assert.Equal(t, EstimateTokens("hello world"), TokenizerFor("openai", "gpt-4o").CountTokens("hello world"))
+}
+
+func TestModelContext_CountTokens(t *testing.T) {
+	modelContext := NewModelContext()
+	_, err := modelContext.CountTokens(context.Background(), nil)
+	assert.Error(t, err)
+
+	modelContext.SetChatStrategy(&MockChatStrategy{})
+	count, err := modelContext.CountTokens(context.Background(), []ChatMessage{{Role: "user", Content: "hello world"}})
+	require.NoError(t, err)
+	assert.Equal(t, 4+EstimateTokens("user")+EstimateTokens("hello world")+3, count)
+}
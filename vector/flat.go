@@ -0,0 +1,118 @@
+package vector
+
+import (
+	"encoding/gob"
+	"io"
+	"sync"
+)
+
+// FlatIndex searches by comparing the query with every vector. Results are exact; search time grows
+// linearly with the number of vectors, which is fine up to tens of thousands.
+type FlatIndex struct {
+	metric Metric
+
+	mu         sync.RWMutex
+	dimensions int
+	ids        []string
+	vectors    [][]float32
+	metadata   []Metadata
+	positions  map[string]int
+}
+
+// NewFlatIndex creates an empty exact index.
+func NewFlatIndex(metric Metric) *FlatIndex {
+	return &FlatIndex{metric: metric, positions: make(map[string]int)}
+}
+
+func (f *FlatIndex) Add(id string, vector []float32, metadata Metadata) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := checkDimensions(f.dimensions, vector); err != nil {
+		return err
+	}
+	f.dimensions = len(vector)
+	prepared := prepare(f.metric, vector)
+	if position, ok := f.positions[id]; ok {
+		f.vectors[position] = prepared
+		f.metadata[position] = metadata
+		return nil
+	}
+	f.positions[id] = len(f.ids)
+	f.ids = append(f.ids, id)
+	f.vectors = append(f.vectors, prepared)
+	f.metadata = append(f.metadata, metadata)
+	return nil
+}
+
+func (f *FlatIndex) Delete(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	position, ok := f.positions[id]
+	if !ok {
+		return false
+	}
+	last := len(f.ids) - 1
+	f.ids[position], f.vectors[position], f.metadata[position] = f.ids[last], f.vectors[last], f.metadata[last]
+	f.positions[f.ids[position]] = position
+	f.ids, f.vectors, f.metadata = f.ids[:last], f.vectors[:last], f.metadata[:last]
+	delete(f.positions, id)
+	return true
+}
+
+func (f *FlatIndex) Search(query []float32, k int, filter Filter) ([]Match, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.ids) == 0 || k <= 0 {
+		return nil, nil
+	}
+	if err := checkDimensions(f.dimensions, query); err != nil {
+		return nil, err
+	}
+	query = prepare(f.metric, query)
+
+	results := &resultHeap{}
+	for i, v := range f.vectors {
+		if filter != nil && !filter(f.ids[i], f.metadata[i]) {
+			continue
+		}
+		pushBounded(results, Result{Index: i, Score: score(f.metric, query, v)}, k)
+	}
+	return f.matches(results.sorted()), nil
+}
+
+func (f *FlatIndex) matches(results []Result) []Match {
+	matches := make([]Match, len(results))
+	for i, result := range results {
+		matches[i] = Match{ID: f.ids[result.Index], Score: result.Score, Metadata: f.metadata[result.Index]}
+	}
+	return matches
+}
+
+func (f *FlatIndex) Len() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.ids)
+}
+
+func (f *FlatIndex) Save(w io.Writer) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	s := snapshot{Version: 1, Kind: kindFlat, Metric: f.metric, Dimensions: f.dimensions}
+	s.Nodes = make([]snapshotNode, len(f.ids))
+	for i := range f.ids {
+		s.Nodes[i] = snapshotNode{ID: f.ids[i], Vector: f.vectors[i], Metadata: f.metadata[i]}
+	}
+	return gob.NewEncoder(w).Encode(s)
+}
+
+func flatFromSnapshot(s snapshot) *FlatIndex {
+	f := NewFlatIndex(s.Metric)
+	f.dimensions = s.Dimensions
+	for _, node := range s.Nodes {
+		f.positions[node.ID] = len(f.ids)
+		f.ids = append(f.ids, node.ID)
+		f.vectors = append(f.vectors, node.Vector)
+		f.metadata = append(f.metadata, node.Metadata)
+	}
+	return f
+}
@@ -0,0 +1,375 @@
+package vector
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// HNSWOptions tunes an HNSWIndex. Zero values select the defaults.
+type HNSWOptions struct {
+	// M is the number of neighbors linked per node on upper layers; layer 0 links 2*M. Defaults to 16.
+	M int
+	// EfConstruction is the candidate list size when inserting. Higher builds a better graph, slower. Defaults to 200.
+	EfConstruction int
+	// EfSearch is the minimum candidate list size when searching. Higher improves recall, slower. Defaults to 64.
+	EfSearch int
+	// Seed makes the level assignment reproducible. 0 seeds from the clock.
+	Seed int64
+}
+
+func (o HNSWOptions) withDefaults() HNSWOptions {
+	if o.M <= 0 {
+		o.M = 16
+	}
+	if o.EfConstruction <= 0 {
+		o.EfConstruction = 200
+	}
+	if o.EfSearch <= 0 {
+		o.EfSearch = 64
+	}
+	return o
+}
+
+// HNSWIndex is an approximate index based on Hierarchical Navigable Small World graphs. Search time grows
+// logarithmically with the number of vectors, at the cost of occasionally missing a true neighbor.
+// Deleted vectors are kept as tombstones to preserve the graph until Compact is called.
+type HNSWIndex struct {
+	metric      Metric
+	options     HNSWOptions
+	levelFactor float64
+
+	mu         sync.RWMutex
+	rng        *rand.Rand
+	dimensions int
+	nodes      []hnswNode
+	ids        map[string]int32
+	entry      int32
+	maxLevel   int
+}
+
+type hnswNode struct {
+	id       string
+	vector   []float32
+	metadata Metadata
+	deleted  bool
+	// neighbors holds the linked nodes of every layer the node is on.
+	neighbors [][]int32
+}
+
+// NewHNSWIndex creates an empty approximate index.
+func NewHNSWIndex(metric Metric, options HNSWOptions) *HNSWIndex {
+	options = options.withDefaults()
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &HNSWIndex{
+		metric:      metric,
+		options:     options,
+		levelFactor: 1 / math.Log(float64(options.M)),
+		rng:         rand.New(rand.NewSource(seed)),
+		ids:         make(map[string]int32),
+		entry:       -1,
+	}
+}
+
+func (h *HNSWIndex) Add(id string, vector []float32, metadata Metadata) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := checkDimensions(h.dimensions, vector); err != nil {
+		return err
+	}
+	h.dimensions = len(vector)
+	if old, ok := h.ids[id]; ok {
+		h.nodes[old].deleted = true
+	}
+	h.insert(hnswNode{id: id, vector: prepare(h.metric, vector), metadata: metadata})
+	return nil
+}
+
+func (h *HNSWIndex) insert(node hnswNode) {
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelFactor)
+	node.neighbors = make([][]int32, level+1)
+	n := int32(len(h.nodes))
+	h.nodes = append(h.nodes, node)
+	h.ids[node.id] = n
+	if h.entry < 0 {
+		h.entry, h.maxLevel = n, level
+		return
+	}
+
+	query := node.vector
+	entries := []int32{h.entry}
+	for layer := h.maxLevel; layer > level; layer-- {
+		entries = []int32{h.searchLayer(query, entries, 1, layer)[0].node}
+	}
+	for layer := min(level, h.maxLevel); layer >= 0; layer-- {
+		candidates := h.searchLayer(query, entries, h.options.EfConstruction, layer)
+		selected := candidates[:min(h.options.M, len(candidates))]
+		links := make([]int32, len(selected))
+		for i, candidate := range selected {
+			links[i] = candidate.node
+			h.link(candidate.node, n, layer)
+		}
+		h.nodes[n].neighbors[layer] = links
+
+		entries = entries[:0]
+		for _, candidate := range candidates {
+			entries = append(entries, candidate.node)
+		}
+	}
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = n, level
+	}
+}
+
+// link adds to as a neighbor of from, dropping the least similar neighbor when from has too many.
+func (h *HNSWIndex) link(from, to int32, layer int) {
+	limit := h.options.M
+	if layer == 0 {
+		limit *= 2
+	}
+	neighbors := append(h.nodes[from].neighbors[layer], to)
+	if len(neighbors) > limit {
+		results := &resultHeap{}
+		for _, neighbor := range neighbors {
+			pushBounded(results, Result{Index: int(neighbor), Score: h.score(from, neighbor)}, limit)
+		}
+		neighbors = neighbors[:0]
+		for _, result := range *results {
+			neighbors = append(neighbors, int32(result.Index))
+		}
+	}
+	h.nodes[from].neighbors[layer] = neighbors
+}
+
+func (h *HNSWIndex) score(a, b int32) float32 {
+	return score(h.metric, h.nodes[a].vector, h.nodes[b].vector)
+}
+
+type candidate struct {
+	node  int32
+	score float32
+}
+
+// searchLayer returns up to ef nodes of layer most similar to query, most similar first,
+// exploring the graph from entries.
+func (h *HNSWIndex) searchLayer(query []float32, entries []int32, ef int, layer int) []candidate {
+	visited := make(map[int32]bool, ef*4)
+	frontier := &candidateHeap{best: true}
+	results := &candidateHeap{}
+	for _, entry := range entries {
+		if visited[entry] {
+			continue
+		}
+		visited[entry] = true
+		c := candidate{node: entry, score: score(h.metric, query, h.nodes[entry].vector)}
+		heap.Push(frontier, c)
+		heap.Push(results, c)
+		if results.Len() > ef {
+			heap.Pop(results)
+		}
+	}
+
+	for frontier.Len() > 0 {
+		current := heap.Pop(frontier).(candidate)
+		if results.Len() >= ef && current.score < results.items[0].score {
+			break
+		}
+		for _, neighbor := range h.nodes[current.node].neighbors[layer] {
+			if visited[neighbor] {
+				continue
+			}
+			visited[neighbor] = true
+			s := score(h.metric, query, h.nodes[neighbor].vector)
+			if results.Len() < ef || s > results.items[0].score {
+				c := candidate{node: neighbor, score: s}
+				heap.Push(frontier, c)
+				heap.Push(results, c)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sorted := make([]candidate, results.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(results).(candidate)
+	}
+	return sorted
+}
+
+func (h *HNSWIndex) Delete(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n, ok := h.ids[id]
+	if !ok {
+		return false
+	}
+	h.nodes[n].deleted = true
+	delete(h.ids, id)
+	return true
+}
+
+func (h *HNSWIndex) Search(query []float32, k int, filter Filter) ([]Match, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.ids) == 0 || k <= 0 {
+		return nil, nil
+	}
+	if err := checkDimensions(h.dimensions, query); err != nil {
+		return nil, err
+	}
+	query = prepare(h.metric, query)
+
+	entries := []int32{h.entry}
+	for layer := h.maxLevel; layer > 0; layer-- {
+		entries = []int32{h.searchLayer(query, entries, 1, layer)[0].node}
+	}
+	// Tombstones and filtered out vectors take up candidate slots, so widen the search until k match.
+	for ef := max(h.options.EfSearch, k); ; ef *= 2 {
+		var matches []Match
+		for _, c := range h.searchLayer(query, entries, ef, 0) {
+			node := &h.nodes[c.node]
+			if node.deleted || (filter != nil && !filter(node.id, node.metadata)) {
+				continue
+			}
+			matches = append(matches, Match{ID: node.id, Score: c.score, Metadata: node.metadata})
+			if len(matches) == k {
+				return matches, nil
+			}
+		}
+		if ef >= len(h.nodes) {
+			return matches, nil
+		}
+	}
+}
+
+func (h *HNSWIndex) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.ids)
+}
+
+// Compact rebuilds the graph without deleted vectors.
+func (h *HNSWIndex) Compact() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	nodes := h.nodes
+	h.nodes, h.ids, h.entry, h.maxLevel = nil, make(map[string]int32), -1, 0
+	for _, node := range nodes {
+		if !node.deleted {
+			h.insert(hnswNode{id: node.id, vector: node.vector, metadata: node.metadata})
+		}
+	}
+}
+
+func (h *HNSWIndex) Save(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	s := snapshot{
+		Version:    1,
+		Kind:       kindHNSW,
+		Metric:     h.metric,
+		Dimensions: h.dimensions,
+		Options:    h.options,
+		EntryPoint: h.entry,
+		MaxLevel:   h.maxLevel,
+		Nodes:      make([]snapshotNode, len(h.nodes)),
+	}
+	for i, node := range h.nodes {
+		s.Nodes[i] = snapshotNode{
+			ID:        node.id,
+			Vector:    node.vector,
+			Metadata:  node.metadata,
+			Deleted:   node.deleted,
+			Neighbors: node.neighbors,
+		}
+	}
+	return gob.NewEncoder(w).Encode(s)
+}
+
+func hnswFromSnapshot(s snapshot) (*HNSWIndex, error) {
+	if err := checkGraph(s); err != nil {
+		return nil, fmt.Errorf("corrupt vector index: %w", err)
+	}
+	h := NewHNSWIndex(s.Metric, s.Options)
+	h.dimensions, h.entry, h.maxLevel = s.Dimensions, s.EntryPoint, s.MaxLevel
+	h.nodes = make([]hnswNode, len(s.Nodes))
+	for i, node := range s.Nodes {
+		h.nodes[i] = hnswNode{
+			id:        node.ID,
+			vector:    node.Vector,
+			metadata:  node.Metadata,
+			deleted:   node.Deleted,
+			neighbors: node.Neighbors,
+		}
+		if !node.Deleted {
+			h.ids[node.ID] = int32(i)
+		}
+	}
+	return h, nil
+}
+
+// checkGraph checks that searching and inserting into the graph of s stays within its nodes and their layers.
+func checkGraph(s snapshot) error {
+	if s.MaxLevel < 0 {
+		return fmt.Errorf("negative max level %d", s.MaxLevel)
+	}
+	live := false
+	for i, node := range s.Nodes {
+		live = live || !node.Deleted
+		if len(node.Neighbors) == 0 || len(node.Neighbors) > s.MaxLevel+1 {
+			return fmt.Errorf("node %d has %d layers, max level is %d", i, len(node.Neighbors), s.MaxLevel)
+		}
+		for layer, links := range node.Neighbors {
+			for _, link := range links {
+				if link < 0 || int(link) >= len(s.Nodes) {
+					return fmt.Errorf("link to node %d of %d", link, len(s.Nodes))
+				}
+				if len(s.Nodes[link].Neighbors) <= layer {
+					return fmt.Errorf("link to node %d on layer %d it is not on", link, layer)
+				}
+			}
+		}
+	}
+	switch {
+	case s.EntryPoint < 0:
+		if live || s.EntryPoint < -1 {
+			return fmt.Errorf("entry point %d of %d nodes", s.EntryPoint, len(s.Nodes))
+		}
+	case int(s.EntryPoint) >= len(s.Nodes):
+		return fmt.Errorf("entry point %d of %d nodes", s.EntryPoint, len(s.Nodes))
+	case len(s.Nodes[s.EntryPoint].Neighbors) != s.MaxLevel+1:
+		return fmt.Errorf("entry point has %d layers, max level is %d", len(s.Nodes[s.EntryPoint].Neighbors), s.MaxLevel)
+	}
+	return nil
+}
+
+// candidateHeap orders candidates by score: most similar on top when best is set, least similar otherwise.
+type candidateHeap struct {
+	items []candidate
+	best  bool
+}
+
+func (h candidateHeap) Len() int { return len(h.items) }
+func (h candidateHeap) Less(i, j int) bool {
+	if h.best {
+		return h.items[i].score > h.items[j].score
+	}
+	return h.items[i].score < h.items[j].score
+}
+func (h candidateHeap) Swap(i, j int)       { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x interface{}) { h.items = append(h.items, x.(candidate)) }
+func (h *candidateHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
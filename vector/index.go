@@ -0,0 +1,186 @@
+package vector
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/simp-lee/llmconnector"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Metadata annotates an indexed vector, e.g. with the document it was computed from.
+type Metadata map[string]string
+
+// Filter selects the vectors a search may return.
+type Filter func(id string, metadata Metadata) bool
+
+// Equals returns a filter accepting vectors whose metadata has value for key.
+func Equals(key, value string) Filter {
+	return func(id string, metadata Metadata) bool {
+		actual, ok := metadata[key]
+		return ok && actual == value
+	}
+}
+
+// And returns a filter accepting vectors accepted by all filters.
+func And(filters ...Filter) Filter {
+	return func(id string, metadata Metadata) bool {
+		for _, filter := range filters {
+			if !filter(id, metadata) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Match is a vector found by Index.Search.
+type Match struct {
+	ID       string
+	Score    float32
+	Metadata Metadata
+}
+
+// ErrDimensionMismatch is returned, wrapped, when a vector does not have the size of the vectors already indexed.
+var ErrDimensionMismatch = errors.New("vector dimension mismatch")
+
+// Index is a searchable set of vectors identified by ID. Implementations are safe for concurrent use.
+type Index interface {
+	// Add inserts vector under id, replacing an existing vector with the same id. The vector is copied.
+	Add(id string, vector []float32, metadata Metadata) error
+	// Delete removes the vector of id and reports whether it existed.
+	Delete(id string) bool
+	// Search returns the k vectors most similar to query that filter accepts, most similar first.
+	// A nil filter accepts every vector.
+	Search(query []float32, k int, filter Filter) ([]Match, error)
+	// Len returns the number of vectors.
+	Len() int
+	// Save writes the index so that Load can restore it.
+	Save(w io.Writer) error
+}
+
+// AddResponse adds the embeddings of resp to index under ids, in input order. metadata may be nil,
+// otherwise it must have an entry for every id.
+func AddResponse(index Index, ids []string, resp llmconnector.EmbedResponse, metadata []Metadata) error {
+	embeddings := resp.GetEmbeddings()
+	if len(embeddings) != len(ids) {
+		return fmt.Errorf("vector: %d ids for %d embeddings", len(ids), len(embeddings))
+	}
+	if metadata != nil && len(metadata) != len(ids) {
+		return fmt.Errorf("vector: %d metadata entries for %d embeddings", len(metadata), len(embeddings))
+	}
+	for i, embedding := range embeddings {
+		var m Metadata
+		if metadata != nil {
+			m = metadata[i]
+		}
+		if err := index.Add(ids[i], embedding, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepare copies v for storage. Cosine vectors are normalized, so that scoring is a dot product.
+func prepare(metric Metric, v []float32) []float32 {
+	if metric == MetricCosine {
+		return Normalize(v)
+	}
+	return append([]float32(nil), v...)
+}
+
+// score compares vectors prepared for metric.
+func score(metric Metric, a, b []float32) float32 {
+	if metric == MetricEuclidean {
+		return -Euclidean(a, b)
+	}
+	return Dot(a, b)
+}
+
+func checkDimensions(dimensions int, v []float32) error {
+	if dimensions != 0 && len(v) != dimensions {
+		return fmt.Errorf("%w: got %d, index has %d", ErrDimensionMismatch, len(v), dimensions)
+	}
+	if len(v) == 0 {
+		return fmt.Errorf("%w: empty vector", ErrDimensionMismatch)
+	}
+	return nil
+}
+
+const (
+	kindFlat = "flat"
+	kindHNSW = "hnsw"
+)
+
+// snapshot is the persisted form of both index kinds.
+type snapshot struct {
+	Version    int
+	Kind       string
+	Metric     Metric
+	Dimensions int
+	Nodes      []snapshotNode
+	// HNSW only.
+	Options    HNSWOptions
+	EntryPoint int32
+	MaxLevel   int
+}
+
+type snapshotNode struct {
+	ID        string
+	Vector    []float32
+	Metadata  Metadata
+	Deleted   bool
+	Neighbors [][]int32
+}
+
+// Load restores an index written by Index.Save.
+func Load(r io.Reader) (Index, error) {
+	var s snapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to decode vector index: %w", err)
+	}
+	if s.Version != 1 {
+		return nil, fmt.Errorf("unsupported vector index version %d", s.Version)
+	}
+	switch s.Kind {
+	case kindFlat:
+		return flatFromSnapshot(s), nil
+	case kindHNSW:
+		return hnswFromSnapshot(s)
+	}
+	return nil, fmt.Errorf("unknown vector index kind %q", s.Kind)
+}
+
+// SaveFile writes index to path atomically.
+func SaveFile(index Index, path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if err := index.Save(file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// LoadFile restores an index written by SaveFile.
+func LoadFile(path string) (Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Load(file)
+}
@@ -0,0 +1,190 @@
+// Package vector provides similarity functions, top-k search and in-memory vector indexes (exact and HNSW)
+// for embeddings returned by llmconnector.ModelContext.Embed.
+package vector
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+)
+
+// The loops below are unrolled by four with independent accumulators, which lets the compiler
+// keep them in registers and pipeline the multiplications.
+
+// Dot returns the dot product of a and b, which must have the same length.
+func Dot(a, b []float32) float32 {
+	checkLengths(a, b)
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// Norm returns the Euclidean length of v.
+func Norm(v []float32) float32 {
+	return float32(math.Sqrt(float64(Dot(v, v))))
+}
+
+// Cosine returns the cosine similarity of a and b, between -1 and 1. It is 0 when either vector is zero.
+func Cosine(a, b []float32) float32 {
+	checkLengths(a, b)
+	var dot, normA, normB float32
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / float32(math.Sqrt(float64(normA)*float64(normB)))
+}
+
+// SquaredEuclidean returns the squared Euclidean distance of a and b, which orders vectors like Euclidean
+// without the square root.
+func SquaredEuclidean(a, b []float32) float32 {
+	checkLengths(a, b)
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		d0, d1, d2, d3 := a[i]-b[i], a[i+1]-b[i+1], a[i+2]-b[i+2], a[i+3]-b[i+3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+	}
+	for ; i < len(a); i++ {
+		d := a[i] - b[i]
+		s0 += d * d
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// Euclidean returns the Euclidean distance of a and b.
+func Euclidean(a, b []float32) float32 {
+	return float32(math.Sqrt(float64(SquaredEuclidean(a, b))))
+}
+
+// Normalize returns a copy of v scaled to unit length. Zero vectors are copied unchanged.
+func Normalize(v []float32) []float32 {
+	normalized := make([]float32, len(v))
+	norm := Norm(v)
+	if norm == 0 {
+		copy(normalized, v)
+		return normalized
+	}
+	for i, value := range v {
+		normalized[i] = value / norm
+	}
+	return normalized
+}
+
+func checkLengths(a, b []float32) {
+	if len(a) != len(b) {
+		panic(fmt.Sprintf("vector: length mismatch %d != %d", len(a), len(b)))
+	}
+}
+
+// Metric is how the similarity of two vectors is measured.
+type Metric int
+
+const (
+	// MetricCosine scores by cosine similarity.
+	MetricCosine Metric = iota
+	// MetricDot scores by dot product, which equals cosine similarity for normalized vectors and is cheaper.
+	MetricDot
+	// MetricEuclidean scores by negated Euclidean distance, so higher scores are still more similar.
+	MetricEuclidean
+)
+
+func (m Metric) String() string {
+	switch m {
+	case MetricCosine:
+		return "cosine"
+	case MetricDot:
+		return "dot"
+	case MetricEuclidean:
+		return "euclidean"
+	}
+	return fmt.Sprintf("Metric(%d)", int(m))
+}
+
+// Score returns the similarity of a and b under m. Higher is more similar.
+func (m Metric) Score(a, b []float32) float32 {
+	switch m {
+	case MetricDot:
+		return Dot(a, b)
+	case MetricEuclidean:
+		return -Euclidean(a, b)
+	}
+	return Cosine(a, b)
+}
+
+// Result is a vector found by TopK.
+type Result struct {
+	// Index is the position of the vector in the searched slice.
+	Index int
+	Score float32
+}
+
+// TopK returns the k vectors most similar to query under metric, most similar first.
+func TopK(query []float32, vectors [][]float32, k int, metric Metric) []Result {
+	if k <= 0 {
+		return nil
+	}
+	results := &resultHeap{}
+	for i, v := range vectors {
+		pushBounded(results, Result{Index: i, Score: metric.Score(query, v)}, k)
+	}
+	return results.sorted()
+}
+
+// BatchTopK runs TopK for every query.
+func BatchTopK(queries, vectors [][]float32, k int, metric Metric) [][]Result {
+	results := make([][]Result, len(queries))
+	for i, query := range queries {
+		results[i] = TopK(query, vectors, k, metric)
+	}
+	return results
+}
+
+// pushBounded adds result to h, keeping only the k best results.
+func pushBounded(h *resultHeap, result Result, k int) {
+	if h.Len() < k {
+		heap.Push(h, result)
+	} else if result.Score > (*h)[0].Score {
+		(*h)[0] = result
+		heap.Fix(h, 0)
+	}
+}
+
+// sorted empties h and returns its results, best first.
+func (h *resultHeap) sorted() []Result {
+	sorted := make([]Result, h.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(h).(Result)
+	}
+	return sorted
+}
+
+// resultHeap is a min-heap of results by score, so the worst of the best k is at the top.
+type resultHeap []Result
+
+func (h resultHeap) Len() int            { return len(h) }
+func (h resultHeap) Less(i, j int) bool  { return h[i].Score < h[j].Score }
+func (h resultHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *resultHeap) Push(x interface{}) { *h = append(*h, x.(Result)) }
+func (h *resultHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,236 @@
+package vector
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"github.com/simp-lee/llmconnector/llmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	a := []float32{1, 2, 3, 4, 5}
+	b := []float32{5, 4, 3, 2, 1}
+	assert.Equal(t, float32(35), Dot(a, b))
+	assert.InDelta(t, 35.0/55.0, Cosine(a, b), 1e-6)
+	assert.InDelta(t, 6.3245553, Euclidean(a, b), 1e-6)
+	assert.Equal(t, float32(40), SquaredEuclidean(a, b))
+	assert.Zero(t, Cosine(a, make([]float32, 5)))
+	assert.InDelta(t, 1, Norm(Normalize(a)), 1e-6)
+	assert.Panics(t, func() { Dot(a, b[:4]) })
+}
+
+func TestTopK(t *testing.T) {
+	vectors := [][]float32{{1, 0}, {0, 1}, {0.7, 0.7}, {-1, 0}}
+	results := TopK([]float32{1, 0.1}, vectors, 2, MetricCosine)
+	require.Len(t, results, 2)
+	assert.Equal(t, 0, results[0].Index)
+	assert.Equal(t, 2, results[1].Index)
+
+	results = TopK([]float32{-0.9, 0}, vectors, 10, MetricEuclidean)
+	assert.Len(t, results, 4)
+	assert.Equal(t, 3, results[0].Index)
+
+	batch := BatchTopK([][]float32{{0, 1}, {1, 0}}, vectors, 1, MetricDot)
+	assert.Equal(t, 1, batch[0][0].Index)
+	assert.Equal(t, 0, batch[1][0].Index)
+}
+
+func testIndexes() map[string]func() Index {
+	return map[string]func() Index{
+		"flat": func() Index { return NewFlatIndex(MetricCosine) },
+		"hnsw": func() Index { return NewHNSWIndex(MetricCosine, HNSWOptions{Seed: 1}) },
+	}
+}
+
+func TestIndex(t *testing.T) {
+	for name, newIndex := range testIndexes() {
+		t.Run(name, func(t *testing.T) {
+			index := newIndex()
+			require.NoError(t, index.Add("x", []float32{1, 0, 0}, Metadata{"lang": "en"}))
+			require.NoError(t, index.Add("y", []float32{0, 1, 0}, Metadata{"lang": "zh"}))
+			require.NoError(t, index.Add("xy", []float32{1, 1, 0}, Metadata{"lang": "zh"}))
+			assert.ErrorIs(t, index.Add("bad", []float32{1, 0}, nil), ErrDimensionMismatch)
+
+			matches, err := index.Search([]float32{1, 0.1, 0}, 2, nil)
+			require.NoError(t, err)
+			require.Len(t, matches, 2)
+			assert.Equal(t, "x", matches[0].ID)
+			assert.Equal(t, "xy", matches[1].ID)
+			assert.Equal(t, Metadata{"lang": "en"}, matches[0].Metadata)
+
+			matches, err = index.Search([]float32{1, 0.1, 0}, 2, Equals("lang", "zh"))
+			require.NoError(t, err)
+			require.Len(t, matches, 2)
+			assert.Equal(t, "xy", matches[0].ID)
+			assert.Equal(t, "y", matches[1].ID)
+
+			assert.True(t, index.Delete("x"))
+			assert.False(t, index.Delete("x"))
+			assert.Equal(t, 2, index.Len())
+			matches, err = index.Search([]float32{1, 0, 0}, 1, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "xy", matches[0].ID)
+
+			// Replacing a vector moves it.
+			require.NoError(t, index.Add("y", []float32{1, 0, 0}, nil))
+			assert.Equal(t, 2, index.Len())
+			matches, err = index.Search([]float32{1, 0, 0}, 1, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "y", matches[0].ID)
+			assert.InDelta(t, 1, matches[0].Score, 1e-6)
+
+			_, err = index.Search([]float32{1}, 1, nil)
+			assert.ErrorIs(t, err, ErrDimensionMismatch)
+		})
+	}
+}
+
+func TestIndex_Persistence(t *testing.T) {
+	for name, newIndex := range testIndexes() {
+		t.Run(name, func(t *testing.T) {
+			index := newIndex()
+			for i := 0; i < 50; i++ {
+				require.NoError(t, index.Add(strconv.Itoa(i), randomVector(rand.New(rand.NewSource(int64(i))), 8), Metadata{"n": strconv.Itoa(i)}))
+			}
+			index.Delete("7")
+			query := randomVector(rand.New(rand.NewSource(99)), 8)
+			want, err := index.Search(query, 5, nil)
+			require.NoError(t, err)
+
+			path := filepath.Join(t.TempDir(), "index.gob")
+			require.NoError(t, SaveFile(index, path))
+			loaded, err := LoadFile(path)
+			require.NoError(t, err)
+			assert.IsType(t, index, loaded)
+			assert.Equal(t, 49, loaded.Len())
+			got, err := loaded.Search(query, 5, nil)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := Load(bytes.NewReader([]byte("not an index")))
+	assert.Error(t, err)
+}
+
+func TestLoad_CorruptHNSW(t *testing.T) {
+	index := NewHNSWIndex(MetricCosine, HNSWOptions{Seed: 1})
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		require.NoError(t, index.Add(strconv.Itoa(i), randomVector(rng, 8), nil))
+	}
+	var saved bytes.Buffer
+	require.NoError(t, index.Save(&saved))
+
+	for name, corrupt := range map[string]func(s *snapshot){
+		"no entry point": func(s *snapshot) { s.EntryPoint = -1 },
+		"entry point below the top layer": func(s *snapshot) {
+			for i, node := range s.Nodes {
+				if len(node.Neighbors) < s.MaxLevel+1 {
+					s.EntryPoint = int32(i)
+					return
+				}
+			}
+		},
+		"node above the max level": func(s *snapshot) {
+			s.Nodes[3].Neighbors = append(s.Nodes[3].Neighbors, nil, nil, nil, nil, nil, nil, nil, nil)
+		},
+		"link out of range": func(s *snapshot) { s.Nodes[3].Neighbors[0] = append(s.Nodes[3].Neighbors[0], -2) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var s snapshot
+			require.NoError(t, gob.NewDecoder(bytes.NewReader(saved.Bytes())).Decode(&s))
+			require.Greater(t, s.MaxLevel, 0, "the seed builds more than one layer")
+			corrupt(&s)
+			var buf bytes.Buffer
+			require.NoError(t, gob.NewEncoder(&buf).Encode(s))
+
+			_, err := Load(&buf)
+			assert.ErrorContains(t, err, "corrupt vector index")
+		})
+	}
+
+	// An empty index has no entry point.
+	empty := NewHNSWIndex(MetricCosine, HNSWOptions{})
+	var buf bytes.Buffer
+	require.NoError(t, empty.Save(&buf))
+	_, err := Load(&buf)
+	assert.NoError(t, err)
+}
+
+func TestHNSWIndex_Recall(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	flat := NewFlatIndex(MetricCosine)
+	hnsw := NewHNSWIndex(MetricCosine, HNSWOptions{Seed: 1})
+	for i := 0; i < 2000; i++ {
+		v := randomVector(rng, 32)
+		require.NoError(t, flat.Add(strconv.Itoa(i), v, nil))
+		require.NoError(t, hnsw.Add(strconv.Itoa(i), v, nil))
+	}
+
+	found, total := 0, 0
+	for q := 0; q < 50; q++ {
+		query := randomVector(rng, 32)
+		exact, err := flat.Search(query, 10, nil)
+		require.NoError(t, err)
+		approximate, err := hnsw.Search(query, 10, nil)
+		require.NoError(t, err)
+		ids := make(map[string]bool)
+		for _, match := range approximate {
+			ids[match.ID] = true
+		}
+		for _, match := range exact {
+			total++
+			if ids[match.ID] {
+				found++
+			}
+		}
+	}
+	assert.GreaterOrEqual(t, float64(found)/float64(total), 0.9)
+}
+
+func TestHNSWIndex_Compact(t *testing.T) {
+	index := NewHNSWIndex(MetricEuclidean, HNSWOptions{Seed: 1})
+	for i := 0; i < 100; i++ {
+		require.NoError(t, index.Add(strconv.Itoa(i), []float32{float32(i), 0}, nil))
+	}
+	for i := 0; i < 90; i++ {
+		index.Delete(strconv.Itoa(i))
+	}
+	index.Compact()
+	assert.Equal(t, 10, index.Len())
+	assert.Len(t, index.nodes, 10)
+	matches, err := index.Search([]float32{0, 0}, 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "90", matches[0].ID)
+	assert.Equal(t, float32(-90), matches[0].Score)
+}
+
+func TestAddResponse(t *testing.T) {
+	index := NewFlatIndex(MetricDot)
+	resp, err := llmtest.HashEmbedder{}.Embed(context.Background(), []string{"a", "b"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, AddResponse(index, []string{"doc-a", "doc-b"}, resp, []Metadata{{"k": "a"}, {"k": "b"}}))
+	assert.Equal(t, 2, index.Len())
+	assert.Error(t, AddResponse(index, []string{"only-one"}, resp, nil))
+
+	matches, err := index.Search(llmtest.HashEmbedder{}.Vector("b"), 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "doc-b", matches[0].ID)
+}
+
+func randomVector(rng *rand.Rand, dimensions int) []float32 {
+	v := make([]float32, dimensions)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64())
+	}
+	return v
+}